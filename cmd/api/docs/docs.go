@@ -15,9 +15,234 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/dead-letters": {
+            "get": {
+                "description": "Вебхуки, которые не удалось доставить за RETRY_LIMIT попыток, от новых к старым",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List Dead Letters",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Limit for pagination",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Offset for pagination",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/deadletter.DeadLetterListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid limit/offset",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет все записи без повторной отправки",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Purge Dead Letters",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/deadletter.PurgeResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters/replay": {
+            "post": {
+                "description": "Возвращает в очередь все записи",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Replay All Dead Letters",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/deadletter.ReplayAllResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters/{id}": {
+            "get": {
+                "description": "Запись с историей попыток и телом события",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get Dead Letter by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dead letter UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/deadletter.DeadLetterResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid UUID",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Dead letter not found",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет запись без повторной отправки",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete Dead Letter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dead letter UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid UUID",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Dead letter not found",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/dead-letters/{id}/replay": {
+            "post": {
+                "description": "Возвращает событие в очередь вебхуков с обнулённым счётчиком попыток и удаляет запись",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Replay Dead Letter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dead letter UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid UUID",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Dead letter not found",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/incidents": {
             "get": {
-                "description": "Получает все активные и неактивные инциденты с поддержкой пагинации",
+                "description": "Получает все активные и неактивные инциденты с поддержкой пагинации и фильтрацией по важности и категории",
                 "produces": [
                     "application/json"
                 ],
@@ -39,6 +264,18 @@ const docTemplate = `{
                         "description": "Offset for pagination",
                         "name": "offset",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Severities, comma separated (info,warning,critical)",
+                        "name": "severity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Categories, comma separated (fire,flood,police,traffic,medical,weather,other)",
+                        "name": "category",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -49,7 +286,7 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid limit/offset",
+                        "description": "Invalid limit/offset or filter",
                         "schema": {
                             "$ref": "#/definitions/httphelper.APIResponse"
                         }
@@ -63,7 +300,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Создаёт новый инцидент с указанным заголовком, координатами и радиусом",
+                "description": "Создаёт новый инцидент с указанным заголовком, координатами и радиусом либо с зоной GeoJSON Polygon/MultiPolygon. Необязательные starts_at, ends_at и recurrence задают расписание активности",
                 "consumes": [
                     "application/json"
                 ],
@@ -135,7 +372,7 @@ const docTemplate = `{
         },
         "/incidents/stats": {
             "get": {
-                "description": "Возвращает кол-во уникальных пользователей за последние N минут, попавших в инцидент",
+                "description": "Возвращает число уникальных пользователей, попавших хотя бы в одну зону инцидента за окно window, разбивку по инцидентам и ряд по интервалам bucket для графиков.\nОкна и интервалы, кратные минуте, считаются по счётчикам HyperLogLog в Redis (оценка); exact=true или более старые окна — точно по сохранённым проверкам",
                 "produces": [
                     "application/json"
                 ],
@@ -143,6 +380,27 @@ const docTemplate = `{
                     "stats"
                 ],
                 "summary": "Get Incidents Stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Stats window as Go duration, e.g. 30m or 24h (default STATS_TIME_WINDOW_MINUTES)",
+                        "name": "window",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time series bucket as Go duration, e.g. 1m (default window/12)",
+                        "name": "bucket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "default": false,
+                        "description": "Count exactly over stored locations instead of Redis HyperLogLog counters",
+                        "name": "exact",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -150,6 +408,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/stats.StatsResponse"
                         }
                     },
+                    "400": {
+                        "description": "Invalid window or bucket",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -206,7 +470,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Деактивирует инцидент, не удаляя его полностью",
+                "description": "Деактивирует инцидент, не удаляя его полностью; ends_at становится текущим временем",
                 "produces": [
                     "application/json"
                 ],
@@ -307,7 +571,7 @@ const docTemplate = `{
         },
         "/location/check": {
             "post": {
-                "description": "Возвращает локации инцидентов, в которые попал пользователь",
+                "description": "Возвращает локации инцидентов, в которые попал пользователь, переходы ENTER/EXIT/DWELL относительно прошлой проверки, зоны, к которым пользователь приближается, и зоны, пересечённые между прошлой и текущей точкой. Если передан accuracy_m, попадание оценивается как certain/possible/outside по доле круга точности внутри зоны",
                 "consumes": [
                     "application/json"
                 ],
@@ -347,51 +611,869 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/httphelper.APIResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
                     }
                 }
             }
         },
-        "/system/health": {
-            "get": {
-                "description": "Проверка доступности API",
+        "/location/check/batch": {
+            "post": {
+                "description": "Проверяет пачку точек, накопленных клиентами офлайн (точки могут принадлежать разным пользователям). Каждая точка сверяется с инцидентами, активными в момент её timestamp; результаты возвращаются в порядке запроса",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "location"
+                ],
+                "summary": "Check buffered user locations",
+                "parameters": [
+                    {
+                        "description": "Buffered locations",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/location.CheckLocationBatchRequest"
+                        }
+                    }
                 ],
-                "summary": "Health Check",
                 "responses": {
                     "200": {
-                        "description": "status: ok",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/location.CheckLocationBatchResponse"
                         }
-                    }
-                }
-            }
-        }
-    },
-    "definitions": {
-        "httphelper.APIResponse": {
-            "type": "object",
-            "properties": {
-                "data": {},
-                "error": {
-                    "type": "string"
-                },
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/system/health": {
+            "get": {
+                "description": "Проверка доступности API и состояние автоматов получателей вебхуков",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Health Check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Получает все подписки на вебхуки, включая отключённые",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Get All Webhook Subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.SubscriptionListResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Регистрирует получателя вебхуков. Пустые event_types, incident_ids и categories означают «все»; секрет генерируется, если не задан, и возвращается только в ответе на создание",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Create Webhook Subscription",
+                "parameters": [
+                    {
+                        "description": "Subscription data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webhook.CreateSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.SubscriptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Subscription already exists",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "description": "Получает подписку по UUID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Get Webhook Subscription by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.SubscriptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid UUID",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет подписку; события, уже поставленные в очередь, ей не доставляются",
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Delete Webhook Subscription by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Invalid UUID",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Обновляет подписку (частичное обновление)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Update Webhook Subscription by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Subscription update data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webhook.UpdateSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.SubscriptionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid UUID or request body",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/rotate-secret": {
+            "post": {
+                "description": "Выдаёт подписке новый секрет (генерируется, если не передан). Прежний секрет остаётся действующим WEBHOOK_SECRET_GRACE_PERIOD: до этого времени X-Signature содержит подписи обоими секретами",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Rotate Webhook Subscription Secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription UUID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New secret",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.RotateSecretRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhook.RotateSecretResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid UUID or request body",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/httphelper.APIResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "deadletter.Attempt": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "description": "HTTP-статус ответа; 0 — ответа не было",
+                    "type": "integer"
+                }
+            }
+        },
+        "deadletter.DeadLetterListResponse": {
+            "type": "object",
+            "properties": {
+                "dead_letters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/deadletter.DeadLetterResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "deadletter.DeadLetterResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/deadletter.Attempt"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "subscription_id": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "deadletter.PurgeResponse": {
+            "type": "object",
+            "properties": {
+                "purged": {
+                    "type": "integer"
+                }
+            }
+        },
+        "deadletter.ReplayAllResponse": {
+            "type": "object",
+            "properties": {
+                "replayed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "health.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "description": "ok либо degraded, если автомат какого-то получателя не замкнут",
+                    "type": "string",
+                    "example": "ok"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "webhook_circuits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/integration.CircuitState"
+                    }
+                }
+            }
+        },
+        "httphelper.APIResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error": {
+                    "type": "string"
+                },
                 "status": {
                     "type": "string"
                 }
             }
         },
-        "incident.CreateIncidentRequest": {
+        "incident.CreateIncidentRequest": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string",
+                    "enum": [
+                        "fire",
+                        "flood",
+                        "police",
+                        "traffic",
+                        "medical",
+                        "weather",
+                        "other"
+                    ],
+                    "example": "other"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "geometry": {
+                    "$ref": "#/definitions/incident.GeometryDTO"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "lng": {
+                    "type": "number"
+                },
+                "notify_cooldown_seconds": {
+                    "description": "окно повтора уведомлений по зоне; 0 — без окна",
+                    "type": "integer",
+                    "example": 600
+                },
+                "radius": {
+                    "type": "number"
+                },
+                "recurrence": {
+                    "$ref": "#/definitions/incident.RecurrenceDTO"
+                },
+                "severity": {
+                    "type": "string",
+                    "enum": [
+                        "info",
+                        "warning",
+                        "critical"
+                    ],
+                    "example": "warning"
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "incident.GeometryDTO": {
+            "type": "object",
+            "properties": {
+                "coordinates": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "type": {
+                    "type": "string",
+                    "example": "Polygon"
+                }
+            }
+        },
+        "incident.IncidentListResponse": {
+            "type": "object",
+            "properties": {
+                "incidents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/incident.IncidentResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "incident.IncidentResponse": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "geometry": {
+                    "$ref": "#/definitions/incident.GeometryDTO"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "lng": {
+                    "type": "number"
+                },
+                "notify_cooldown_seconds": {
+                    "description": "не задано — глобальная настройка NOTIFY_COOLDOWN",
+                    "type": "integer"
+                },
+                "radius": {
+                    "type": "number"
+                },
+                "recurrence": {
+                    "$ref": "#/definitions/incident.RecurrenceDTO"
+                },
+                "severity": {
+                    "type": "string"
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "incident.RecurrenceDTO": {
+            "type": "object",
+            "properties": {
+                "end_time": {
+                    "type": "string",
+                    "example": "06:00"
+                },
+                "frequency": {
+                    "type": "string",
+                    "enum": [
+                        "daily",
+                        "weekly"
+                    ],
+                    "example": "daily"
+                },
+                "start_time": {
+                    "type": "string",
+                    "example": "22:00"
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Europe/Moscow"
+                },
+                "weekdays": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "mon",
+                        "tue"
+                    ]
+                }
+            }
+        },
+        "incident.UpdateIncidentRequest": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string",
+                    "enum": [
+                        "fire",
+                        "flood",
+                        "police",
+                        "traffic",
+                        "medical",
+                        "weather",
+                        "other"
+                    ]
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "geometry": {
+                    "$ref": "#/definitions/incident.GeometryDTO"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "lng": {
+                    "type": "number"
+                },
+                "notify_cooldown_seconds": {
+                    "description": "-1 — вернуть глобальную настройку NOTIFY_COOLDOWN",
+                    "type": "integer"
+                },
+                "radius": {
+                    "type": "number"
+                },
+                "recurrence": {
+                    "$ref": "#/definitions/incident.RecurrenceDTO"
+                },
+                "severity": {
+                    "type": "string",
+                    "enum": [
+                        "info",
+                        "warning",
+                        "critical"
+                    ]
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "integration.CircuitState": {
+            "type": "object",
+            "properties": {
+                "failures": {
+                    "description": "неудач в окне",
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "opened_at": {
+                    "description": "когда автомат разомкнулся",
+                    "type": "string"
+                },
+                "requests": {
+                    "description": "отправок в окне",
+                    "type": "integer"
+                },
+                "retry_at": {
+                    "description": "когда будет пробная отправка",
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "location.CheckLocationBatchRequest": {
+            "type": "object",
+            "properties": {
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/location.CheckLocationPoint"
+                    }
+                }
+            }
+        },
+        "location.CheckLocationBatchResponse": {
+            "type": "object",
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/location.LocationResponse"
+                    }
+                }
+            }
+        },
+        "location.CheckLocationPoint": {
+            "type": "object",
+            "properties": {
+                "accuracy_m": {
+                    "description": "радиус круга точности в метрах",
+                    "type": "number",
+                    "example": 25
+                },
+                "altitude": {
+                    "description": "высота в метрах",
+                    "type": "number"
+                },
+                "heading": {
+                    "description": "направление движения, 0..360 от севера",
+                    "type": "number"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "lng": {
+                    "type": "number"
+                },
+                "speed": {
+                    "description": "скорость в м/с",
+                    "type": "number"
+                },
+                "timestamp": {
+                    "description": "время фиксации точки на клиенте",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "location.CheckLocationRequest": {
+            "type": "object",
+            "properties": {
+                "accuracy_m": {
+                    "description": "радиус круга точности в метрах",
+                    "type": "number",
+                    "example": 25
+                },
+                "altitude": {
+                    "description": "высота в метрах",
+                    "type": "number"
+                },
+                "heading": {
+                    "description": "направление движения, 0..360 от севера",
+                    "type": "number"
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "lng": {
+                    "type": "number"
+                },
+                "speed": {
+                    "description": "скорость в м/с",
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "location.LocationResponse": {
             "type": "object",
             "properties": {
-                "is_active": {
+                "accuracy_m": {
+                    "description": "радиус круга точности в метрах",
+                    "type": "number",
+                    "example": 25
+                },
+                "altitude": {
+                    "description": "высота в метрах",
+                    "type": "number"
+                },
+                "confidence": {
+                    "type": "string",
+                    "enum": [
+                        "certain",
+                        "possible",
+                        "outside"
+                    ]
+                },
+                "heading": {
+                    "description": "направление движения, 0..360 от севера",
+                    "type": "number"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "incident_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "is_check": {
                     "type": "boolean"
                 },
                 "lat": {
@@ -400,66 +1482,216 @@ const docTemplate = `{
                 "lng": {
                     "type": "number"
                 },
-                "radius": {
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/location.MatchResponse"
+                    }
+                },
+                "nearby": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/location.NearbyResponse"
+                    }
+                },
+                "notification": {
+                    "$ref": "#/definitions/location.NotificationResponse"
+                },
+                "passed_through_incident_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "speed": {
+                    "description": "скорость в м/с",
                     "type": "number"
                 },
-                "title": {
+                "timestamp": {
+                    "type": "string"
+                },
+                "transitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/location.TransitionResponse"
+                    }
+                },
+                "user_id": {
                     "type": "string"
                 }
             }
         },
-        "incident.IncidentListResponse": {
+        "location.MatchResponse": {
             "type": "object",
             "properties": {
-                "incidents": {
+                "confidence": {
+                    "type": "string",
+                    "enum": [
+                        "certain",
+                        "possible"
+                    ]
+                },
+                "incident_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "location.NearbyResponse": {
+            "type": "object",
+            "properties": {
+                "bearing": {
+                    "type": "number"
+                },
+                "distance_m": {
+                    "type": "number"
+                },
+                "incident_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "location.NotificationResponse": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "enum": [
+                        "cooldown",
+                        "no_change",
+                        "late"
+                    ]
+                },
+                "sent": {
+                    "type": "boolean"
+                },
+                "suppressed": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/incident.IncidentResponse"
+                        "$ref": "#/definitions/location.SuppressedHitResponse"
                     }
+                }
+            }
+        },
+        "location.SuppressedHitResponse": {
+            "type": "object",
+            "properties": {
+                "event": {
+                    "type": "string",
+                    "enum": [
+                        "ENTER",
+                        "EXIT",
+                        "DWELL",
+                        "PASSED_THROUGH"
+                    ]
                 },
-                "limit": {
-                    "type": "integer"
+                "event_type": {
+                    "type": "string",
+                    "enum": [
+                        "geofence",
+                        "proximity"
+                    ]
                 },
-                "offset": {
+                "incident_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "enum": [
+                        "cooldown"
+                    ]
+                },
+                "until": {
+                    "type": "string"
+                }
+            }
+        },
+        "location.TransitionResponse": {
+            "type": "object",
+            "properties": {
+                "event": {
+                    "type": "string",
+                    "enum": [
+                        "ENTER",
+                        "EXIT",
+                        "DWELL"
+                    ]
+                },
+                "incident_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "stats.IncidentStatsResponse": {
+            "type": "object",
+            "properties": {
+                "hit_count": {
                     "type": "integer"
                 },
-                "total": {
+                "incident_id": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "user_count": {
                     "type": "integer"
                 }
             }
         },
-        "incident.IncidentResponse": {
+        "stats.StatsBucketResponse": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
+                "hit_count": {
+                    "type": "integer"
                 },
-                "id": {
+                "start": {
                     "type": "string"
                 },
-                "is_active": {
+                "user_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "stats.StatsResponse": {
+            "type": "object",
+            "properties": {
+                "bucket_seconds": {
+                    "type": "integer"
+                },
+                "exact": {
+                    "description": "false — числа пользователей оценены HyperLogLog (погрешность около 1%)",
                     "type": "boolean"
                 },
-                "lat": {
-                    "type": "number"
+                "from": {
+                    "type": "string"
                 },
-                "lng": {
-                    "type": "number"
+                "incidents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/stats.IncidentStatsResponse"
+                    }
                 },
-                "radius": {
-                    "type": "number"
+                "series": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/stats.StatsBucketResponse"
+                    }
                 },
-                "title": {
+                "to": {
                     "type": "string"
+                },
+                "user_count": {
+                    "description": "уникальные пользователи, попавшие хотя бы в одну зону",
+                    "type": "integer"
+                },
+                "window_seconds": {
+                    "type": "integer"
                 }
             }
         },
-        "incident.UpdateIncidentRequest": {
+        "webhook.AreaDTO": {
             "type": "object",
             "properties": {
-                "is_active": {
-                    "type": "boolean"
-                },
                 "lat": {
                     "type": "number"
                 },
@@ -467,30 +1699,130 @@ const docTemplate = `{
                     "type": "number"
                 },
                 "radius": {
+                    "description": "в метрах",
                     "type": "number"
+                }
+            }
+        },
+        "webhook.CreateSubscriptionRequest": {
+            "type": "object",
+            "properties": {
+                "area": {
+                    "$ref": "#/definitions/webhook.AreaDTO"
                 },
-                "title": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "fire",
+                        "flood"
+                    ]
+                },
+                "event_types": {
+                    "description": "incident приходит только если указан явно",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "geofence",
+                        "proximity"
+                    ]
+                },
+                "incident_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "min_severity": {
+                    "type": "string",
+                    "enum": [
+                        "info",
+                        "warning",
+                        "critical"
+                    ]
+                },
+                "payload_format": {
+                    "description": "по умолчанию json",
+                    "type": "string",
+                    "enum": [
+                        "json",
+                        "cloudevents",
+                        "cloudevents-binary"
+                    ]
+                },
+                "secret": {
+                    "description": "если не задан, генерируется",
                     "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://news.example.com/hooks/geo"
                 }
             }
         },
-        "location.CheckLocationRequest": {
+        "webhook.RotateSecretRequest": {
             "type": "object",
             "properties": {
-                "lat": {
-                    "type": "number"
+                "secret": {
+                    "description": "если не задан, генерируется",
+                    "type": "string"
+                }
+            }
+        },
+        "webhook.RotateSecretResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
                 },
-                "lng": {
-                    "type": "number"
+                "previous_secret_expires_at": {
+                    "description": "до этого времени доставки подписываются и прежним секретом",
+                    "type": "string"
                 },
-                "user_id": {
+                "secret": {
                     "type": "string"
                 }
             }
         },
-        "location.LocationResponse": {
+        "webhook.SubscriptionListResponse": {
+            "type": "object",
+            "properties": {
+                "subscriptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/webhook.SubscriptionResponse"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "webhook.SubscriptionResponse": {
             "type": "object",
             "properties": {
+                "area": {
+                    "$ref": "#/definitions/webhook.AreaDTO"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "id": {
                     "type": "string"
                 },
@@ -500,28 +1832,75 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
-                "is_check": {
+                "is_active": {
                     "type": "boolean"
                 },
-                "lat": {
-                    "type": "number"
+                "min_severity": {
+                    "type": "string"
                 },
-                "lng": {
-                    "type": "number"
+                "payload_format": {
+                    "type": "string"
                 },
-                "timestamp": {
+                "secret": {
+                    "description": "возвращается только при создании",
                     "type": "string"
                 },
-                "user_id": {
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
                     "type": "string"
                 }
             }
         },
-        "stats.StatsResponse": {
+        "webhook.UpdateSubscriptionRequest": {
             "type": "object",
             "properties": {
-                "user_count": {
-                    "type": "integer"
+                "area": {
+                    "$ref": "#/definitions/webhook.AreaDTO"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "incident_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "min_severity": {
+                    "type": "string",
+                    "enum": [
+                        "info",
+                        "warning",
+                        "critical"
+                    ]
+                },
+                "payload_format": {
+                    "type": "string",
+                    "enum": [
+                        "json",
+                        "cloudevents",
+                        "cloudevents-binary"
+                    ]
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         }
@@ -4,20 +4,26 @@ import (
 	"context"
 	"flag"
 	"log"
-	"os"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata" // часовые пояса расписаний инцидентов: в образе alpine нет zoneinfo
 
 	_ "github.com/Soujuruya/01_SPEC/cmd/api/docs"
 	"github.com/Soujuruya/01_SPEC/internal/config"
+	domainincident "github.com/Soujuruya/01_SPEC/internal/domain/incident"
+	domainlocation "github.com/Soujuruya/01_SPEC/internal/domain/location"
+	"github.com/Soujuruya/01_SPEC/internal/handler/http/deadletter"
 	"github.com/Soujuruya/01_SPEC/internal/handler/http/health"
 	"github.com/Soujuruya/01_SPEC/internal/handler/http/incident"
 	"github.com/Soujuruya/01_SPEC/internal/handler/http/location"
 	"github.com/Soujuruya/01_SPEC/internal/handler/http/middleware"
 	"github.com/Soujuruya/01_SPEC/internal/handler/http/stats"
+	"github.com/Soujuruya/01_SPEC/internal/handler/http/webhook"
 	"github.com/Soujuruya/01_SPEC/internal/integration"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/metrics"
 	redispkg "github.com/Soujuruya/01_SPEC/internal/pkg/redis"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/tracing"
 	"github.com/Soujuruya/01_SPEC/internal/repository/postgres"
 	"github.com/Soujuruya/01_SPEC/internal/repository/redis"
 	"github.com/Soujuruya/01_SPEC/internal/server"
@@ -25,6 +31,7 @@ import (
 	"github.com/Soujuruya/01_SPEC/internal/worker"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // @title 01_SPEC Geo-notification system core API
@@ -43,17 +50,33 @@ func main() {
 		panic("failed to load config: " + err.Error())
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	//Логгер
 	lg := logger.New(cfg.Environment)
 	defer func() { _ = lg.Sync() }()
 
+	// Трассировка
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		panic("failed to set up tracing: " + err.Error())
+	}
+	lg.Info("tracing configured", "exporter", cfg.Tracing.Exporter)
+
 	//  Postgres
 	dbURL := cfg.DB.DSN()
-	pgxPool, err := pgxpool.New(context.Background(), dbURL)
+	poolCfg, err := pgxpool.ParseConfig(dbURL)
+	if err != nil {
+		log.Fatal("failed to parse postgres dsn", "error", err)
+	}
+	poolCfg.ConnConfig.Tracer = tracing.NewPgxTracer()
+	pgxPool, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
 	if err != nil {
 		log.Fatal("failed to connect postgres", "error", err)
 	}
 	defer pgxPool.Close()
+	prometheus.MustRegister(metrics.NewPgxPoolCollector(pgxPool))
 	lg.Info("postgres connected")
 
 	// Redis
@@ -66,28 +89,104 @@ func main() {
 	lg.Info("redis connected")
 
 	//  Репозитории
-	incidentRepo := postgres.NewIncidentRepo(pgxPool, lg)
 	locationRepo := postgres.NewLocationRepo(pgxPool, lg)
+	subscriptionRepo := postgres.NewSubscriptionRepo(pgxPool, lg)
+	deadLetterRepo := postgres.NewDeadLetterRepo(pgxPool, lg)
+	outboxRepo := postgres.NewOutboxRepo(pgxPool, lg)
 
 	// Кэш и очередь
 	incidentCache := redis.NewIncidentCache(rdb, "active_incidents", cfg.CacheTTL, lg)
-	webhookQueue := redis.NewWebhookQueue(rdb, "webhook_queue", lg)
+	webhookQueue := redis.NewWebhookQueue(rdb, "webhook_queue", cfg.OutboxRetention, lg)
+	geofenceStore := redis.NewGeofenceStore(rdb, "geofence", cfg.GeofenceStateTTL, lg)
+	proximityStore := redis.NewGeofenceStore(rdb, "proximity", cfg.GeofenceStateTTL, lg)
+	cooldownStore := redis.NewCooldownStore(rdb, "notify_cooldown", lg)
+	// проверка одного пользователя не дольше обработки запроса
+	userLock := redis.NewUserLock(rdb, "user_lock", cfg.HandleTimeout, lg)
+
+	// Минутные счётчики статистики; STATS_COUNTER_RETENTION=0 — статистика только по locations
+	var statsCounter domainlocation.StatsCounter
+	if cfg.StatsCounterRetention > 0 {
+		statsCounter = redis.NewStatsCounter(rdb, "stats", cfg.StatsCounterRetention, lg)
+	}
+
+	// Инциденты: поиск зон либо в PostGIS, либо по индексу в памяти поверх кэша
+	var (
+		incidentRepo   domainincident.IncidentRepository
+		activeCache    domainincident.IncidentCache = incidentCache
+		incidentLookup domainincident.IncidentLocator
+	)
+	switch cfg.IncidentBackend {
+	case config.IncidentBackendPostGIS:
+		repo := postgres.NewPostGISIncidentRepo(pgxPool, lg)
+		incidentRepo, incidentLookup = repo, repo
+	default:
+		repo := postgres.NewIncidentRepo(pgxPool, lg)
+		index := usecase.NewIncidentIndex(repo, incidentCache, cfg.CacheTTL, cfg.IndexCellDegrees, lg)
+		incidentRepo, activeCache, incidentLookup = repo, index, index
+	}
+	lg.Info("incident backend selected", "backend", cfg.IncidentBackend)
 
 	//  Сервисы
-	incidentService := usecase.NewIncidentService(incidentRepo, incidentCache, lg)
-	locationService := usecase.NewLocationService(locationRepo, incidentRepo, webhookQueue, lg)
-	statsService := usecase.NewStatsService(locationRepo, lg)
+	incidentService := usecase.NewIncidentService(incidentRepo, activeCache, lg)
+	locationService := usecase.NewLocationService(locationRepo, incidentLookup, incidentRepo, geofenceStore, proximityStore, cooldownStore, statsCounter, userLock,
+		usecase.LocationOptions{
+			DwellAfter:       cfg.GeofenceDwellTime,
+			ProximityBuffer:  cfg.ProximityBufferMeters,
+			IndexCellDegrees: cfg.IndexCellDegrees,
+			TrajectoryMaxGap: cfg.TrajectoryMaxGap,
+			CertainOverlap:   cfg.CertainOverlap,
+			PossibleOverlap:  cfg.PossibleOverlap,
+			NotifyConfidence: cfg.WebhookConfidenceLevels,
+			NotifyCooldown:   cfg.NotifyCooldown,
+		}, lg)
+	statsService := usecase.NewStatsService(locationRepo, statsCounter, cfg.StatsCounterRetention, lg)
+	deadLetterService := usecase.NewDeadLetterService(deadLetterRepo, webhookQueue, lg)
+	subscriptionService := usecase.NewSubscriptionService(subscriptionRepo, cfg.CacheTTL, cfg.WebhookSecretGracePeriod, lg)
+
+	// Relay переносит события из outbox в очередь вебхуков
+	outboxRelay := worker.NewOutboxRelay(outboxRepo, webhookQueue, cfg.OutboxBatchSize, cfg.OutboxPollInterval, cfg.OutboxRetention, lg)
+	go outboxRelay.Run(ctx)
 
 	// Воркер для вебхуков
-	webhookClient := integration.NewWebhookClient(cfg.WebhookURL, cfg.HandleTimeout, lg)
-	worker := worker.NewWebhookWorker(rdb, "webhook_queue", webhookClient, cfg.RetryLimit, cfg.RetryDelay, lg)
-	go worker.Run(context.Background(), lg)
+	webhookRoutes, err := integration.ParseWebhookRoutes(cfg.WebhookRoutes)
+	if err != nil {
+		panic("failed to parse WEBHOOK_ROUTES: " + err.Error())
+	}
+	webhookRouter, err := integration.NewWebhookRouter(webhookRoutes, subscriptionService, cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookPayloadFormat)
+	if err != nil {
+		panic("failed to create webhook router: " + err.Error())
+	}
+	circuitBreaker := integration.NewCircuitBreaker(integration.BreakerOptions{
+		FailureRate: cfg.CircuitFailureRate,
+		MinRequests: cfg.CircuitMinRequests,
+		CoolDown:    cfg.CircuitCoolDown,
+	})
+	webhookClient := integration.NewWebhookClient(cfg.WebhookURL, cfg.HandleTimeout, webhookRouter, circuitBreaker, cfg.CloudEventsSource, lg)
+	webhookConsumer := redis.NewWebhookConsumer(rdb, "webhook_queue", "webhook_retry", cfg.WebhookVisibilityTimeout, cfg.WebhookDedupTTL, lg)
+	webhookWorker := worker.NewWebhookWorker(webhookConsumer, webhookClient, webhookRouter, deadLetterService,
+		cfg.RetryLimit, cfg.RetryDelay, cfg.RetryMaxDelay, cfg.RetryPollInterval,
+		worker.PoolOptions{
+			Workers:         cfg.WebhookWorkers,
+			PerDestination:  cfg.WebhookDestinationConcurrency,
+			ShutdownTimeout: cfg.WebhookShutdownTimeout,
+		}, lg)
+	workerDone := make(chan struct{})
+	go func() {
+		webhookWorker.Run(ctx, lg)
+		close(workerDone)
+	}()
+
+	// Планировщик включает и выключает инциденты по расписанию
+	scheduler := worker.NewIncidentScheduler(incidentService, cfg.IncidentScheduleInterval, lg)
+	go scheduler.Run(ctx)
 
 	// Хендлеры
-	healthHandler := health.NewHealthHandler(lg)
+	healthHandler := health.NewHealthHandler(circuitBreaker, lg)
 	incidentHandler := incident.NewIncidentHandler(incidentService, lg)
-	locationHandler := location.NewLocationHandler(locationService, lg)
+	locationHandler := location.NewLocationHandler(locationService, cfg, lg)
 	statsHandler := stats.NewStatsHandler(statsService, cfg, lg)
+	webhookHandler := webhook.NewWebhookHandler(subscriptionService, lg)
+	deadLetterHandler := deadletter.NewDeadLetterHandler(deadLetterService, lg)
 
 	//  HTTP Server
 	srv := server.NewServer(cfg,
@@ -95,7 +194,11 @@ func main() {
 		incidentHandler,
 		locationHandler,
 		statsHandler,
+		webhookHandler,
+		deadLetterHandler,
+		middleware.Tracing(),  //  спан запроса и traceparent
 		middleware.Logger(lg), //  middleware логирования
+		middleware.Metrics(),  //  метрики HTTP-запросов
 	)
 
 	go func() {
@@ -104,17 +207,26 @@ func main() {
 		}
 	}()
 
-	//Graceful shutdown
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-	<-stop
+	//Graceful shutdown: сигнал отменяет ctx, по нему останавливаются воркеры
+	<-ctx.Done()
+	stop()
 
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.HandleTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HandleTimeout)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatal("server shutdown failed", "error", err)
 	}
-
 	lg.Info("server stopped gracefully")
+
+	// пул вебхуков доотправляет начатое в пределах WEBHOOK_SHUTDOWN_TIMEOUT
+	<-workerDone
+	lg.Info("webhook worker stopped")
+
+	// дописываем накопленные спаны, в том числе последних отправок
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), cfg.HandleTimeout)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		lg.Error("failed to flush traces", "error", err)
+	}
 }
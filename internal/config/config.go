@@ -7,21 +7,82 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+const (
+	IncidentBackendPostgres = "postgres"
+	IncidentBackendPostGIS  = "postgis"
+)
+
+// MaxLocationBatch предел LOCATION_BATCH_MAX: пакет пишется одним INSERT по 13 параметров на точку,
+// а Postgres принимает не больше 65535 параметров в запросе
+const MaxLocationBatch = 5000
+
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
 type Config struct {
 	Environment string `env-required:"true" env:"ENV"`
 
 	DB    DBConfig    `env-required:"true" env-prefix:"DATABASE_"`
 	Redis RedisConfig `env-required:"true" env-prefix:"REDIS_"`
 
+	Tracing TracingConfig `env-prefix:"TRACING_"`
+
 	RetryLimit int           `env:"RETRY_LIMIT" env-default:"5"`
 	RetryDelay time.Duration `env:"RETRY_DELAY" env-default:"5s"`
 
-	WebhookURL             string `env-required:"true" env:"WEBHOOK_URL"`
-	StatsTimeWindowMinutes int    `env:"STATS_TIME_WINDOW_MINUTES" env-default:"5"`
+	RetryMaxDelay     time.Duration `env:"RETRY_MAX_DELAY" env-default:"10m"`
+	RetryPollInterval time.Duration `env:"RETRY_POLL_INTERVAL" env-default:"1s"`
+
+	WebhookVisibilityTimeout time.Duration `env:"WEBHOOK_VISIBILITY_TIMEOUT" env-default:"1m"`
+	WebhookDedupTTL          time.Duration `env:"WEBHOOK_DEDUP_TTL" env-default:"24h"`
+
+	WebhookWorkers                int           `env:"WEBHOOK_WORKERS" env-default:"4"`
+	WebhookDestinationConcurrency int           `env:"WEBHOOK_DESTINATION_CONCURRENCY" env-default:"2"`
+	WebhookShutdownTimeout        time.Duration `env:"WEBHOOK_SHUTDOWN_TIMEOUT" env-default:"10s"`
+
+	CircuitFailureRate float64       `env:"CIRCUIT_FAILURE_RATE" env-default:"0.5"`
+	CircuitMinRequests int           `env:"CIRCUIT_MIN_REQUESTS" env-default:"10"`
+	CircuitCoolDown    time.Duration `env:"CIRCUIT_COOL_DOWN" env-default:"30s"`
+
+	OutboxPollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" env-default:"1s"`
+	OutboxBatchSize    int           `env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+	OutboxRetention    time.Duration `env:"OUTBOX_RETENTION" env-default:"24h"`
+
+	WebhookURL             string        `env-required:"true" env:"WEBHOOK_URL"`
+	WebhookRoutes          string        `env:"WEBHOOK_ROUTES"`
+	WebhookSecret          string        `env:"WEBHOOK_SECRET"`
+	WebhookPayloadFormat   string        `env:"WEBHOOK_PAYLOAD_FORMAT" env-default:"json"`
+	CloudEventsSource      string        `env:"CLOUDEVENTS_SOURCE" env-default:"/01spec/api"`
+	StatsTimeWindowMinutes int           `env:"STATS_TIME_WINDOW_MINUTES" env-default:"5"`
+	StatsMaxWindow         time.Duration `env:"STATS_MAX_WINDOW" env-default:"168h"`
+	StatsCounterRetention  time.Duration `env:"STATS_COUNTER_RETENTION" env-default:"24h"`
 
 	HTTPPort      int           `env-required:"true" env:"HTTP_PORT"`
 	HandleTimeout time.Duration `env-required:"true" env:"HANDLE_TIMEOUT"`
 	CacheTTL      time.Duration `env-required:"true" env:"CACHE_TTL"`
+
+	GeofenceDwellTime time.Duration `env:"GEOFENCE_DWELL_TIME" env-default:"5m"`
+	GeofenceStateTTL  time.Duration `env:"GEOFENCE_STATE_TTL" env-default:"24h"`
+	NotifyCooldown    time.Duration `env:"NOTIFY_COOLDOWN" env-default:"5m"`
+
+	ProximityBufferMeters float64 `env:"PROXIMITY_BUFFER_METERS" env-default:"500"`
+	LocationBatchMax      int     `env:"LOCATION_BATCH_MAX" env-default:"500"`
+
+	TrajectoryMaxGap time.Duration `env:"TRAJECTORY_MAX_GAP" env-default:"10m"`
+
+	CertainOverlap          float64  `env:"CERTAIN_OVERLAP" env-default:"0.9"`
+	PossibleOverlap         float64  `env:"POSSIBLE_OVERLAP" env-default:"0.1"`
+	WebhookConfidenceLevels []string `env:"WEBHOOK_CONFIDENCE_LEVELS" env-default:"certain,possible" env-separator:","`
+
+	WebhookSecretGracePeriod time.Duration `env:"WEBHOOK_SECRET_GRACE_PERIOD" env-default:"24h"`
+
+	IncidentScheduleInterval time.Duration `env:"INCIDENT_SCHEDULE_INTERVAL" env-default:"30s"`
+
+	IncidentBackend  string  `env:"INCIDENT_BACKEND" env-default:"postgres"`
+	IndexCellDegrees float64 `env:"INDEX_CELL_DEGREES" env-default:"0.25"`
 }
 
 type DBConfig struct {
@@ -37,6 +98,14 @@ type RedisConfig struct {
 	Password string `env:"PASSWORD"`
 }
 
+// TracingConfig экспорт трассировки OpenTelemetry
+type TracingConfig struct {
+	Exporter     string  `env:"EXPORTER" env-default:"none"`  // none, stdout или otlp
+	OTLPEndpoint string  `env:"OTLP_ENDPOINT"`                // адрес коллектора OTLP/HTTP; пусто — из OTEL_EXPORTER_OTLP_ENDPOINT
+	SampleRatio  float64 `env:"SAMPLE_RATIO" env-default:"1"` // доля записываемых трасс без решения вызывающей стороны
+	ServiceName  string  `env:"SERVICE_NAME" env-default:"01spec-api"`
+}
+
 func (c DBConfig) DSN() string {
 	return fmt.Sprintf(
 		"postgres://%s:%s@%s:%d/%s?sslmode=disable",
@@ -57,5 +126,93 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	switch cfg.IncidentBackend {
+	case IncidentBackendPostgres, IncidentBackendPostGIS:
+	default:
+		return nil, fmt.Errorf("unknown INCIDENT_BACKEND %q, expected %q or %q",
+			cfg.IncidentBackend, IncidentBackendPostgres, IncidentBackendPostGIS)
+	}
+
+	switch cfg.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout, TracingExporterOTLP:
+	default:
+		return nil, fmt.Errorf("unknown TRACING_EXPORTER %q, expected %q, %q or %q",
+			cfg.Tracing.Exporter, TracingExporterNone, TracingExporterStdout, TracingExporterOTLP)
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		return nil, fmt.Errorf("TRACING_SAMPLE_RATIO must be in [0, 1], got %v", cfg.Tracing.SampleRatio)
+	}
+
+	if cfg.PossibleOverlap < 0 || cfg.PossibleOverlap > cfg.CertainOverlap || cfg.CertainOverlap > 1 {
+		return nil, fmt.Errorf("overlap thresholds must satisfy 0 <= POSSIBLE_OVERLAP <= CERTAIN_OVERLAP <= 1, got %v and %v",
+			cfg.PossibleOverlap, cfg.CertainOverlap)
+	}
+	for _, level := range cfg.WebhookConfidenceLevels {
+		if level != "certain" && level != "possible" {
+			return nil, fmt.Errorf("unknown WEBHOOK_CONFIDENCE_LEVELS value %q, expected certain or possible", level)
+		}
+	}
+
+	if cfg.IncidentScheduleInterval <= 0 {
+		return nil, fmt.Errorf("INCIDENT_SCHEDULE_INTERVAL must be positive, got %v", cfg.IncidentScheduleInterval)
+	}
+	if cfg.RetryPollInterval <= 0 {
+		return nil, fmt.Errorf("RETRY_POLL_INTERVAL must be positive, got %v", cfg.RetryPollInterval)
+	}
+	if cfg.WebhookVisibilityTimeout < 3*time.Second {
+		return nil, fmt.Errorf("WEBHOOK_VISIBILITY_TIMEOUT must be at least 3s, got %v", cfg.WebhookVisibilityTimeout)
+	}
+	switch cfg.WebhookPayloadFormat {
+	case "json", "cloudevents", "cloudevents-binary":
+	default:
+		return nil, fmt.Errorf("unknown WEBHOOK_PAYLOAD_FORMAT %q, expected json, cloudevents or cloudevents-binary", cfg.WebhookPayloadFormat)
+	}
+	if cfg.WebhookDedupTTL <= 0 {
+		return nil, fmt.Errorf("WEBHOOK_DEDUP_TTL must be positive, got %v", cfg.WebhookDedupTTL)
+	}
+	if cfg.WebhookWorkers <= 0 {
+		return nil, fmt.Errorf("WEBHOOK_WORKERS must be positive, got %d", cfg.WebhookWorkers)
+	}
+	if cfg.WebhookDestinationConcurrency <= 0 {
+		return nil, fmt.Errorf("WEBHOOK_DESTINATION_CONCURRENCY must be positive, got %d", cfg.WebhookDestinationConcurrency)
+	}
+	if cfg.LocationBatchMax <= 0 || cfg.LocationBatchMax > MaxLocationBatch {
+		return nil, fmt.Errorf("LOCATION_BATCH_MAX must be in (0, %d], got %d", MaxLocationBatch, cfg.LocationBatchMax)
+	}
+	if cfg.StatsTimeWindowMinutes <= 0 || time.Duration(cfg.StatsTimeWindowMinutes)*time.Minute > cfg.StatsMaxWindow {
+		return nil, fmt.Errorf("STATS_TIME_WINDOW_MINUTES must be positive and not exceed STATS_MAX_WINDOW %v, got %d", cfg.StatsMaxWindow, cfg.StatsTimeWindowMinutes)
+	}
+	if cfg.StatsCounterRetention < 0 {
+		return nil, fmt.Errorf("STATS_COUNTER_RETENTION must not be negative, got %v", cfg.StatsCounterRetention)
+	}
+	if cfg.NotifyCooldown < 0 {
+		return nil, fmt.Errorf("NOTIFY_COOLDOWN must not be negative, got %v", cfg.NotifyCooldown)
+	}
+
+	if cfg.WebhookShutdownTimeout < 0 {
+		return nil, fmt.Errorf("WEBHOOK_SHUTDOWN_TIMEOUT must not be negative, got %v", cfg.WebhookShutdownTimeout)
+	}
+	if cfg.CircuitFailureRate <= 0 || cfg.CircuitFailureRate > 1 {
+		return nil, fmt.Errorf("CIRCUIT_FAILURE_RATE must be in (0, 1], got %v", cfg.CircuitFailureRate)
+	}
+	if cfg.CircuitMinRequests <= 0 {
+		return nil, fmt.Errorf("CIRCUIT_MIN_REQUESTS must be positive, got %d", cfg.CircuitMinRequests)
+	}
+	if cfg.CircuitCoolDown <= 0 {
+		return nil, fmt.Errorf("CIRCUIT_COOL_DOWN must be positive, got %v", cfg.CircuitCoolDown)
+	}
+	if cfg.OutboxPollInterval <= 0 {
+		return nil, fmt.Errorf("OUTBOX_POLL_INTERVAL must be positive, got %v", cfg.OutboxPollInterval)
+	}
+	if cfg.OutboxBatchSize <= 0 {
+		return nil, fmt.Errorf("OUTBOX_BATCH_SIZE must be positive, got %d", cfg.OutboxBatchSize)
+	}
+	if cfg.OutboxRetention <= 0 {
+		return nil, fmt.Errorf("OUTBOX_RETENTION must be positive, got %v", cfg.OutboxRetention)
+	}
+	if cfg.RetryMaxDelay < cfg.RetryDelay {
+		return nil, fmt.Errorf("RETRY_MAX_DELAY must not be less than RETRY_DELAY, got %v and %v", cfg.RetryMaxDelay, cfg.RetryDelay)
+	}
+
 	return &cfg, nil
 }
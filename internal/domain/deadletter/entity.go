@@ -0,0 +1,43 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attempt одна неудачная попытка доставки
+type Attempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"` // HTTP-статус ответа; 0 — ответа не было
+	Error      string    `json:"error"`
+}
+
+// DeadLetter событие, которое не удалось доставить за отведённое число попыток
+type DeadLetter struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID *uuid.UUID      `json:"subscription_id,omitempty"`
+	URL            string          `json:"url"`
+	EventType      string          `json:"event_type"`
+	UserID         uuid.UUID       `json:"user_id"`
+	Payload        json.RawMessage `json:"payload"` // событие в виде, готовом для повторной постановки в очередь
+	LastError      string          `json:"last_error"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	Attempts       []Attempt       `json:"attempts"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+func NewDeadLetter(payload json.RawMessage, attempts []Attempt) *DeadLetter {
+	d := &DeadLetter{
+		ID:        uuid.New(),
+		Payload:   payload,
+		Attempts:  attempts,
+		CreatedAt: time.Now(),
+	}
+	if len(attempts) > 0 {
+		last := attempts[len(attempts)-1]
+		d.LastError, d.LastStatusCode = last.Error, last.StatusCode
+	}
+	return d
+}
@@ -0,0 +1,21 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+type DeadLetterRepository interface {
+	Create(ctx context.Context, d *DeadLetter) error
+	GetByID(ctx context.Context, id uuid.UUID) (*DeadLetter, error)
+	List(ctx context.Context, offset, limit int) ([]*DeadLetter, int, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	Purge(ctx context.Context) (int64, error)
+}
+
+// Queue очередь вебхуков, в которую возвращаются события при повторной отправке
+type Queue interface {
+	Push(ctx context.Context, payload json.RawMessage) error
+}
@@ -0,0 +1,52 @@
+package incident
+
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+const (
+	CategoryFire    = "fire"
+	CategoryFlood   = "flood"
+	CategoryPolice  = "police"
+	CategoryTraffic = "traffic"
+	CategoryMedical = "medical"
+	CategoryWeather = "weather"
+	CategoryOther   = "other"
+)
+
+var severityRank = map[string]int{
+	SeverityInfo:     1,
+	SeverityWarning:  2,
+	SeverityCritical: 3,
+}
+
+var categories = map[string]bool{
+	CategoryFire:    true,
+	CategoryFlood:   true,
+	CategoryPolice:  true,
+	CategoryTraffic: true,
+	CategoryMedical: true,
+	CategoryWeather: true,
+	CategoryOther:   true,
+}
+
+// SeverityRank порядок уровней важности для сравнения; 0 для неизвестного уровня
+func SeverityRank(severity string) int {
+	return severityRank[severity]
+}
+
+func IsSeverity(v string) bool {
+	return severityRank[v] > 0
+}
+
+func IsCategory(v string) bool {
+	return categories[v]
+}
+
+// ListFilter фильтр списка инцидентов; пустое поле не ограничивает выборку
+type ListFilter struct {
+	Severities []string
+	Categories []string
+}
@@ -0,0 +1,132 @@
+package incident
+
+import (
+	"math"
+)
+
+func toRad(deg float64) float64 { return deg * math.Pi / 180.0 }
+func toDeg(rad float64) float64 { return rad * 180.0 / math.Pi }
+
+// HaversineMeters расстояние между двумя точками на сфере
+func HaversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1, phi2 := toRad(lat1), toRad(lat2)
+	dPhi := phi2 - phi1
+	dLambda := toRad(lng2 - lng1)
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*
+			math.Sin(dLambda/2)*math.Sin(dLambda/2)
+
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return EarthRadiusMeters * c
+}
+
+// BearingDegrees начальный азимут из первой точки во вторую, 0..360 по часовой от севера
+func BearingDegrees(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1, phi2 := toRad(lat1), toRad(lat2)
+	dLambda := toRad(lng2 - lng1)
+
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+	return math.Mod(toDeg(math.Atan2(y, x))+360, 360)
+}
+
+// destination точка на расстоянии distance метров от исходной по азимуту bearing
+func destination(lat, lng, bearing, distance float64) (float64, float64) {
+	phi1, lambda1, theta := toRad(lat), toRad(lng), toRad(bearing)
+	delta := distance / EarthRadiusMeters
+
+	phi2 := math.Asin(math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta))
+	lambda2 := lambda1 + math.Atan2(
+		math.Sin(theta)*math.Sin(delta)*math.Cos(phi1),
+		math.Cos(delta)-math.Sin(phi1)*math.Sin(phi2),
+	)
+	return toDeg(phi2), normalizeLng(toDeg(lambda2))
+}
+
+// Within проверяет, что точка внутри зоны либо не дальше meters от её границы
+func (i *Incident) Within(lat, lng, meters float64) bool {
+	if i.Contains(lat, lng) {
+		return true
+	}
+	if meters <= 0 {
+		return false
+	}
+	d, _, _ := i.EdgeDistance(lat, lng)
+	return d <= meters
+}
+
+// EdgeDistance расстояние от точки до ближайшей точки границы зоны и сама эта точка.
+// Работает одинаково для точек внутри и снаружи зоны
+func (i *Incident) EdgeDistance(lat, lng float64) (meters, edgeLat, edgeLng float64) {
+	if i.Geometry != nil {
+		return i.Geometry.EdgeDistance(lat, lng)
+	}
+
+	d := HaversineMeters(i.Lat, i.Lng, lat, lng)
+	bearing := 0.0
+	if d > 0 {
+		bearing = BearingDegrees(i.Lat, i.Lng, lat, lng)
+	}
+	edgeLat, edgeLng = destination(i.Lat, i.Lng, bearing, i.Radius)
+	return math.Abs(d - i.Radius), edgeLat, edgeLng
+}
+
+// EdgeDistance ближайшая точка на контурах геометрии. Рёбра проецируются на локальную
+// равнопромежуточную плоскость вокруг точки, чего достаточно для расстояний до сотен километров
+func (g *Geometry) EdgeDistance(lat, lng float64) (meters, edgeLat, edgeLng float64) {
+	meters = math.Inf(1)
+	p := newLocalPlane(lat, lng)
+
+	for _, poly := range g.Polygons {
+		for _, ring := range poly {
+			for k := 1; k < len(ring); k++ {
+				ax, ay := p.project(ring[k-1].Lat(), ring[k-1].Lng())
+				bx, by := p.project(ring[k].Lat(), ring[k].Lng())
+				cx, cy := closestOnSegment(0, 0, ax, ay, bx, by)
+
+				if d := math.Hypot(cx, cy); d < meters {
+					meters = d
+					edgeLat, edgeLng = p.unproject(cx, cy)
+				}
+			}
+		}
+	}
+	return meters, edgeLat, edgeLng
+}
+
+// localPlane равнопромежуточная проекция в метрах с началом в заданной точке
+type localPlane struct {
+	lat0, lng0 float64
+	kx         float64
+}
+
+func newLocalPlane(lat, lng float64) localPlane {
+	return localPlane{lat0: lat, lng0: lng, kx: metersPerDegree * math.Cos(toRad(lat))}
+}
+
+func (p localPlane) project(lat, lng float64) (x, y float64) {
+	return normalizeLng(lng-p.lng0) * p.kx, (lat - p.lat0) * metersPerDegree
+}
+
+func (p localPlane) unproject(x, y float64) (lat, lng float64) {
+	lat = p.lat0 + y/metersPerDegree
+	lng = p.lng0
+	if p.kx > 0 {
+		lng += x / p.kx
+	}
+	return lat, normalizeLng(lng)
+}
+
+// closestOnSegment ближайшая к (px, py) точка отрезка a-b
+func closestOnSegment(px, py, ax, ay, bx, by float64) (float64, float64) {
+	dx, dy := bx-ax, by-ay
+	lenSq := dx*dx + dy*dy
+	if lenSq == 0 {
+		return ax, ay
+	}
+
+	t := ((px-ax)*dx + (py-ay)*dy) / lenSq
+	t = math.Max(0, math.Min(1, t))
+	return ax + t*dx, ay + t*dy
+}
@@ -1,7 +1,6 @@
 package incident
 
 import (
-	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,9 +14,18 @@ type Incident struct {
 	Lat       float64   `json:"lat"`        // широта зоны инцидента
 	Lng       float64   `json:"lng"`        // долгота зоны зоны инцидента
 	Radius    float64   `json:"radius"`     // радиус зоны инцидента
+	Geometry  *Geometry `json:"geometry"`   // зона произвольной формы, если задана — радиус не используется
+	Severity  string    `json:"severity"`   // важность: info, warning, critical
+	Category  string    `json:"category"`   // категория: fire, flood, police, traffic, ...
 	IsActive  bool      `json:"is_active"`  // активность
 	CreatedAt time.Time `json:"created_at"` // дата появления
 	UpdatedAt time.Time `json:"-"`          // дата изменения информации об инциденте
+
+	StartsAt   *time.Time  `json:"starts_at,omitempty"`  // запланированное начало
+	EndsAt     *time.Time  `json:"ends_at,omitempty"`    // автоматическое окончание
+	Recurrence *Recurrence `json:"recurrence,omitempty"` // повторяющееся окно активности
+
+	NotifyCooldown *time.Duration `json:"notify_cooldown,omitempty"` // окно повтора уведомлений по зоне; nil — глобальная настройка
 }
 
 func NewIncident(title string, lat, lng, radius float64, isActive bool) *Incident {
@@ -27,28 +35,38 @@ func NewIncident(title string, lat, lng, radius float64, isActive bool) *Inciden
 		Lat:       lat,
 		Lng:       lng,
 		Radius:    radius,
+		Severity:  SeverityWarning,
+		Category:  CategoryOther,
 		IsActive:  isActive,
 		CreatedAt: time.Now(),
 	}
 }
 
+// SetGeometry задаёт зону произвольной формы, координаты инцидента становятся центром геометрии
+func (i *Incident) SetGeometry(g *Geometry) {
+	i.Geometry = g
+	i.Lat, i.Lng = g.Center()
+	i.Radius = 0
+}
+
+// Contains проверяет попадание точки в зону инцидента: полигон, если он задан, иначе круг
+func (i *Incident) Contains(lat, lng float64) bool {
+	if i.Geometry != nil {
+		return i.Geometry.Contains(lat, lng)
+	}
+	return i.IsPointInRadius(lat, lng)
+}
+
+// WasActiveAt был ли инцидент активен в момент t. Выключение записывает своё время в EndsAt (см. SetActive),
+// поэтому для выключенного инцидента это окно до EndsAt; инцидент без расписания активен с создания, если включён
+func (i *Incident) WasActiveAt(t time.Time) bool {
+	if i.CreatedAt.After(t) {
+		return false
+	}
+	return i.ActiveAt(t)
+}
+
 // IsPointInRadius Вычисление расстояние между двумя точками на сфере
 func (i *Incident) IsPointInRadius(lat, lng float64) bool {
-	//переводим градусы в радианы
-	lat1 := i.Lat * math.Pi / 180.0
-	lng1 := i.Lng * math.Pi / 180.0
-	lat2 := lat * math.Pi / 180.0
-	lng2 := lng * math.Pi / 180.0
-
-	dLat := lat2 - lat1
-	dLng := lng2 - lng1
-	//формулы
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1)*math.Cos(lat2)*
-			math.Sin(dLng/2)*math.Sin(dLng/2)
-
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-	distanceMeters := EarthRadiusMeters * c
-
-	return distanceMeters <= i.Radius
+	return HaversineMeters(i.Lat, i.Lng, lat, lng) <= i.Radius
 }
@@ -0,0 +1,111 @@
+package incident
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIncidentWasActiveAt(t *testing.T) {
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	deactivated := created.Add(2 * time.Hour)
+	edited := created.Add(5 * time.Hour)
+
+	incidentAt := func(active bool) *Incident {
+		inc := circle(55.75, 37.61, 500)
+		inc.IsActive = active
+		inc.CreatedAt, inc.UpdatedAt = created, created
+		return inc
+	}
+
+	active := incidentAt(true)
+
+	createdInactive := incidentAt(false)
+	createdInactive.UpdatedAt = edited // правка названия не делает инцидент активным задним числом
+
+	// выключен через PUT, затем отредактирован
+	switchedOff := incidentAt(true)
+	switchedOff.SetActive(false, deactivated)
+	switchedOff.UpdatedAt = edited
+
+	// выключен и снова включён
+	switchedOn := incidentAt(true)
+	switchedOn.SetActive(false, deactivated)
+	switchedOn.SetActive(true, edited)
+
+	starts, ends := created.Add(time.Hour), created.Add(3*time.Hour)
+	scheduled := incidentAt(false)
+	scheduled.StartsAt, scheduled.EndsAt = &starts, &ends
+
+	tests := []struct {
+		name string
+		inc  *Incident
+		at   time.Time
+		want bool
+	}{
+		{"active before creation", active, created.Add(-time.Minute), false},
+		{"active after creation", active, created.Add(time.Minute), true},
+		{"created inactive", createdInactive, created.Add(time.Hour), false},
+		{"created inactive before edit", createdInactive, edited.Add(-time.Minute), false},
+		{"switched off before deactivation", switchedOff, deactivated.Add(-time.Minute), true},
+		{"switched off at deactivation", switchedOff, deactivated, false},
+		{"switched off before edit", switchedOff, edited.Add(-time.Minute), false},
+		{"switched on again", switchedOn, edited.Add(time.Minute), true},
+		{"scheduled before window", scheduled, starts.Add(-time.Minute), false},
+		{"scheduled in window", scheduled, starts.Add(time.Minute), true},
+		{"scheduled after window", scheduled, ends, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.inc.WasActiveAt(tt.at); got != tt.want {
+				t.Fatalf("WasActiveAt(%s) = %v, want %v", tt.at.Format(time.RFC3339), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncidentSetActiveRecordsDeactivation(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	inc := circle(55.75, 37.61, 500)
+	inc.SetActive(false, now)
+	if inc.IsActive || inc.EndsAt == nil || !inc.EndsAt.Equal(now) {
+		t.Fatalf("after SetActive(false): is_active=%v ends_at=%v, want false and %v", inc.IsActive, inc.EndsAt, now)
+	}
+
+	// более раннее окончание не сдвигается
+	inc.SetActive(false, now.Add(time.Hour))
+	if !inc.EndsAt.Equal(now) {
+		t.Fatalf("repeated SetActive(false) moved ends_at to %v", inc.EndsAt)
+	}
+
+	inc.SetActive(true, now.Add(2*time.Hour))
+	if !inc.IsActive || inc.EndsAt != nil || inc.Scheduled() {
+		t.Fatalf("after SetActive(true): is_active=%v ends_at=%v, want true and no schedule", inc.IsActive, inc.EndsAt)
+	}
+}
+
+func TestIncidentSetActiveBeforeStart(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	starts, ends := now.Add(time.Hour), now.Add(3*time.Hour)
+
+	inc := circle(55.75, 37.61, 500)
+	inc.StartsAt, inc.EndsAt = &starts, &ends
+	inc.SetActive(true, now)
+
+	// иначе UpdateIncident пересчитал бы is_active по расписанию и сохранил false
+	if !inc.ActiveAt(now) || inc.StartsAt == nil || !inc.StartsAt.Equal(now) || !inc.EndsAt.Equal(ends) {
+		t.Fatalf("after SetActive(true): starts_at=%v ends_at=%v active=%v, want starts_at moved to %v", inc.StartsAt, inc.EndsAt, inc.ActiveAt(now), now)
+	}
+	// проверки задним числом не видят инцидент активным до включения
+	if inc.WasActiveAt(now.Add(-time.Minute)) {
+		t.Fatal("WasActiveAt before activation = true, want false")
+	}
+
+	// окно повторения включение не открывает
+	nightly := circle(55.75, 37.61, 500)
+	nightly.Recurrence = &Recurrence{Frequency: RecurrenceDaily, StartTime: "22:00", EndTime: "06:00"}
+	nightly.SetActive(true, now)
+	if nightly.ActiveAt(now) {
+		t.Fatal("SetActive(true) opened a closed recurrence window")
+	}
+}
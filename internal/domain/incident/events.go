@@ -0,0 +1,12 @@
+package incident
+
+// EventLifecycle тип события вебхука об изменении инцидента
+const EventLifecycle = "incident"
+
+// Что произошло с инцидентом
+const (
+	ActionCreated     = "created"
+	ActionUpdated     = "updated"
+	ActionActivated   = "activated"   // включён по расписанию
+	ActionDeactivated = "deactivated" // выключен вручную или по расписанию
+)
@@ -0,0 +1,229 @@
+package incident
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	GeometryPolygon      = "Polygon"
+	GeometryMultiPolygon = "MultiPolygon"
+)
+
+// Position точка в порядке GeoJSON: [долгота, широта]
+type Position [2]float64
+
+func (p Position) Lng() float64 { return p[0] }
+func (p Position) Lat() float64 { return p[1] }
+
+// Ring замкнутый контур полигона (первая точка совпадает с последней)
+type Ring []Position
+
+// Polygon первый контур внешний, остальные — дыры
+type Polygon []Ring
+
+// Geometry зона инцидента произвольной формы (GeoJSON Polygon/MultiPolygon)
+type Geometry struct {
+	Type     string
+	Polygons []Polygon
+}
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ParseGeometry разбирает GeoJSON геометрию по типу и координатам и проверяет её корректность
+func ParseGeometry(geomType string, coordinates json.RawMessage) (*Geometry, error) {
+	g := &Geometry{Type: geomType}
+
+	switch geomType {
+	case GeometryPolygon:
+		var p Polygon
+		if err := json.Unmarshal(coordinates, &p); err != nil {
+			return nil, fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+		g.Polygons = []Polygon{p}
+	case GeometryMultiPolygon:
+		if err := json.Unmarshal(coordinates, &g.Polygons); err != nil {
+			return nil, fmt.Errorf("invalid multipolygon coordinates: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q, expected Polygon or MultiPolygon", geomType)
+	}
+
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *Geometry) Validate() error {
+	if len(g.Polygons) == 0 {
+		return errors.New("geometry must contain at least one polygon")
+	}
+	if g.Type == GeometryPolygon && len(g.Polygons) != 1 {
+		return errors.New("polygon geometry must contain exactly one polygon")
+	}
+
+	for pi, p := range g.Polygons {
+		if len(p) == 0 {
+			return fmt.Errorf("polygon %d has no rings", pi)
+		}
+		for ri, r := range p {
+			if len(r) < 4 {
+				return fmt.Errorf("polygon %d ring %d must have at least 4 positions, got %d", pi, ri, len(r))
+			}
+			if r[0] != r[len(r)-1] {
+				return fmt.Errorf("polygon %d ring %d is not closed", pi, ri)
+			}
+			if r.area() == 0 {
+				return fmt.Errorf("polygon %d ring %d is degenerate: its points do not enclose an area", pi, ri)
+			}
+			for _, pos := range r {
+				if pos.Lat() < -90 || pos.Lat() > 90 {
+					return fmt.Errorf("latitude must be between -90 and 90, got %f", pos.Lat())
+				}
+				if pos.Lng() < -180 || pos.Lng() > 180 {
+					return fmt.Errorf("longitude must be between -180 and 180, got %f", pos.Lng())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (g Geometry) MarshalJSON() ([]byte, error) {
+	var coords any = g.Polygons
+	if g.Type == GeometryPolygon && len(g.Polygons) == 1 {
+		coords = g.Polygons[0]
+	}
+
+	raw, err := json.Marshal(coords)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(geoJSON{Type: g.Type, Coordinates: raw})
+}
+
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	var raw geoJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	parsed, err := ParseGeometry(raw.Type, raw.Coordinates)
+	if err != nil {
+		return err
+	}
+	*g = *parsed
+	return nil
+}
+
+// Contains проверяет попадание точки в геометрию с учётом дыр и пересечения антимеридиана
+func (g *Geometry) Contains(lat, lng float64) bool {
+	for _, p := range g.Polygons {
+		if p.contains(lat, lng) {
+			return true
+		}
+	}
+	return false
+}
+
+// Center центр охватывающего прямоугольника внешних контуров
+func (g *Geometry) Center() (lat, lng float64) {
+	box := g.BBox()
+	if math.IsInf(box.MinLat, 1) {
+		return 0, 0
+	}
+	return (box.MinLat + box.MaxLat) / 2, normalizeLng((box.MinLng + box.MaxLng) / 2)
+}
+
+// BBox охватывающий прямоугольник внешних контуров
+func (g *Geometry) BBox() BBox {
+	box := BBox{MinLat: math.Inf(1), MinLng: math.Inf(1), MaxLat: math.Inf(-1), MaxLng: math.Inf(-1)}
+	for _, p := range g.Polygons {
+		if len(p) == 0 {
+			continue
+		}
+		for _, pos := range p[0].unwrap() {
+			box.MinLat = math.Min(box.MinLat, pos.Lat())
+			box.MaxLat = math.Max(box.MaxLat, pos.Lat())
+			box.MinLng = math.Min(box.MinLng, pos.Lng())
+			box.MaxLng = math.Max(box.MaxLng, pos.Lng())
+		}
+	}
+	return box
+}
+
+func (p Polygon) contains(lat, lng float64) bool {
+	if len(p) == 0 || !p[0].contains(lat, lng) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if hole.contains(lat, lng) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Ring) contains(lat, lng float64) bool {
+	pts := r.unwrap()
+	// развёрнутый контур может выходить за ±180, поэтому проверяем точку и её копии со сдвигом на 360°
+	for _, shift := range []float64{0, 360, -360} {
+		if pointInRing(pts, lat, lng+shift) {
+			return true
+		}
+	}
+	return false
+}
+
+// unwrap делает долготы контура непрерывными: соседние вершины отличаются не больше чем на 180°
+func (r Ring) unwrap() Ring {
+	if len(r) == 0 {
+		return r
+	}
+
+	out := make(Ring, len(r))
+	out[0] = r[0]
+	for i := 1; i < len(r); i++ {
+		d := normalizeLng(r[i].Lng() - r[i-1].Lng())
+		out[i] = Position{out[i-1].Lng() + d, r[i].Lat()}
+	}
+	return out
+}
+
+// area площадь контура в квадратных градусах (формула шнурования), 0 — точки на одной линии
+func (r Ring) area() float64 {
+	pts := r.unwrap()
+	var sum float64
+	for i := 1; i < len(pts); i++ {
+		sum += pts[i-1].Lng()*pts[i].Lat() - pts[i].Lng()*pts[i-1].Lat()
+	}
+	return math.Abs(sum) / 2
+}
+
+// pointInRing классический ray casting (even-odd) в координатах долгота/широта
+func pointInRing(pts Ring, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(pts)-1; i < len(pts); j, i = i, i+1 {
+		xi, yi := pts[i].Lng(), pts[i].Lat()
+		xj, yj := pts[j].Lng(), pts[j].Lat()
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// normalizeLng приводит долготу к диапазону [-180, 180)
+func normalizeLng(lng float64) float64 {
+	lng = math.Mod(lng+180, 360)
+	if lng < 0 {
+		lng += 360
+	}
+	return lng - 180
+}
@@ -0,0 +1,113 @@
+package incident
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func mustParse(t *testing.T, geomType, coordinates string) *Geometry {
+	t.Helper()
+	g, err := ParseGeometry(geomType, json.RawMessage(coordinates))
+	if err != nil {
+		t.Fatalf("ParseGeometry(%s): %v", geomType, err)
+	}
+	return g
+}
+
+type probe struct {
+	name     string
+	lat, lng float64
+	want     bool
+}
+
+func checkContains(t *testing.T, g *Geometry, probes []probe) {
+	t.Helper()
+	for _, p := range probes {
+		t.Run(p.name, func(t *testing.T) {
+			if got := g.Contains(p.lat, p.lng); got != p.want {
+				t.Fatalf("Contains(%v, %v) = %v, want %v", p.lat, p.lng, got, p.want)
+			}
+		})
+	}
+}
+
+func TestGeometryContainsHole(t *testing.T) {
+	// квадрат 10×10° с дырой 4×4° в центре
+	g := mustParse(t, GeometryPolygon, `[
+		[[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]],
+		[[3, 3], [7, 3], [7, 7], [3, 7], [3, 3]]
+	]`)
+
+	checkContains(t, g, []probe{
+		{"inside outer ring", 1, 1, true},
+		{"between hole and edge", 5, 8, true},
+		{"inside hole", 5, 5, false},
+		{"outside", 11, 5, false},
+	})
+}
+
+func TestGeometryContainsMultiPolygon(t *testing.T) {
+	g := mustParse(t, GeometryMultiPolygon, `[
+		[[[0, 0], [2, 0], [2, 2], [0, 2], [0, 0]]],
+		[[[10, 10], [14, 10], [14, 14], [10, 14], [10, 10]], [[11, 11], [13, 11], [13, 13], [11, 13], [11, 11]]]
+	]`)
+
+	checkContains(t, g, []probe{
+		{"first polygon", 1, 1, true},
+		{"second polygon", 10.5, 10.5, true},
+		{"hole of second polygon", 12, 12, false},
+		{"between polygons", 5, 5, false},
+	})
+}
+
+func TestGeometryContainsAntimeridian(t *testing.T) {
+	// прямоугольник от 170° в.д. до 170° з.д. через ±180
+	g := mustParse(t, GeometryPolygon, `[
+		[[170, -5], [-170, -5], [-170, 5], [170, 5], [170, -5]],
+		[[178, -1], [-178, -1], [-178, 1], [178, 1], [178, -1]]
+	]`)
+
+	checkContains(t, g, []probe{
+		{"east side", 0, 175, true},
+		{"west side", 0, -175, true},
+		{"on the antimeridian", 3, 180, true},
+		{"on the antimeridian as -180", 3, -180, true},
+		{"hole east side", 0, 179, false},
+		{"hole west side", 0, -179, false},
+		{"east of zone", 0, -160, false},
+		{"west of zone", 0, 160, false},
+		{"greenwich", 0, 0, false},
+	})
+
+	if lat, lng := g.Center(); lat != 0 || (lng != 180 && lng != -180) {
+		t.Fatalf("Center() = (%v, %v), want (0, ±180)", lat, lng)
+	}
+}
+
+func TestParseGeometryRejectsInvalidRings(t *testing.T) {
+	tests := []struct {
+		name        string
+		geomType    string
+		coordinates string
+		wantErr     string
+	}{
+		{"open ring", GeometryPolygon, `[[[0, 0], [1, 0], [1, 1], [0, 1]]]`, "not closed"},
+		{"too few positions", GeometryPolygon, `[[[0, 0], [1, 0], [0, 0]]]`, "at least 4 positions"},
+		{"collinear points", GeometryPolygon, `[[[0, 0], [1, 1], [2, 2], [0, 0]]]`, "degenerate"},
+		{"repeated point", GeometryPolygon, `[[[5, 5], [5, 5], [5, 5], [5, 5]]]`, "degenerate"},
+		{"open hole", GeometryPolygon, `[[[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]], [[3, 3], [7, 3], [7, 7], [3, 7]]]`, "ring 1 is not closed"},
+		{"open ring in multipolygon", GeometryMultiPolygon, `[[[[0, 0], [1, 0], [1, 1], [0, 0]]], [[[5, 5], [6, 5], [6, 6], [5, 6]]]]`, "polygon 1 ring 0 is not closed"},
+		{"no rings", GeometryPolygon, `[]`, "no rings"},
+		{"latitude out of range", GeometryPolygon, `[[[0, 0], [1, 0], [1, 91], [0, 0]]]`, "latitude"},
+		{"unknown type", "Point", `[0, 0]`, "unsupported geometry type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGeometry(tt.geomType, json.RawMessage(tt.coordinates))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseGeometry() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
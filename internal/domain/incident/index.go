@@ -0,0 +1,240 @@
+package incident
+
+import (
+	"math"
+)
+
+const (
+	DefaultIndexCellDegrees = 0.25
+
+	// зоны, покрывающие больше ячеек, хранятся отдельным списком и проверяются всегда
+	maxCellsPerIncident = 1024
+	metersPerDegree     = EarthRadiusMeters * math.Pi / 180.0
+)
+
+// BBox охватывающий прямоугольник зоны. Для зон через антимеридиан долготы могут выходить за ±180
+type BBox struct {
+	MinLat, MinLng float64
+	MaxLat, MaxLng float64
+}
+
+// BBox возвращает охватывающий прямоугольник зоны инцидента
+func (i *Incident) BBox() BBox {
+	if i.Geometry != nil {
+		return i.Geometry.BBox()
+	}
+	return CircleBBox(i.Lat, i.Lng, i.Radius)
+}
+
+// CircleBBox охватывающий прямоугольник круга радиусом radius метров
+func CircleBBox(lat, lng, radius float64) BBox {
+	dLat := radius / metersPerDegree
+	box := BBox{
+		MinLat: math.Max(lat-dLat, -90),
+		MaxLat: math.Min(lat+dLat, 90),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+
+	// у полюса круг накрывает все долготы
+	cosLat := math.Cos(toRad(lat))
+	if box.MinLat > -90 && box.MaxLat < 90 && cosLat > 1e-9 {
+		dLng := dLat / cosLat
+		if dLng < 180 {
+			box.MinLng = lng - dLng
+			box.MaxLng = lng + dLng
+		}
+	}
+	return box
+}
+
+type cellKey struct {
+	lat, lng int
+}
+
+// SpatialIndex сетка из ячеек фиксированного размера в градусах (аналог geohash-бакетов).
+// Каждый инцидент попадает во все ячейки, которые пересекает его охватывающий прямоугольник,
+// поэтому поиск по точке проверяет только инциденты одной ячейки
+type SpatialIndex struct {
+	cell  float64
+	nLat  int
+	nLng  int
+	cells map[cellKey][]*Incident
+	wide  []*Incident
+	size  int
+}
+
+func NewSpatialIndex(incs []*Incident, cellDegrees float64) *SpatialIndex {
+	if cellDegrees <= 0 {
+		cellDegrees = DefaultIndexCellDegrees
+	}
+
+	x := &SpatialIndex{
+		cell:  cellDegrees,
+		nLat:  int(math.Ceil(180 / cellDegrees)),
+		nLng:  int(math.Ceil(360 / cellDegrees)),
+		cells: make(map[cellKey][]*Incident),
+		size:  len(incs),
+	}
+	for _, inc := range incs {
+		x.insert(inc)
+	}
+	return x
+}
+
+func (x *SpatialIndex) insert(inc *Incident) {
+	keys, ok := x.cellsOf(inc.BBox())
+	if !ok {
+		x.wide = append(x.wide, inc)
+		return
+	}
+	for _, key := range keys {
+		x.cells[key] = append(x.cells[key], inc)
+	}
+}
+
+// cellsOf ячейки, которые пересекает прямоугольник; false — если их слишком много
+func (x *SpatialIndex) cellsOf(box BBox) ([]cellKey, bool) {
+	if box.MinLat > box.MaxLat || box.MinLng > box.MaxLng {
+		return nil, false
+	}
+
+	latFrom, latTo := x.latIndex(box.MinLat), x.latIndex(box.MaxLat)
+	lngFrom := int(math.Floor((box.MinLng + 180) / x.cell))
+	lngTo := int(math.Floor((box.MaxLng + 180) / x.cell))
+	if lngTo-lngFrom+1 > x.nLng {
+		lngTo = lngFrom + x.nLng - 1
+	}
+
+	if (latTo-latFrom+1)*(lngTo-lngFrom+1) > maxCellsPerIncident {
+		return nil, false
+	}
+
+	keys := make([]cellKey, 0, (latTo-latFrom+1)*(lngTo-lngFrom+1))
+	for la := latFrom; la <= latTo; la++ {
+		for ln := lngFrom; ln <= lngTo; ln++ {
+			keys = append(keys, cellKey{lat: la, lng: x.wrapLng(ln)})
+		}
+	}
+	return keys, true
+}
+
+func (x *SpatialIndex) latIndex(lat float64) int {
+	i := int(math.Floor((lat + 90) / x.cell))
+	return min(max(i, 0), x.nLat-1)
+}
+
+func (x *SpatialIndex) wrapLng(i int) int {
+	i %= x.nLng
+	if i < 0 {
+		i += x.nLng
+	}
+	return i
+}
+
+func (x *SpatialIndex) key(lat, lng float64) cellKey {
+	return cellKey{
+		lat: x.latIndex(lat),
+		lng: x.wrapLng(int(math.Floor((normalizeLng(lng) + 180) / x.cell))),
+	}
+}
+
+// Len количество проиндексированных инцидентов
+func (x *SpatialIndex) Len() int {
+	return x.size
+}
+
+// Candidates инциденты, чей охватывающий прямоугольник может содержать точку
+func (x *SpatialIndex) Candidates(lat, lng float64) []*Incident {
+	cell := x.cells[x.key(lat, lng)]
+	if len(x.wide) == 0 {
+		return cell
+	}
+
+	out := make([]*Incident, 0, len(cell)+len(x.wide))
+	out = append(out, cell...)
+	return append(out, x.wide...)
+}
+
+// CandidatesWithin инциденты, чей охватывающий прямоугольник может быть ближе meters к точке
+func (x *SpatialIndex) CandidatesWithin(lat, lng, meters float64) []*Incident {
+	if meters <= 0 {
+		return x.Candidates(lat, lng)
+	}
+
+	keys, ok := x.cellsOf(CircleBBox(lat, lng, meters))
+	if !ok {
+		return x.all()
+	}
+	return x.collect(keys)
+}
+
+// CandidatesAlong инциденты, чей охватывающий прямоугольник пересекает прямоугольник отрезка
+func (x *SpatialIndex) CandidatesAlong(lat1, lng1, lat2, lng2 float64) []*Incident {
+	keys, ok := x.cellsOf(SegmentBBox(lat1, lng1, lat2, lng2))
+	if !ok {
+		return x.all()
+	}
+	return x.collect(keys)
+}
+
+func (x *SpatialIndex) collect(keys []cellKey) []*Incident {
+	seen := make(map[*Incident]struct{})
+	out := make([]*Incident, 0, len(x.wide))
+	for _, key := range keys {
+		for _, inc := range x.cells[key] {
+			if _, dup := seen[inc]; !dup {
+				seen[inc] = struct{}{}
+				out = append(out, inc)
+			}
+		}
+	}
+	return append(out, x.wide...)
+}
+
+func (x *SpatialIndex) all() []*Incident {
+	seen := make(map[*Incident]struct{}, x.size)
+	out := make([]*Incident, 0, x.size)
+	for _, incs := range x.cells {
+		for _, inc := range incs {
+			if _, dup := seen[inc]; !dup {
+				seen[inc] = struct{}{}
+				out = append(out, inc)
+			}
+		}
+	}
+	return append(out, x.wide...)
+}
+
+// FindWithin инциденты, в зону которых попадает точка либо граница которых ближе meters
+func (x *SpatialIndex) FindWithin(lat, lng, meters float64) []*Incident {
+	var out []*Incident
+	for _, inc := range x.CandidatesWithin(lat, lng, meters) {
+		if inc.Within(lat, lng, meters) {
+			out = append(out, inc)
+		}
+	}
+	return out
+}
+
+// FindCrossing инциденты, зону которых пересекает отрезок пути
+func (x *SpatialIndex) FindCrossing(lat1, lng1, lat2, lng2 float64) []*Incident {
+	var out []*Incident
+	for _, inc := range x.CandidatesAlong(lat1, lng1, lat2, lng2) {
+		if inc.IntersectsSegment(lat1, lng1, lat2, lng2) {
+			out = append(out, inc)
+		}
+	}
+	return out
+}
+
+// FindContaining инциденты, в зону которых попадает точка
+func (x *SpatialIndex) FindContaining(lat, lng float64) []*Incident {
+	var out []*Incident
+	for _, inc := range x.Candidates(lat, lng) {
+		if inc.Contains(lat, lng) {
+			out = append(out, inc)
+		}
+	}
+	return out
+}
@@ -0,0 +1,221 @@
+package incident
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func circle(lat, lng, radius float64) *Incident {
+	return NewIncident("test", lat, lng, radius, true)
+}
+
+func ids(incs []*Incident) []string {
+	out := make([]string, 0, len(incs))
+	for _, inc := range incs {
+		out = append(out, inc.ID.String())
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sameIDs(a, b []*Incident) bool {
+	x, y := ids(a), ids(b)
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// linearWithin прежний поиск перебором всех инцидентов
+func linearWithin(incs []*Incident, lat, lng, meters float64) []*Incident {
+	var out []*Incident
+	for _, inc := range incs {
+		if inc.Within(lat, lng, meters) {
+			out = append(out, inc)
+		}
+	}
+	return out
+}
+
+func linearCrossing(incs []*Incident, lat1, lng1, lat2, lng2 float64) []*Incident {
+	var out []*Incident
+	for _, inc := range incs {
+		if inc.IntersectsSegment(lat1, lng1, lat2, lng2) {
+			out = append(out, inc)
+		}
+	}
+	return out
+}
+
+func randomIncidents(r *rand.Rand, n int, lat, lng, spread float64) []*Incident {
+	incs := make([]*Incident, n)
+	for i := range incs {
+		inc := circle(lat+(r.Float64()*2-1)*spread, lng+(r.Float64()*2-1)*spread, 50+r.Float64()*5000)
+		inc.ID = uuid.New()
+		incs[i] = inc
+	}
+	return incs
+}
+
+func TestSpatialIndexFindWithin(t *testing.T) {
+	zone := circle(55.75, 37.61, 500)
+	x := NewSpatialIndex([]*Incident{zone}, DefaultIndexCellDegrees)
+
+	// 0.0064° долготы на широте 55.75 — около 400 м, 0.011° — около 690 м
+	tests := []struct {
+		name     string
+		lat, lng float64
+		meters   float64
+		want     int
+	}{
+		{"center", 55.75, 37.61, 0, 1},
+		{"inside near edge", 55.75, 37.6164, 0, 1},
+		{"outside", 55.75, 37.621, 0, 0},
+		{"outside within buffer", 55.75, 37.621, 300, 1},
+		{"outside beyond buffer", 55.75, 37.621, 100, 0},
+		{"far away", 59.93, 30.31, 1000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := x.FindWithin(tt.lat, tt.lng, tt.meters); len(got) != tt.want {
+				t.Fatalf("FindWithin(%v, %v, %v) = %d incidents, want %d", tt.lat, tt.lng, tt.meters, len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestSpatialIndexMatchesLinearScan(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	incs := randomIncidents(r, 500, 55.75, 37.61, 1)
+	x := NewSpatialIndex(incs, DefaultIndexCellDegrees)
+
+	for i := 0; i < 2000; i++ {
+		lat, lng := 55.75+(r.Float64()*2-1)*1.2, 37.61+(r.Float64()*2-1)*1.2
+		meters := []float64{0, 500, 20000}[i%3]
+		if got, want := x.FindWithin(lat, lng, meters), linearWithin(incs, lat, lng, meters); !sameIDs(got, want) {
+			t.Fatalf("FindWithin(%v, %v, %v): index %v, linear %v", lat, lng, meters, ids(got), ids(want))
+		}
+
+		lat2, lng2 := lat+(r.Float64()*2-1)*0.3, lng+(r.Float64()*2-1)*0.3
+		if got, want := x.FindCrossing(lat, lng, lat2, lng2), linearCrossing(incs, lat, lng, lat2, lng2); !sameIDs(got, want) {
+			t.Fatalf("FindCrossing(%v, %v, %v, %v): index %v, linear %v", lat, lng, lat2, lng2, ids(got), ids(want))
+		}
+	}
+}
+
+func TestSpatialIndexFindCrossing(t *testing.T) {
+	zone := circle(55.75, 37.61, 500)
+	x := NewSpatialIndex([]*Incident{zone}, DefaultIndexCellDegrees)
+
+	// обе точки снаружи, путь проходит через центр зоны
+	if got := x.FindCrossing(55.75, 37.59, 55.75, 37.63); len(got) != 1 {
+		t.Fatalf("segment through zone: got %d incidents, want 1", len(got))
+	}
+	// путь в 2 км севернее зоны
+	if got := x.FindCrossing(55.768, 37.59, 55.768, 37.63); len(got) != 0 {
+		t.Fatalf("segment past zone: got %d incidents, want 0", len(got))
+	}
+	// путь через несколько ячеек сетки
+	if got := x.FindCrossing(55.2, 37.61, 56.3, 37.61); len(got) != 1 {
+		t.Fatalf("long segment through zone: got %d incidents, want 1", len(got))
+	}
+}
+
+func TestSpatialIndexCellBoundaries(t *testing.T) {
+	const cell = 0.25
+	// круг радиусом 2 км у угла ячеек (0.25, 0.25) накрывает четыре соседние ячейки
+	zone := circle(0.249, 0.249, 2000)
+	x := NewSpatialIndex([]*Incident{zone}, cell)
+
+	points := [][2]float64{
+		{0.249, 0.249},
+		{0.25, 0.25}, // ровно на границе ячеек
+		{0.251, 0.251},
+		{0.251, 0.245},
+		{0.245, 0.251},
+	}
+	for _, p := range points {
+		if got := x.FindContaining(p[0], p[1]); len(got) != 1 {
+			t.Errorf("FindContaining(%v, %v) = %d incidents, want 1", p[0], p[1], len(got))
+		}
+	}
+	// соседняя ячейка, до зоны больше 2 км
+	if got := x.FindContaining(0.27, 0.27); len(got) != 0 {
+		t.Errorf("FindContaining outside zone = %d incidents, want 0", len(got))
+	}
+	// граница зоны в 500 м от точки из другой ячейки
+	if got := x.FindWithin(0.249, 0.2716, 1000); len(got) != 1 {
+		t.Errorf("FindWithin across cell boundary = %d incidents, want 1", len(got))
+	}
+}
+
+func TestSpatialIndexAntimeridian(t *testing.T) {
+	zone := circle(0, 179.99, 5000)
+	poly := &Incident{ID: uuid.New()}
+	poly.SetGeometry(&Geometry{Type: GeometryPolygon, Polygons: []Polygon{{{
+		{179.5, 10}, {-179.5, 10}, {-179.5, 11}, {179.5, 11}, {179.5, 10},
+	}}}})
+	x := NewSpatialIndex([]*Incident{zone, poly}, DefaultIndexCellDegrees)
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		want     *Incident
+	}{
+		{"circle east side", 0, 179.995, zone},
+		{"circle west side", 0, -179.99, zone},
+		{"circle west side as 180", 0, 180, zone},
+		{"polygon east side", 10.5, 179.9, poly},
+		{"polygon west side", 10.5, -179.9, poly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := x.FindContaining(tt.lat, tt.lng)
+			if len(got) != 1 || got[0] != tt.want {
+				t.Fatalf("FindContaining(%v, %v) = %v, want [%s]", tt.lat, tt.lng, ids(got), tt.want.ID)
+			}
+		})
+	}
+
+	if got := x.FindCrossing(0, 179.9, 0, -179.9); len(got) != 1 || got[0] != zone {
+		t.Fatalf("FindCrossing across antimeridian = %v, want [%s]", ids(got), zone.ID)
+	}
+	if got := x.FindWithin(0, -179.97, 1000); len(got) != 1 || got[0] != zone {
+		t.Fatalf("FindWithin across antimeridian = %v, want [%s]", ids(got), zone.ID)
+	}
+}
+
+func BenchmarkSpatialIndex(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		r := rand.New(rand.NewSource(1))
+		incs := randomIncidents(r, n, 55.75, 37.61, 5)
+		x := NewSpatialIndex(incs, DefaultIndexCellDegrees)
+
+		points := make([][2]float64, 1024)
+		for i := range points {
+			points[i] = [2]float64{55.75 + (r.Float64()*2-1)*5, 37.61 + (r.Float64()*2-1)*5}
+		}
+
+		b.Run(fmt.Sprintf("index/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p := points[i%len(points)]
+				x.FindWithin(p[0], p[1], 500)
+			}
+		})
+		b.Run(fmt.Sprintf("linear/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p := points[i%len(points)]
+				linearWithin(incs, p[0], p[1], 500)
+			}
+		})
+	}
+}
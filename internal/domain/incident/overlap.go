@@ -0,0 +1,76 @@
+package incident
+
+import (
+	"math"
+)
+
+// Overlap доля круга точности радиусом accuracy метров вокруг точки, которая попадает в зону.
+// Граница зоны возле точки считается прямой, а результат ограничен площадью самой зоны,
+// чтобы маленькая зона не давала высокую долю при большом круге точности
+func (i *Incident) Overlap(lat, lng, accuracy float64) float64 {
+	inside := i.Contains(lat, lng)
+	if accuracy <= 0 {
+		if inside {
+			return 1
+		}
+		return 0
+	}
+
+	d, _, _ := i.EdgeDistance(lat, lng)
+	if !inside {
+		d = -d
+	}
+
+	circle := math.Pi * accuracy * accuracy
+	return math.Min(circleShareBeyondChord(d/accuracy), i.Area()/circle)
+}
+
+// Area площадь зоны в квадратных метрах
+func (i *Incident) Area() float64 {
+	if i.Geometry != nil {
+		return i.Geometry.Area()
+	}
+	return math.Pi * i.Radius * i.Radius
+}
+
+// Area площадь геометрии в квадратных метрах за вычетом дыр. Контуры проецируются
+// на локальную плоскость вокруг центра геометрии
+func (g *Geometry) Area() float64 {
+	p := newLocalPlane(g.Center())
+
+	total := 0.0
+	for _, poly := range g.Polygons {
+		for k, ring := range poly {
+			a := math.Abs(ringArea(p, ring))
+			if k == 0 {
+				total += a
+			} else {
+				total -= a
+			}
+		}
+	}
+	return math.Max(total, 0)
+}
+
+// ringArea ориентированная площадь контура по формуле шнурования
+func ringArea(p localPlane, ring Ring) float64 {
+	sum := 0.0
+	for k := 1; k < len(ring); k++ {
+		ax, ay := p.project(ring[k-1].Lat(), ring[k-1].Lng())
+		bx, by := p.project(ring[k].Lat(), ring[k].Lng())
+		sum += ax*by - bx*ay
+	}
+	return sum / 2
+}
+
+// circleShareBeyondChord доля площади единичного круга по одну сторону от прямой,
+// проходящей на расстоянии s от центра (s > 0 — центр на этой стороне)
+func circleShareBeyondChord(s float64) float64 {
+	if s >= 1 {
+		return 1
+	}
+	if s <= -1 {
+		return 0
+	}
+	return 0.5 + (s*math.Sqrt(1-s*s)+math.Asin(s))/math.Pi
+}
@@ -2,20 +2,28 @@ package incident
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// IncidentRepository изменения инцидентов записываются вместе с событием вебхука (см. EventLifecycle)
+// в outbox одной транзакцией: событие появляется тогда и только тогда, когда сохранено изменение
 type IncidentRepository interface {
 	Create(ctx context.Context, inc *Incident) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Incident, error)
 	Update(ctx context.Context, inc *Incident) error
 	Deactivate(ctx context.Context, id uuid.UUID) error
-	List(ctx context.Context, offset, limit int) ([]*Incident, error)
+	List(ctx context.Context, filter ListFilter, offset, limit int) ([]*Incident, error)
 	GetActiveIncidents(ctx context.Context) ([]*Incident, error)
+	ListActiveBetween(ctx context.Context, from, to time.Time) ([]*Incident, error)
 	CountActiveIncidents(ctx context.Context) (int, error)
-	ListWithTotal(ctx context.Context, offset, limit int) ([]*Incident, int, error)
-	CountAll(ctx context.Context) (int, error)
+	ListWithTotal(ctx context.Context, filter ListFilter, offset, limit int) ([]*Incident, int, error)
+	CountAll(ctx context.Context, filter ListFilter) (int, error)
+	ListScheduled(ctx context.Context, now time.Time) ([]*Incident, error)
+	// SetActive переключает инцидент по расписанию; switchedAt — граница окна, на которой это произошло.
+	// false, если флаг уже переключил другой инстанс: тогда и событие не записывается
+	SetActive(ctx context.Context, id uuid.UUID, active bool, switchedAt time.Time) (bool, error)
 }
 
 type IncidentCache interface {
@@ -23,3 +31,19 @@ type IncidentCache interface {
 	SetActive(ctx context.Context, incs []*Incident) error
 	InvalidateActive(ctx context.Context) error
 }
+
+// IncidentLocator ищет активные инциденты рядом с точкой
+type IncidentLocator interface {
+	// FindContaining инциденты, в зону которых попадает точка
+	FindContaining(ctx context.Context, lat, lng float64) ([]*Incident, error)
+	// FindWithin инциденты, в зону которых попадает точка либо граница которых ближе meters
+	FindWithin(ctx context.Context, lat, lng, meters float64) ([]*Incident, error)
+	// FindCrossing инциденты, зону которых пересекает отрезок пути между двумя точками
+	FindCrossing(ctx context.Context, lat1, lng1, lat2, lng2 float64) ([]*Incident, error)
+}
+
+// IncidentHistory восстанавливает набор инцидентов, которые могли быть активны в прошлом
+type IncidentHistory interface {
+	// ListActiveBetween инциденты, активные хотя бы в один момент из [from, to]
+	ListActiveBetween(ctx context.Context, from, to time.Time) ([]*Incident, error)
+}
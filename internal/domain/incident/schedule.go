@@ -0,0 +1,253 @@
+package incident
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	RecurrenceDaily  = "daily"
+	RecurrenceWeekly = "weekly"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Recurrence повторяющееся окно активности внутри [StartsAt, EndsAt), например каждую ночь с 22:00 до 06:00.
+// Если EndTime не позже StartTime, окно переходит через полночь и относится к дню начала
+type Recurrence struct {
+	Frequency string   `json:"frequency"`          // daily или weekly
+	Weekdays  []string `json:"weekdays,omitempty"` // дни начала окна для weekly: mon, tue, ...
+	StartTime string   `json:"start_time"`         // начало окна, ЧЧ:ММ
+	EndTime   string   `json:"end_time"`           // конец окна, ЧЧ:ММ
+	Timezone  string   `json:"timezone,omitempty"` // часовой пояс IANA, по умолчанию UTC
+}
+
+func (r *Recurrence) Validate() error {
+	switch r.Frequency {
+	case RecurrenceDaily:
+		if len(r.Weekdays) > 0 {
+			return errors.New("weekdays are allowed only for weekly recurrence")
+		}
+	case RecurrenceWeekly:
+		if len(r.Weekdays) == 0 {
+			return errors.New("weekly recurrence requires at least one weekday")
+		}
+		for _, d := range r.Weekdays {
+			if _, ok := weekdays[d]; !ok {
+				return fmt.Errorf("unknown weekday %q, expected mon, tue, wed, thu, fri, sat or sun", d)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported recurrence frequency %q, expected daily or weekly", r.Frequency)
+	}
+
+	if _, err := clockMinutes(r.StartTime); err != nil {
+		return fmt.Errorf("invalid start_time: %w", err)
+	}
+	if _, err := clockMinutes(r.EndTime); err != nil {
+		return fmt.Errorf("invalid end_time: %w", err)
+	}
+	if _, err := loadTimezone(r.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", r.Timezone, err)
+	}
+	return nil
+}
+
+// ActiveAt попадает ли момент t в одно из окон повторения
+func (r *Recurrence) ActiveAt(t time.Time) bool {
+	tz, err := loadTimezone(r.Timezone)
+	if err != nil {
+		return false
+	}
+	start, err := clockMinutes(r.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := clockMinutes(r.EndTime)
+	if err != nil {
+		return false
+	}
+
+	local := t.In(tz)
+	now := local.Hour()*60 + local.Minute()
+
+	if start < end {
+		return now >= start && now < end && r.onDay(local.Weekday())
+	}
+	// окно через полночь: вечерняя часть относится к текущему дню, утренняя — к предыдущему
+	if now >= start {
+		return r.onDay(local.Weekday())
+	}
+	if now < end {
+		return r.onDay((local.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+// boundaries начала и концы окон за последние восемь дней до now включительно — хватает, чтобы
+// застать хотя бы одно окно недельного правила
+func (r *Recurrence) boundaries(now time.Time) []time.Time {
+	tz, err := loadTimezone(r.Timezone)
+	if err != nil {
+		return nil
+	}
+	start, err := clockMinutes(r.StartTime)
+	if err != nil {
+		return nil
+	}
+	end, err := clockMinutes(r.EndTime)
+	if err != nil {
+		return nil
+	}
+
+	local := now.In(tz)
+	out := make([]time.Time, 0, 18)
+	for d := 0; d <= 8; d++ {
+		day := local.AddDate(0, 0, -d)
+		for _, m := range []int{start, end} {
+			out = append(out, time.Date(day.Year(), day.Month(), day.Day(), m/60, m%60, 0, 0, tz))
+		}
+	}
+	return out
+}
+
+func (r *Recurrence) onDay(day time.Weekday) bool {
+	if r.Frequency != RecurrenceWeekly {
+		return true
+	}
+	for _, d := range r.Weekdays {
+		if weekdays[d] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Scheduled задано ли у инцидента расписание; такие инциденты включаются и выключаются по времени
+func (i *Incident) Scheduled() bool {
+	return i.StartsAt != nil || i.EndsAt != nil || i.Recurrence != nil
+}
+
+// ActiveAt активен ли инцидент в момент t. Для инцидентов без расписания это флаг IsActive
+func (i *Incident) ActiveAt(t time.Time) bool {
+	if !i.Scheduled() {
+		return i.IsActive
+	}
+	if i.StartsAt != nil && t.Before(*i.StartsAt) {
+		return false
+	}
+	if i.EndsAt != nil && !t.Before(*i.EndsAt) {
+		return false
+	}
+	if i.Recurrence != nil {
+		return i.Recurrence.ActiveAt(t)
+	}
+	return true
+}
+
+// SwitchedAt момент последнего переключения по расписанию не позже now: начало или конец окна.
+// Не зависит от того, когда его вычисляют, поэтому годится для ID события о переключении.
+// Если расписание инцидент ещё не переключало — нулевое время
+func (i *Incident) SwitchedAt(now time.Time) time.Time {
+	var last time.Time
+	consider := func(t time.Time) {
+		if t.After(now) || !t.After(last) {
+			return
+		}
+		if i.ActiveAt(t) != i.ActiveAt(t.Add(-time.Nanosecond)) {
+			last = t
+		}
+	}
+
+	if i.StartsAt != nil {
+		consider(*i.StartsAt)
+	}
+	if i.EndsAt != nil {
+		consider(*i.EndsAt)
+	}
+	if i.Recurrence != nil {
+		for _, t := range i.Recurrence.boundaries(now) {
+			consider(t)
+		}
+	}
+	return last
+}
+
+// ActiveAt оставляет инциденты, активные в момент t
+func ActiveAt(incs []*Incident, t time.Time) []*Incident {
+	out := make([]*Incident, 0, len(incs))
+	for _, inc := range incs {
+		if inc.ActiveAt(t) {
+			out = append(out, inc)
+		}
+	}
+	return out
+}
+
+func clockMinutes(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+var timezones sync.Map
+
+// loadTimezone кэширует часовые пояса: time.LoadLocation читает базу zoneinfo при каждом вызове
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if tz, ok := timezones.Load(name); ok {
+		return tz.(*time.Location), nil
+	}
+
+	tz, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	timezones.Store(name, tz)
+	return tz, nil
+}
+
+// ValidateSchedule проверяет согласованность окна и правила повторения
+func (i *Incident) ValidateSchedule() error {
+	if i.StartsAt != nil && i.EndsAt != nil && !i.EndsAt.After(*i.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if i.Recurrence != nil {
+		return i.Recurrence.Validate()
+	}
+	return nil
+}
+
+// SetActive включает или выключает инцидент вручную. Выключение, как и IncidentRepo.Deactivate, записывает
+// время в EndsAt: расписание после него не возобновляется, а проверки задним числом видят, когда инцидент
+// перестал действовать. Включение снимает истёкшее окончание и переносит ещё не наступившее начало на now,
+// иначе планировщик вернул бы прежнее состояние. Окно повторения включение не меняет, см. ActiveAt
+func (i *Incident) SetActive(active bool, now time.Time) {
+	i.IsActive = active
+	if !active {
+		if i.EndsAt == nil || i.EndsAt.After(now) {
+			i.EndsAt = &now
+		}
+		return
+	}
+	if i.EndsAt != nil && !now.Before(*i.EndsAt) {
+		i.EndsAt = nil
+	}
+	if i.StartsAt != nil && i.StartsAt.After(now) {
+		i.StartsAt = &now
+	}
+}
@@ -0,0 +1,115 @@
+package incident
+
+import (
+	"testing"
+	"time"
+)
+
+func utc(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRecurrenceActiveAt(t *testing.T) {
+	overnight := &Recurrence{Frequency: RecurrenceDaily, StartTime: "22:00", EndTime: "06:00"}
+	// 2026-10-19 — понедельник
+	mondayNights := &Recurrence{Frequency: RecurrenceWeekly, Weekdays: []string{"mon"}, StartTime: "22:00", EndTime: "06:00"}
+	// 29.03.2026 в Берлине часы переводятся с 02:00 CET на 03:00 CEST, 25.10.2026 — с 03:00 CEST на 02:00 CET
+	berlin := &Recurrence{Frequency: RecurrenceDaily, StartTime: "22:00", EndTime: "06:00", Timezone: "Europe/Berlin"}
+	gap := &Recurrence{Frequency: RecurrenceDaily, StartTime: "02:00", EndTime: "03:00", Timezone: "Europe/Berlin"}
+
+	tests := []struct {
+		name string
+		r    *Recurrence
+		at   string
+		want bool
+	}{
+		{"overnight before start", overnight, "2026-10-19T21:59:00Z", false},
+		{"overnight at start", overnight, "2026-10-19T22:00:00Z", true},
+		{"overnight after midnight", overnight, "2026-10-20T05:59:00Z", true},
+		{"overnight at end", overnight, "2026-10-20T06:00:00Z", false},
+		{"overnight midday", overnight, "2026-10-20T12:00:00Z", false},
+
+		{"weekly evening of start day", mondayNights, "2026-10-19T23:00:00Z", true},
+		{"weekly morning after start day", mondayNights, "2026-10-20T03:00:00Z", true},
+		{"weekly morning of start day belongs to sunday", mondayNights, "2026-10-19T03:00:00Z", false},
+		{"weekly evening of next day", mondayNights, "2026-10-20T23:00:00Z", false},
+
+		{"spring before start", berlin, "2026-03-28T20:59:00Z", false},
+		{"spring start in CET", berlin, "2026-03-28T21:00:00Z", true},
+		{"spring across the switch", berlin, "2026-03-29T01:30:00Z", true},
+		{"spring last minute in CEST", berlin, "2026-03-29T03:59:00Z", true},
+		{"spring end in CEST", berlin, "2026-03-29T04:00:00Z", false},
+		{"autumn start in CEST", berlin, "2026-10-24T20:00:00Z", true},
+		{"autumn repeated hour", berlin, "2026-10-25T01:30:00Z", true},
+		{"autumn last minute in CET", berlin, "2026-10-25T04:59:00Z", true},
+		{"autumn end in CET", berlin, "2026-10-25T05:00:00Z", false},
+
+		{"window inside skipped hour before", gap, "2026-03-29T00:59:00Z", false},
+		{"window inside skipped hour after", gap, "2026-03-29T01:00:00Z", false},
+		{"window on next day", gap, "2026-03-30T00:30:00Z", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.ActiveAt(utc(tt.at)); got != tt.want {
+				t.Fatalf("ActiveAt(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncidentSwitchedAt(t *testing.T) {
+	recurring := func(r *Recurrence) *Incident {
+		inc := circle(52.52, 13.40, 500)
+		inc.Recurrence = r
+		return inc
+	}
+	berlin := recurring(&Recurrence{Frequency: RecurrenceDaily, StartTime: "22:00", EndTime: "06:00", Timezone: "Europe/Berlin"})
+	mondayNights := recurring(&Recurrence{Frequency: RecurrenceWeekly, Weekdays: []string{"mon"}, StartTime: "22:00", EndTime: "06:00"})
+
+	// начало внутри уже открытого окна: включение происходит в starts_at, а не в начале окна
+	startsLate := recurring(&Recurrence{Frequency: RecurrenceDaily, StartTime: "22:00", EndTime: "06:00"})
+	lateStart := utc("2026-10-19T23:00:00Z")
+	startsLate.StartsAt = &lateStart
+
+	tests := []struct {
+		name string
+		inc  *Incident
+		now  string
+		want string
+	}{
+		{"spring inside window", berlin, "2026-03-29T02:00:00Z", "2026-03-28T21:00:00Z"},
+		{"spring after window", berlin, "2026-03-29T10:00:00Z", "2026-03-29T04:00:00Z"},
+		{"autumn inside window", berlin, "2026-10-24T21:00:00Z", "2026-10-24T20:00:00Z"},
+		{"autumn repeated hour", berlin, "2026-10-25T01:30:00Z", "2026-10-24T20:00:00Z"},
+		{"autumn after window", berlin, "2026-10-25T10:00:00Z", "2026-10-25T05:00:00Z"},
+		{"at the boundary itself", berlin, "2026-10-25T05:00:00Z", "2026-10-25T05:00:00Z"},
+
+		{"weekly later in the week", mondayNights, "2026-10-22T12:00:00Z", "2026-10-20T06:00:00Z"},
+		{"weekly a week later", mondayNights, "2026-10-26T12:00:00Z", "2026-10-20T06:00:00Z"},
+
+		{"before starts_at", startsLate, "2026-10-19T22:30:00Z", ""},
+		{"after starts_at", startsLate, "2026-10-20T01:00:00Z", "2026-10-19T23:00:00Z"},
+		{"end of first window", startsLate, "2026-10-20T07:00:00Z", "2026-10-20T06:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want time.Time
+			if tt.want != "" {
+				want = utc(tt.want)
+			}
+			if got := tt.inc.SwitchedAt(utc(tt.now)); !got.Equal(want) {
+				t.Fatalf("SwitchedAt(%s) = %s, want %s", tt.now, got.UTC().Format(time.RFC3339), want.Format(time.RFC3339))
+			}
+		})
+	}
+
+	// момент переключения не зависит от того, когда его вычислили
+	first := berlin.SwitchedAt(utc("2026-10-24T20:30:00Z"))
+	if later := berlin.SwitchedAt(utc("2026-10-25T04:30:00Z")); !later.Equal(first) {
+		t.Fatalf("SwitchedAt drifted within one window: %s then %s", first, later)
+	}
+}
@@ -0,0 +1,98 @@
+package incident
+
+import (
+	"math"
+)
+
+// IntersectsSegment проверяет, пересекает ли отрезок пути между двумя точками зону инцидента.
+// Для круга используется расстояние от центра до дуги большого круга, для полигона —
+// пересечение рёбер в локальной плоскости вокруг начала отрезка
+func (i *Incident) IntersectsSegment(lat1, lng1, lat2, lng2 float64) bool {
+	if i.Contains(lat1, lng1) || i.Contains(lat2, lng2) {
+		return true
+	}
+	if i.Geometry != nil {
+		return i.Geometry.intersectsSegment(lat1, lng1, lat2, lng2)
+	}
+	return segmentDistance(lat1, lng1, lat2, lng2, i.Lat, i.Lng) <= i.Radius
+}
+
+// segmentDistance расстояние от точки (lat, lng) до дуги большого круга p1-p2
+func segmentDistance(lat1, lng1, lat2, lng2, lat, lng float64) float64 {
+	d13 := HaversineMeters(lat1, lng1, lat, lng)
+	d12 := HaversineMeters(lat1, lng1, lat2, lng2)
+	if d12 == 0 {
+		return d13
+	}
+
+	theta13 := toRad(BearingDegrees(lat1, lng1, lat, lng))
+	theta12 := toRad(BearingDegrees(lat1, lng1, lat2, lng2))
+	delta13 := d13 / EarthRadiusMeters
+
+	// проекция точки на дугу лежит за началом отрезка
+	if math.Cos(theta13-theta12) < 0 {
+		return d13
+	}
+
+	dxt := math.Asin(math.Sin(delta13) * math.Sin(theta13-theta12))
+	dat := math.Acos(math.Max(-1, math.Min(1, math.Cos(delta13)/math.Cos(dxt)))) * EarthRadiusMeters
+	if dat > d12 {
+		return HaversineMeters(lat2, lng2, lat, lng)
+	}
+	return math.Abs(dxt) * EarthRadiusMeters
+}
+
+func (g *Geometry) intersectsSegment(lat1, lng1, lat2, lng2 float64) bool {
+	p := newLocalPlane(lat1, lng1)
+	bx, by := p.project(lat2, lng2)
+
+	for _, poly := range g.Polygons {
+		for _, ring := range poly {
+			for k := 1; k < len(ring); k++ {
+				cx, cy := p.project(ring[k-1].Lat(), ring[k-1].Lng())
+				dx, dy := p.project(ring[k].Lat(), ring[k].Lng())
+				if segmentsIntersect(0, 0, bx, by, cx, cy, dx, dy) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// segmentsIntersect пересекаются ли отрезки a-b и c-d на плоскости
+func segmentsIntersect(ax, ay, bx, by, cx, cy, dx, dy float64) bool {
+	d1 := orientation(cx, cy, dx, dy, ax, ay)
+	d2 := orientation(cx, cy, dx, dy, bx, by)
+	d3 := orientation(ax, ay, bx, by, cx, cy)
+	d4 := orientation(ax, ay, bx, by, dx, dy)
+
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+
+	return (d1 == 0 && onSegment(cx, cy, dx, dy, ax, ay)) ||
+		(d2 == 0 && onSegment(cx, cy, dx, dy, bx, by)) ||
+		(d3 == 0 && onSegment(ax, ay, bx, by, cx, cy)) ||
+		(d4 == 0 && onSegment(ax, ay, bx, by, dx, dy))
+}
+
+func orientation(ax, ay, bx, by, px, py float64) float64 {
+	return (bx-ax)*(py-ay) - (by-ay)*(px-ax)
+}
+
+func onSegment(ax, ay, bx, by, px, py float64) bool {
+	return math.Min(ax, bx) <= px && px <= math.Max(ax, bx) &&
+		math.Min(ay, by) <= py && py <= math.Max(ay, by)
+}
+
+// SegmentBBox охватывающий прямоугольник отрезка, долгота второй точки развёрнута относительно первой
+func SegmentBBox(lat1, lng1, lat2, lng2 float64) BBox {
+	lng2 = lng1 + normalizeLng(lng2-lng1)
+	return BBox{
+		MinLat: math.Min(lat1, lat2),
+		MinLng: math.Min(lng1, lng2),
+		MaxLat: math.Max(lat1, lat2),
+		MaxLng: math.Max(lng1, lng2),
+	}
+}
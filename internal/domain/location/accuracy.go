@@ -0,0 +1,43 @@
+package location
+
+import (
+	"github.com/google/uuid"
+)
+
+// Уверенность попадания в зону с учётом точности координат
+const (
+	ConfidenceCertain  = "certain"  // круг точности почти целиком внутри зоны
+	ConfidencePossible = "possible" // круг точности частично пересекает зону
+	ConfidenceOutside  = "outside"  // пересечение пренебрежимо мало или отсутствует
+)
+
+// Fix дополнительные данные GNSS-фиксации, которые присылает клиент. Все поля необязательные
+type Fix struct {
+	AccuracyMeters *float64 `json:"accuracy_m,omitempty"` // радиус круга точности в метрах
+	Altitude       *float64 `json:"altitude,omitempty"`   // высота над уровнем моря в метрах
+	Speed          *float64 `json:"speed,omitempty"`      // скорость в м/с
+	Heading        *float64 `json:"heading,omitempty"`    // направление движения, 0..360 от севера
+}
+
+// Accuracy радиус круга точности; 0, если клиент его не прислал
+func (f Fix) Accuracy() float64 {
+	if f.AccuracyMeters == nil {
+		return 0
+	}
+	return *f.AccuracyMeters
+}
+
+// Match попадание в зону с оценкой уверенности
+type Match struct {
+	IncidentID uuid.UUID `json:"incident_id"`
+	Confidence string    `json:"confidence"`
+}
+
+// IsConfidence проверяет, что значение — один из известных уровней уверенности
+func IsConfidence(v string) bool {
+	switch v {
+	case ConfidenceCertain, ConfidencePossible, ConfidenceOutside:
+		return true
+	}
+	return false
+}
@@ -7,15 +7,65 @@ import (
 )
 
 type Location struct {
-	ID          uuid.UUID   `json:"id"`           // уникальный идентификатор проверки
-	UserID      uuid.UUID   `json:"user_id"`      // пользователь, который прислал координаты
-	Lat         float64     `json:"lat"`          // широта пользователя
-	Lng         float64     `json:"lng"`          // долгота пользователя
-	Timestamp   time.Time   `json:"timestamp"`    // когда пришли координаты
-	IsCheck     bool        `json:"is_check"`     // попали ли координаты в зону инцидента
-	IncidentIDs []uuid.UUID `json:"incident_ids"` // список инцидентов, которые попали
+	ID          uuid.UUID        `json:"id"`           // уникальный идентификатор проверки
+	UserID      uuid.UUID        `json:"user_id"`      // пользователь, который прислал координаты
+	Lat         float64          `json:"lat"`          // широта пользователя
+	Lng         float64          `json:"lng"`          // долгота пользователя
+	Timestamp   time.Time        `json:"timestamp"`    // когда пришли координаты
+	IsCheck     bool             `json:"is_check"`     // попали ли координаты в зону инцидента
+	IncidentIDs []uuid.UUID      `json:"incident_ids"` // список инцидентов, которые попали
+	Matches     []Match          `json:"matches"`      // уверенность попадания в каждую из зон
+	Confidence  string           `json:"confidence"`   // лучшая уверенность среди зон
+	Transitions []Transition     `json:"transitions"`  // входы/выходы/задержки в зонах относительно прошлой проверки
+	Nearby      []NearbyIncident `json:"nearby"`       // зоны, к границе которых пользователь приближается
+
+	PassedThroughIncidentIDs []uuid.UUID `json:"passed_through_incident_ids"` // зоны, пересечённые между прошлой и текущей точкой
+
+	Fix // точность, высота, скорость и направление, присланные клиентом
+
+	Incidents []IncidentInfo `json:"incidents"` // сведения о зонах, попавших в проверку
+
+	Events []string `json:"-"` // типы вебхуков, которые записываются в outbox вместе с точкой
+
+	Notification Notification `json:"notification"` // отправлено ли уведомление и что подавлено окном повтора
+}
+
+// IncidentInfo краткие сведения об инциденте для получателей вебхуков
+type IncidentInfo struct {
+	IncidentID uuid.UUID `json:"incident_id"`
+	Title      string    `json:"title"`
+	Severity   string    `json:"severity"`
+	Category   string    `json:"category"`
+
+	NotifyCooldown *time.Duration `json:"-"` // окно повтора уведомлений зоны; nil — глобальная настройка
+}
+
+// NearbyIncident зона, рядом с которой находится пользователь
+type NearbyIncident struct {
+	IncidentID     uuid.UUID `json:"incident_id"`
+	DistanceMeters float64   `json:"distance_m"` // расстояние до ближайшей точки границы
+	BearingDegrees float64   `json:"bearing"`    // направление на эту точку, 0..360 от севера
 }
 
 func (l *Location) HasIncidents() bool {
 	return len(l.IncidentIDs) > 0
 }
+
+// MatchedIDs зоны, попадание в которые оценено одним из уровней levels
+func (l *Location) MatchedIDs(levels map[string]bool) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(l.Matches))
+	for _, m := range l.Matches {
+		if levels[m.Confidence] {
+			ids = append(ids, m.IncidentID)
+		}
+	}
+	return ids
+}
+
+func (l *Location) NearbyIDs() []uuid.UUID {
+	ids := make([]uuid.UUID, len(l.Nearby))
+	for i, n := range l.Nearby {
+		ids[i] = n.IncidentID
+	}
+	return ids
+}
@@ -0,0 +1,67 @@
+package location
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TransitionEnter = "ENTER" // пользователь вошёл в зону
+	TransitionExit  = "EXIT"  // пользователь покинул зону
+	TransitionDwell = "DWELL" // пользователь находится в зоне дольше заданного времени
+)
+
+type Transition struct {
+	IncidentID uuid.UUID `json:"incident_id"`
+	Event      string    `json:"event"`
+}
+
+// GeofenceState состояние пользователя внутри одной зоны
+type GeofenceState struct {
+	EnteredAt time.Time `json:"entered_at"`      // когда пользователь вошёл в зону
+	Dwelled   bool      `json:"dwelled"`         // событие DWELL уже отправлено
+	Muted     bool      `json:"muted,omitempty"` // вход пришёлся на окно повтора: DWELL и EXIT этого входа не отправляются
+}
+
+// NextTransitions сравнивает прежнее состояние с зонами, в которых пользователь находится сейчас,
+// и возвращает переходы и новое состояние. dwellAfter <= 0 отключает события DWELL
+func NextTransitions(
+	prev map[uuid.UUID]GeofenceState,
+	inside []uuid.UUID,
+	now time.Time,
+	dwellAfter time.Duration,
+) ([]Transition, map[uuid.UUID]GeofenceState) {
+	transitions := []Transition{}
+	next := make(map[uuid.UUID]GeofenceState, len(inside))
+
+	for _, id := range inside {
+		if _, dup := next[id]; dup {
+			continue
+		}
+
+		state, ok := prev[id]
+		if !ok {
+			state = GeofenceState{EnteredAt: now}
+			transitions = append(transitions, Transition{IncidentID: id, Event: TransitionEnter})
+		} else if dwellAfter > 0 && !state.Dwelled && now.Sub(state.EnteredAt) >= dwellAfter {
+			state.Dwelled = true
+			transitions = append(transitions, Transition{IncidentID: id, Event: TransitionDwell})
+		}
+		next[id] = state
+	}
+
+	var exited []uuid.UUID
+	for id := range prev {
+		if _, ok := next[id]; !ok {
+			exited = append(exited, id)
+		}
+	}
+	sort.Slice(exited, func(i, j int) bool { return exited[i].String() < exited[j].String() })
+	for _, id := range exited {
+		transitions = append(transitions, Transition{IncidentID: id, Event: TransitionExit})
+	}
+
+	return transitions, next
+}
@@ -0,0 +1,71 @@
+package location
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HitPassedThrough событие проезда через зону в SuppressedHit.Event
+const HitPassedThrough = "PASSED_THROUGH"
+
+// Причины, по которым уведомление не отправлено
+const (
+	ReasonCooldown = "cooldown"  // по зоне уже было уведомление, окно повтора ещё не истекло
+	ReasonNoChange = "no_change" // состояние пользователя относительно зон не изменилось
+	ReasonLate     = "late"      // точка старше последней проверки пользователя: переходы по ней не считаются
+)
+
+// CooldownKey окно повтора уведомлений одного типа по одной зоне
+type CooldownKey struct {
+	EventType  string
+	IncidentID uuid.UUID
+}
+
+// SuppressedHit событие по зоне, не отправленное получателям вебхуков
+type SuppressedHit struct {
+	EventType  string     `json:"event_type"` // geofence или proximity
+	IncidentID uuid.UUID  `json:"incident_id"`
+	Event      string     `json:"event"` // ENTER/EXIT/DWELL или PASSED_THROUGH
+	Reason     string     `json:"reason"`
+	Until      *time.Time `json:"until,omitempty"` // когда истекает окно повтора
+}
+
+// Notification итог проверки для получателей вебхуков
+type Notification struct {
+	Sent       bool            `json:"sent"`             // записано ли хотя бы одно событие
+	Reason     string          `json:"reason,omitempty"` // почему ничего не отправлено
+	Suppressed []SuppressedHit `json:"suppressed"`
+}
+
+// IsSuppressed отправка события event по зоне id подавлена окном повтора
+func (l *Location) IsSuppressed(eventType string, id uuid.UUID, event string) bool {
+	for _, h := range l.Notification.Suppressed {
+		if h.EventType == eventType && h.IncidentID == id && h.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifiedTransitions переходы, о которых сообщается получателям вебхуков
+func (l *Location) NotifiedTransitions() []Transition {
+	transitions := make([]Transition, 0, len(l.Transitions))
+	for _, t := range l.Transitions {
+		if !l.IsSuppressed(EventGeofence, t.IncidentID, t.Event) {
+			transitions = append(transitions, t)
+		}
+	}
+	return transitions
+}
+
+// NotifiedPassedThrough пересечённые в пути зоны, о которых сообщается получателям вебхуков
+func (l *Location) NotifiedPassedThrough() []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(l.PassedThroughIncidentIDs))
+	for _, id := range l.PassedThroughIncidentIDs {
+		if !l.IsSuppressed(EventGeofence, id, HitPassedThrough) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -9,6 +9,38 @@ import (
 
 type LocationRepository interface {
 	Save(ctx context.Context, loc *Location) error
+	SaveBatch(ctx context.Context, locs []*Location) error
 	ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*Location, error)
-	CountUniqueUsers(ctx context.Context, since time.Time) (int, error)
+	CountUsersInIncidents(ctx context.Context, from, to time.Time) (int, error)
+	CountByIncident(ctx context.Context, from, to time.Time) ([]IncidentStats, error)
+	HitSeries(ctx context.Context, from, to time.Time, bucket time.Duration) ([]StatsBucket, error)
+}
+
+// GeofenceStore хранит состояние пользователя относительно зон между проверками
+type GeofenceStore interface {
+	Get(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]GeofenceState, error)
+	Save(ctx context.Context, userID uuid.UUID, states map[uuid.UUID]GeofenceState) error
+}
+
+// UserLocker сериализует проверки одного пользователя между запросами и инстансами API,
+// чтобы переход по зоне не посчитали дважды
+type UserLocker interface {
+	// Lock ждёт блокировку пользователя; errs.ErrLocked — не дождались. unlock снимает блокировку
+	Lock(ctx context.Context, userID uuid.UUID) (unlock func(), err error)
+}
+
+// StatsCounter приближённые счётчики попаданий в зоны по минутам
+type StatsCounter interface {
+	// Record учитывает проверки с попаданием в зоны
+	Record(ctx context.Context, locs []*Location) error
+	// Stats статистика за [from, to); from, to и bucket кратны минуте. Ряд содержит только интервалы с попаданиями
+	Stats(ctx context.Context, from, to time.Time, bucket time.Duration) (*Stats, error)
+}
+
+// CooldownStore окна повтора уведомлений пользователя по зонам
+type CooldownStore interface {
+	// Active возвращает окончание окна для тех keys, окно которых ещё не истекло
+	Active(ctx context.Context, userID uuid.UUID, keys []CooldownKey) (map[CooldownKey]time.Time, error)
+	// Start открывает окна, которые закончатся в указанное время
+	Start(ctx context.Context, userID uuid.UUID, until map[CooldownKey]time.Time) error
 }
@@ -0,0 +1,33 @@
+package location
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Stats статистика попаданий в зоны за окно [From, To)
+type Stats struct {
+	From      time.Time
+	To        time.Time
+	Bucket    time.Duration
+	UserCount int             // уникальные пользователи, попавшие хотя бы в одну зону
+	Incidents []IncidentStats // разбивка по инцидентам, по убыванию числа пользователей
+	Series    []StatsBucket   // ряд по интервалам Bucket, начиная с From; пустые интервалы заполнены нулями
+	Exact     bool            // посчитано по locations; иначе число пользователей — оценка HyperLogLog
+}
+
+// IncidentStats попадания в одну зону
+type IncidentStats struct {
+	IncidentID uuid.UUID
+	Title      string // пусто, если инцидент уже удалён
+	UserCount  int    // уникальные пользователи
+	HitCount   int    // проверки с попаданием
+}
+
+// StatsBucket попадания за один интервал ряда
+type StatsBucket struct {
+	Start     time.Time
+	UserCount int
+	HitCount  int
+}
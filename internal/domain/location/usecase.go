@@ -1,9 +1,7 @@
 package location
 
-import (
-	"context"
+// Типы событий вебхука
+const (
+	EventGeofence  = "geofence"  // переходы ENTER/EXIT/DWELL
+	EventProximity = "proximity" // пользователь приближается к зоне
 )
-
-type WebhookQueue interface {
-	Enqueue(ctx context.Context, loc *Location) error
-}
@@ -0,0 +1,17 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message событие вебхука, записанное в одной транзакции с проверкой локации
+type Message struct {
+	ID         uuid.UUID       `json:"id"`
+	LocationID *uuid.UUID      `json:"location_id"` // nil у событий об изменении инцидента
+	EventType  string          `json:"event_type"`
+	Payload    json.RawMessage `json:"payload"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
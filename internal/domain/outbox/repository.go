@@ -0,0 +1,20 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+type OutboxRepository interface {
+	// Relay выбирает до limit неотправленных сообщений, передаёт их в publish и отмечает отправленными
+	// в той же транзакции. Если publish вернул ошибку, сообщения остаются неотправленными
+	Relay(ctx context.Context, limit int, publish func(ctx context.Context, msgs []Message) error) (int, error)
+	// Cleanup удаляет отправленные сообщения старше before
+	Cleanup(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Publisher очередь, в которую relay перекладывает сообщения. Повторная публикация
+// сообщения с тем же ID не должна попадать в очередь второй раз
+type Publisher interface {
+	Publish(ctx context.Context, msgs []Message) error
+}
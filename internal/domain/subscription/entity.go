@@ -0,0 +1,163 @@
+package subscription
+
+import (
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
+	"github.com/Soujuruya/01_SPEC/internal/domain/location"
+	"github.com/google/uuid"
+)
+
+// Форматы тела доставки
+const (
+	FormatJSON              = "json"               // WebhookPayload как есть
+	FormatCloudEvents       = "cloudevents"        // CloudEvents 1.0, structured mode
+	FormatCloudEventsBinary = "cloudevents-binary" // CloudEvents 1.0, binary mode: атрибуты в заголовках ce-*
+)
+
+type Subscription struct {
+	ID                      uuid.UUID   `json:"id"`                     // идентификатор подписки
+	URL                     string      `json:"url"`                    // адрес, на который доставляются события
+	Secret                  string      `json:"-"`                      // секрет, которым подписываются доставки
+	PreviousSecret          string      `json:"-"`                      // прежний секрет; после ротации доставки подписываются обоими
+	PreviousSecretExpiresAt *time.Time  `json:"-"`                      // до этого времени прежний секрет действует
+	EventTypes              []string    `json:"event_types"`            // типы событий; пусто — все, кроме incident
+	IncidentIDs             []uuid.UUID `json:"incident_ids"`           // только события с этими инцидентами
+	Categories              []string    `json:"categories"`             // только события с инцидентами этих категорий
+	MinSeverity             string      `json:"min_severity,omitempty"` // только события с инцидентами не ниже этой важности
+	Area                    *Area       `json:"area,omitempty"`         // только события пользователей внутри области
+	PayloadFormat           string      `json:"payload_format"`         // формат тела доставки: json, cloudevents, cloudevents-binary
+	IsActive                bool        `json:"is_active"`              // доставка включена
+	CreatedAt               time.Time   `json:"created_at"`
+	UpdatedAt               time.Time   `json:"updated_at"`
+}
+
+// Area круговая область, в которой должен находиться пользователь
+type Area struct {
+	Lat    float64 `json:"lat"`
+	Lng    float64 `json:"lng"`
+	Radius float64 `json:"radius"` // в метрах
+}
+
+// Event то, что известно о событии для сопоставления с подписками
+type Event struct {
+	Type      string
+	Lat       float64
+	Lng       float64
+	Incidents []location.IncidentInfo
+}
+
+func NewSubscription(url, secret string) *Subscription {
+	now := time.Now()
+	return &Subscription{
+		ID:            uuid.New(),
+		URL:           url,
+		Secret:        secret,
+		EventTypes:    []string{},
+		IncidentIDs:   []uuid.UUID{},
+		Categories:    []string{},
+		PayloadFormat: FormatJSON,
+		IsActive:      true,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Secrets действующие секреты: текущий и, до истечения, прежний
+func (s *Subscription) Secrets(now time.Time) []string {
+	secrets := []string{s.Secret}
+	if s.PreviousSecret != "" && s.PreviousSecretExpiresAt != nil && now.Before(*s.PreviousSecretExpiresAt) {
+		secrets = append(secrets, s.PreviousSecret)
+	}
+	return secrets
+}
+
+// RotateSecret заменяет секрет; прежний остаётся действующим ещё grace
+func (s *Subscription) RotateSecret(secret string, grace time.Duration, now time.Time) {
+	s.PreviousSecret = s.Secret
+	s.Secret = secret
+	s.PreviousSecretExpiresAt = nil
+	if grace > 0 {
+		until := now.Add(grace)
+		s.PreviousSecretExpiresAt = &until
+	} else {
+		s.PreviousSecret = ""
+	}
+	s.UpdatedAt = now
+}
+
+// IsEventType проверяет, что на этот тип событий можно подписаться
+func IsEventType(v string) bool {
+	switch v {
+	case location.EventGeofence, location.EventProximity, incident.EventLifecycle:
+		return true
+	}
+	return false
+}
+
+// IsOptIn события этого типа получают только подписки, явно перечислившие его в EventTypes
+func IsOptIn(eventType string) bool {
+	return eventType == incident.EventLifecycle
+}
+
+// IsPayloadFormat проверяет формат тела доставки
+func IsPayloadFormat(v string) bool {
+	switch v {
+	case FormatJSON, FormatCloudEvents, FormatCloudEventsBinary:
+		return true
+	}
+	return false
+}
+
+// Matches подходит ли событие под все фильтры подписки
+func (s *Subscription) Matches(e Event) bool {
+	if !s.IsActive || !s.matchesType(e.Type) {
+		return false
+	}
+	if s.Area != nil && incident.HaversineMeters(s.Area.Lat, s.Area.Lng, e.Lat, e.Lng) > s.Area.Radius {
+		return false
+	}
+	if len(s.IncidentIDs) == 0 && len(s.Categories) == 0 && s.MinSeverity == "" {
+		return true
+	}
+	for _, info := range e.Incidents {
+		if s.matchesIncident(info) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subscription) matchesType(eventType string) bool {
+	if len(s.EventTypes) == 0 {
+		return !IsOptIn(eventType)
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subscription) matchesIncident(info location.IncidentInfo) bool {
+	if len(s.IncidentIDs) > 0 && !contains(s.IncidentIDs, info.IncidentID) {
+		return false
+	}
+	if len(s.Categories) > 0 && !contains(s.Categories, info.Category) {
+		return false
+	}
+	if s.MinSeverity != "" && incident.SeverityRank(info.Severity) < incident.SeverityRank(s.MinSeverity) {
+		return false
+	}
+	return true
+}
+
+func contains[T comparable](list []T, v T) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
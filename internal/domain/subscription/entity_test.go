@@ -0,0 +1,67 @@
+package subscription
+
+import (
+	"testing"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
+	"github.com/Soujuruya/01_SPEC/internal/domain/location"
+	"github.com/google/uuid"
+)
+
+func TestSubscriptionMatches(t *testing.T) {
+	flood, fire := uuid.New(), uuid.New()
+	floodInfo := location.IncidentInfo{IncidentID: flood, Category: "flood", Severity: incident.SeverityWarning}
+	fireInfo := location.IncidentInfo{IncidentID: fire, Category: "fire", Severity: incident.SeverityCritical}
+
+	// событие в центре Москвы
+	event := func(eventType string, infos ...location.IncidentInfo) Event {
+		return Event{Type: eventType, Lat: 55.7558, Lng: 37.6173, Incidents: infos}
+	}
+	sub := func(edit func(s *Subscription)) *Subscription {
+		s := NewSubscription("https://hooks.example.com/in", "secret")
+		edit(s)
+		return s
+	}
+	all := sub(func(*Subscription) {})
+
+	tests := []struct {
+		name  string
+		sub   *Subscription
+		event Event
+		want  bool
+	}{
+		{"no filters", all, event(location.EventGeofence, floodInfo), true},
+		{"inactive", sub(func(s *Subscription) { s.IsActive = false }), event(location.EventGeofence, floodInfo), false},
+
+		{"opt-in type without explicit subscription", all, event(incident.EventLifecycle, floodInfo), false},
+		{"opt-in type listed", sub(func(s *Subscription) { s.EventTypes = []string{incident.EventLifecycle} }), event(incident.EventLifecycle, floodInfo), true},
+		{"type listed", sub(func(s *Subscription) { s.EventTypes = []string{location.EventProximity} }), event(location.EventProximity), true},
+		{"type not listed", sub(func(s *Subscription) { s.EventTypes = []string{location.EventProximity} }), event(location.EventGeofence, floodInfo), false},
+
+		{"incident listed", sub(func(s *Subscription) { s.IncidentIDs = []uuid.UUID{fire} }), event(location.EventGeofence, floodInfo, fireInfo), true},
+		{"incident not listed", sub(func(s *Subscription) { s.IncidentIDs = []uuid.UUID{fire} }), event(location.EventGeofence, floodInfo), false},
+		{"incident filter without incidents", sub(func(s *Subscription) { s.IncidentIDs = []uuid.UUID{fire} }), event(location.EventGeofence), false},
+
+		{"category listed", sub(func(s *Subscription) { s.Categories = []string{"flood"} }), event(location.EventGeofence, fireInfo, floodInfo), true},
+		{"category not listed", sub(func(s *Subscription) { s.Categories = []string{"flood"} }), event(location.EventGeofence, fireInfo), false},
+		{"severity at threshold", sub(func(s *Subscription) { s.MinSeverity = incident.SeverityWarning }), event(location.EventGeofence, floodInfo), true},
+		{"severity below threshold", sub(func(s *Subscription) { s.MinSeverity = incident.SeverityCritical }), event(location.EventGeofence, floodInfo), false},
+		// фильтры по инциденту применяются к одному и тому же инциденту, а не к событию в целом
+		{"category and incident on different incidents", sub(func(s *Subscription) {
+			s.IncidentIDs, s.Categories = []uuid.UUID{fire}, []string{"flood"}
+		}), event(location.EventGeofence, floodInfo, fireInfo), false},
+
+		{"inside area", sub(func(s *Subscription) { s.Area = &Area{Lat: 55.75, Lng: 37.62, Radius: 1000} }), event(location.EventGeofence, floodInfo), true},
+		{"outside area", sub(func(s *Subscription) { s.Area = &Area{Lat: 59.94, Lng: 30.31, Radius: 1000} }), event(location.EventGeofence, floodInfo), false},
+		{"area and category", sub(func(s *Subscription) {
+			s.Area, s.Categories = &Area{Lat: 55.75, Lng: 37.62, Radius: 1000}, []string{"fire"}
+		}), event(location.EventGeofence, floodInfo), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sub.Matches(tt.event); got != tt.want {
+				t.Fatalf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package subscription
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type SubscriptionRepository interface {
+	Create(ctx context.Context, s *Subscription) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Subscription, error)
+	Update(ctx context.Context, s *Subscription) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context) ([]*Subscription, error)
+	ListActive(ctx context.Context) ([]*Subscription, error)
+}
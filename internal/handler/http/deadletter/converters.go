@@ -0,0 +1,41 @@
+package deadletter
+
+import (
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/deadletter"
+)
+
+// DeadLetterToResponse; withPayload — добавить тело события (только при просмотре одной записи)
+func DeadLetterToResponse(d *deadletter.DeadLetter, withPayload bool) DeadLetterResponse {
+	resp := DeadLetterResponse{
+		ID:             d.ID.String(),
+		URL:            d.URL,
+		EventType:      d.EventType,
+		UserID:         d.UserID.String(),
+		LastError:      d.LastError,
+		LastStatusCode: d.LastStatusCode,
+		Attempts:       d.Attempts,
+		CreatedAt:      d.CreatedAt.Format(time.RFC3339),
+	}
+	if d.SubscriptionID != nil {
+		resp.SubscriptionID = d.SubscriptionID.String()
+	}
+	if withPayload {
+		resp.Payload = d.Payload
+	}
+	return resp
+}
+
+func DeadLettersToListResponse(letters []*deadletter.DeadLetter, offset, limit, total int) DeadLetterListResponse {
+	resp := make([]DeadLetterResponse, len(letters))
+	for i, d := range letters {
+		resp[i] = DeadLetterToResponse(d, false)
+	}
+	return DeadLetterListResponse{
+		DeadLetters: resp,
+		Limit:       limit,
+		Offset:      offset,
+		Total:       total,
+	}
+}
@@ -0,0 +1,35 @@
+package deadletter
+
+import (
+	"encoding/json"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/deadletter"
+)
+
+type DeadLetterResponse struct {
+	ID             string               `json:"id"`
+	SubscriptionID string               `json:"subscription_id,omitempty"`
+	URL            string               `json:"url"`
+	EventType      string               `json:"event_type"`
+	UserID         string               `json:"user_id"`
+	LastError      string               `json:"last_error"`
+	LastStatusCode int                  `json:"last_status_code,omitempty"`
+	Attempts       []deadletter.Attempt `json:"attempts"`
+	Payload        json.RawMessage      `json:"payload,omitempty" swaggertype:"object"`
+	CreatedAt      string               `json:"created_at"`
+}
+
+type DeadLetterListResponse struct {
+	DeadLetters []DeadLetterResponse `json:"dead_letters"`
+	Limit       int                  `json:"limit"`
+	Offset      int                  `json:"offset"`
+	Total       int                  `json:"total"`
+}
+
+type ReplayAllResponse struct {
+	Replayed int `json:"replayed"`
+}
+
+type PurgeResponse struct {
+	Purged int64 `json:"purged"`
+}
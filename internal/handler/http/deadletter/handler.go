@@ -0,0 +1,213 @@
+package deadletter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/httphelper"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/Soujuruya/01_SPEC/internal/usecase"
+	"github.com/google/uuid"
+)
+
+const (
+	// BasePath префикс админских путей dead letters
+	BasePath = "/api/v1/admin/dead-letters"
+	// ReplayPath суффикс пути повторной отправки
+	ReplayPath = "/replay"
+)
+
+type DeadLetterHandler struct {
+	Service *usecase.DeadLetterService
+	lg      *logger.Logger
+}
+
+func NewDeadLetterHandler(service *usecase.DeadLetterService, lg *logger.Logger) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		Service: service,
+		lg:      lg,
+	}
+}
+
+// ListDeadLetters godoc
+// @Summary List Dead Letters
+// @Description Вебхуки, которые не удалось доставить за RETRY_LIMIT попыток, от новых к старым
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Limit for pagination" default(10)
+// @Param offset query int false "Offset for pagination" default(0)
+// @Success 200 {object} deadletter.DeadLetterListResponse
+// @Failure 400 {object} httphelper.APIResponse "Invalid limit/offset"
+// @Failure 500 {object} httphelper.APIResponse "Internal server error"
+// @Router /admin/dead-letters [get]
+func (h *DeadLetterHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit, offset := 10, 0
+	var err error
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil {
+			h.lg.Error("ListDeadLetters: invalid limit", "value", l, "error", err)
+			httphelper.WriteError(w, fmt.Errorf("invalid limit format"), http.StatusBadRequest)
+			return
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if offset, err = strconv.Atoi(o); err != nil {
+			h.lg.Error("ListDeadLetters: invalid offset", "value", o, "error", err)
+			httphelper.WriteError(w, fmt.Errorf("invalid offset format"), http.StatusBadRequest)
+			return
+		}
+	}
+	if limit < 1 || limit > 200 || offset < 0 {
+		err := fmt.Errorf("limit must be between 1 and 200 and offset >= 0, got limit=%d offset=%d", limit, offset)
+		h.lg.Error("ListDeadLetters: invalid limit/offset", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	letters, total, err := h.Service.List(r.Context(), offset, limit)
+	if err != nil {
+		h.lg.Error("ListDeadLetters: failed to list dead letters", "error", err)
+		httphelper.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.lg.Debug("ListDeadLetters: success", "returned", len(letters), "total", total)
+	httphelper.WriteJSON(w, DeadLettersToListResponse(letters, offset, limit, total), http.StatusOK)
+}
+
+// GetDeadLetter godoc
+// @Summary Get Dead Letter by ID
+// @Description Запись с историей попыток и телом события
+// @Tags admin
+// @Produce json
+// @Param id path string true "Dead letter UUID"
+// @Success 200 {object} deadletter.DeadLetterResponse
+// @Failure 400 {object} httphelper.APIResponse "Invalid UUID"
+// @Failure 404 {object} httphelper.APIResponse "Dead letter not found"
+// @Failure 500 {object} httphelper.APIResponse "Internal server error"
+// @Router /admin/dead-letters/{id} [get]
+func (h *DeadLetterHandler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := httphelper.ParseUUIDFromPath(r, BasePath+"/")
+	if err != nil {
+		h.lg.Error("GetDeadLetter: invalid UUID in path", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	d, err := h.Service.Get(r.Context(), id)
+	if err != nil {
+		h.writeLookupError(w, "GetDeadLetter", id, err)
+		return
+	}
+
+	httphelper.WriteJSON(w, DeadLetterToResponse(d, true), http.StatusOK)
+}
+
+// ReplayDeadLetter godoc
+// @Summary Replay Dead Letter
+// @Description Возвращает событие в очередь вебхуков с обнулённым счётчиком попыток и удаляет запись
+// @Tags admin
+// @Param id path string true "Dead letter UUID"
+// @Success 204 "No Content"
+// @Failure 400 {object} httphelper.APIResponse "Invalid UUID"
+// @Failure 404 {object} httphelper.APIResponse "Dead letter not found"
+// @Failure 500 {object} httphelper.APIResponse "Internal server error"
+// @Router /admin/dead-letters/{id}/replay [post]
+func (h *DeadLetterHandler) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, BasePath+"/"), ReplayPath))
+	if err != nil {
+		h.lg.Error("ReplayDeadLetter: invalid UUID in path", "error", err)
+		httphelper.WriteError(w, fmt.Errorf("invalid id format"), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.Replay(r.Context(), id); err != nil {
+		h.writeLookupError(w, "ReplayDeadLetter", id, err)
+		return
+	}
+
+	h.lg.Info("ReplayDeadLetter: success", "dead_letter_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReplayAllDeadLetters godoc
+// @Summary Replay All Dead Letters
+// @Description Возвращает в очередь все записи
+// @Tags admin
+// @Produce json
+// @Success 200 {object} deadletter.ReplayAllResponse
+// @Failure 500 {object} httphelper.APIResponse "Internal server error"
+// @Router /admin/dead-letters/replay [post]
+func (h *DeadLetterHandler) ReplayAllDeadLetters(w http.ResponseWriter, r *http.Request) {
+	n, err := h.Service.ReplayAll(r.Context())
+	if err != nil {
+		h.lg.Error("ReplayAllDeadLetters: failed", "replayed", n, "error", err)
+		httphelper.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.lg.Info("ReplayAllDeadLetters: success", "replayed", n)
+	httphelper.WriteJSON(w, ReplayAllResponse{Replayed: n}, http.StatusOK)
+}
+
+// DeleteDeadLetter godoc
+// @Summary Delete Dead Letter
+// @Description Удаляет запись без повторной отправки
+// @Tags admin
+// @Param id path string true "Dead letter UUID"
+// @Success 204 "No Content"
+// @Failure 400 {object} httphelper.APIResponse "Invalid UUID"
+// @Failure 404 {object} httphelper.APIResponse "Dead letter not found"
+// @Failure 500 {object} httphelper.APIResponse "Internal server error"
+// @Router /admin/dead-letters/{id} [delete]
+func (h *DeadLetterHandler) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, err := httphelper.ParseUUIDFromPath(r, BasePath+"/")
+	if err != nil {
+		h.lg.Error("DeleteDeadLetter: invalid UUID in path", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.Delete(r.Context(), id); err != nil {
+		h.writeLookupError(w, "DeleteDeadLetter", id, err)
+		return
+	}
+
+	h.lg.Info("DeleteDeadLetter: success", "dead_letter_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PurgeDeadLetters godoc
+// @Summary Purge Dead Letters
+// @Description Удаляет все записи без повторной отправки
+// @Tags admin
+// @Produce json
+// @Success 200 {object} deadletter.PurgeResponse
+// @Failure 500 {object} httphelper.APIResponse "Internal server error"
+// @Router /admin/dead-letters [delete]
+func (h *DeadLetterHandler) PurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	n, err := h.Service.Purge(r.Context())
+	if err != nil {
+		h.lg.Error("PurgeDeadLetters: failed", "error", err)
+		httphelper.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.lg.Info("PurgeDeadLetters: success", "purged", n)
+	httphelper.WriteJSON(w, PurgeResponse{Purged: n}, http.StatusOK)
+}
+
+func (h *DeadLetterHandler) writeLookupError(w http.ResponseWriter, op string, id uuid.UUID, err error) {
+	if errors.Is(err, errs.ErrNotFound) {
+		h.lg.Warn(op+": dead letter not found", "dead_letter_id", id)
+		httphelper.WriteError(w, err, http.StatusNotFound)
+		return
+	}
+	h.lg.Error(op+": failed", "dead_letter_id", id, "error", err)
+	httphelper.WriteError(w, err, http.StatusInternalServerError)
+}
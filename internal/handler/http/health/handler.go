@@ -5,24 +5,38 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Soujuruya/01_SPEC/internal/integration"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
 	"go.uber.org/zap"
 )
 
+// CircuitStates источник состояний автоматов получателей вебхуков
+type CircuitStates interface {
+	States() []integration.CircuitState
+}
+
+// HealthResponse состояние сервиса
+type HealthResponse struct {
+	Status          string                     `json:"status" example:"ok"` // ok либо degraded, если автомат какого-то получателя не замкнут
+	Timestamp       int64                      `json:"timestamp"`
+	WebhookCircuits []integration.CircuitState `json:"webhook_circuits"`
+}
+
 type HealthHandler struct {
-	lg *logger.Logger
+	circuits CircuitStates
+	lg       *logger.Logger
 }
 
-func NewHealthHandler(lg *logger.Logger) *HealthHandler {
-	return &HealthHandler{lg: lg}
+func NewHealthHandler(circuits CircuitStates, lg *logger.Logger) *HealthHandler {
+	return &HealthHandler{circuits: circuits, lg: lg}
 }
 
 // HealthCheck godoc
 // @Summary Health Check
-// @Description Проверка доступности API
+// @Description Проверка доступности API и состояние автоматов получателей вебхуков
 // @Tags health
 // @Produce json
-// @Success 200 {object} map[string]string "status: ok"
+// @Success 200 {object} HealthResponse
 // @Router /system/health [get]
 func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	h.lg.Debug("HealthHandler.HealthCheck: check received", zap.String("remote_addr", r.RemoteAddr))
@@ -30,9 +44,15 @@ func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	resp := map[string]interface{}{
-		"status":    "ok",
-		"timestamp": time.Now().Unix(),
+	resp := HealthResponse{
+		Status:          "ok",
+		Timestamp:       time.Now().Unix(),
+		WebhookCircuits: h.circuits.States(),
+	}
+	for _, c := range resp.WebhookCircuits {
+		if c.State != integration.CircuitClosed {
+			resp.Status = "degraded"
+		}
 	}
 
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
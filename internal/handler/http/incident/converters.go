@@ -1,6 +1,7 @@
 package incident
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
@@ -13,9 +14,88 @@ func IncidentToResponse(inc *incident.Incident) IncidentResponse {
 		Lat:       inc.Lat,
 		Lng:       inc.Lng,
 		Radius:    inc.Radius,
+		Geometry:  GeometryToDTO(inc.Geometry),
+		Severity:  inc.Severity,
+		Category:  inc.Category,
 		IsActive:  inc.IsActive,
 		CreatedAt: inc.CreatedAt.Format(time.RFC3339),
+
+		StartsAt:   formatTime(inc.StartsAt),
+		EndsAt:     formatTime(inc.EndsAt),
+		Recurrence: RecurrenceToDTO(inc.Recurrence),
+
+		NotifyCooldownSeconds: CooldownToDTO(inc.NotifyCooldown),
+	}
+}
+
+// CooldownFromDTO окно повтора из секунд; отрицательное значение снимает переопределение
+func CooldownFromDTO(secs int) *time.Duration {
+	if secs < 0 {
+		return nil
+	}
+	d := time.Duration(secs) * time.Second
+	return &d
+}
+
+func CooldownToDTO(d *time.Duration) *int {
+	if d == nil {
+		return nil
+	}
+	secs := int(d.Seconds())
+	return &secs
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func RecurrenceFromDTO(dto *RecurrenceDTO) *incident.Recurrence {
+	if dto == nil {
+		return nil
+	}
+	return &incident.Recurrence{
+		Frequency: dto.Frequency,
+		Weekdays:  dto.Weekdays,
+		StartTime: dto.StartTime,
+		EndTime:   dto.EndTime,
+		Timezone:  dto.Timezone,
+	}
+}
+
+func RecurrenceToDTO(r *incident.Recurrence) *RecurrenceDTO {
+	if r == nil {
+		return nil
+	}
+	return &RecurrenceDTO{
+		Frequency: r.Frequency,
+		Weekdays:  r.Weekdays,
+		StartTime: r.StartTime,
+		EndTime:   r.EndTime,
+		Timezone:  r.Timezone,
+	}
+}
+
+func GeometryFromDTO(dto *GeometryDTO) (*incident.Geometry, error) {
+	return incident.ParseGeometry(dto.Type, dto.Coordinates)
+}
+
+func GeometryToDTO(g *incident.Geometry) *GeometryDTO {
+	if g == nil {
+		return nil
+	}
+
+	var dto GeometryDTO
+	data, err := json.Marshal(g)
+	if err != nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &dto); err != nil {
+		return nil
 	}
+	return &dto
 }
 
 func IncidentsToListResponse(incs []*incident.Incident, offset, limit, total int) IncidentListResponse {
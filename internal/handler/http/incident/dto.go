@@ -1,29 +1,76 @@
 package incident
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// GeometryDTO GeoJSON Polygon или MultiPolygon, координаты в порядке [lng, lat]
+type GeometryDTO struct {
+	Type        string          `json:"type" example:"Polygon"`
+	Coordinates json.RawMessage `json:"coordinates" swaggertype:"array,number"`
+}
+
+// RecurrenceDTO повторяющееся окно активности; если end_time не позже start_time, окно переходит через полночь
+type RecurrenceDTO struct {
+	Frequency string   `json:"frequency" enums:"daily,weekly" example:"daily"`
+	Weekdays  []string `json:"weekdays,omitempty" example:"mon,tue"`
+	StartTime string   `json:"start_time" example:"22:00"`
+	EndTime   string   `json:"end_time" example:"06:00"`
+	Timezone  string   `json:"timezone,omitempty" example:"Europe/Moscow"`
+}
+
 type CreateIncidentRequest struct {
-	Title    string  `json:"title"`
-	Lat      float64 `json:"lat"`
-	Lng      float64 `json:"lng"`
-	Radius   float64 `json:"radius"`
-	IsActive bool    `json:"is_active"`
+	Title    string       `json:"title"`
+	Lat      float64      `json:"lat"`
+	Lng      float64      `json:"lng"`
+	Radius   float64      `json:"radius"`
+	Geometry *GeometryDTO `json:"geometry,omitempty"`
+	Severity string       `json:"severity,omitempty" enums:"info,warning,critical" example:"warning"`
+	Category string       `json:"category,omitempty" enums:"fire,flood,police,traffic,medical,weather,other" example:"other"`
+	IsActive bool         `json:"is_active"`
+
+	StartsAt   *time.Time     `json:"starts_at,omitempty"`
+	EndsAt     *time.Time     `json:"ends_at,omitempty"`
+	Recurrence *RecurrenceDTO `json:"recurrence,omitempty"`
+
+	NotifyCooldownSeconds *int `json:"notify_cooldown_seconds,omitempty" example:"600"` // окно повтора уведомлений по зоне; 0 — без окна
 }
 
 type UpdateIncidentRequest struct {
-	Title    *string  `json:"title"`
-	Lat      *float64 `json:"lat"`
-	Lng      *float64 `json:"lng"`
-	Radius   *float64 `json:"radius"`
-	IsActive *bool    `json:"is_active"`
+	Title    *string      `json:"title"`
+	Lat      *float64     `json:"lat"`
+	Lng      *float64     `json:"lng"`
+	Radius   *float64     `json:"radius"`
+	Geometry *GeometryDTO `json:"geometry,omitempty"`
+	Severity *string      `json:"severity,omitempty" enums:"info,warning,critical"`
+	Category *string      `json:"category,omitempty" enums:"fire,flood,police,traffic,medical,weather,other"`
+	IsActive *bool        `json:"is_active"`
+
+	StartsAt   *time.Time     `json:"starts_at,omitempty"`
+	EndsAt     *time.Time     `json:"ends_at,omitempty"`
+	Recurrence *RecurrenceDTO `json:"recurrence,omitempty"`
+
+	NotifyCooldownSeconds *int `json:"notify_cooldown_seconds,omitempty"` // -1 — вернуть глобальную настройку NOTIFY_COOLDOWN
 }
 
 type IncidentResponse struct {
-	ID        string  `json:"id"`
-	Title     string  `json:"title"`
-	Lat       float64 `json:"lat"`
-	Lng       float64 `json:"lng"`
-	Radius    float64 `json:"radius"`
-	IsActive  bool    `json:"is_active"`
-	CreatedAt string  `json:"created_at"`
+	ID        string       `json:"id"`
+	Title     string       `json:"title"`
+	Lat       float64      `json:"lat"`
+	Lng       float64      `json:"lng"`
+	Radius    float64      `json:"radius"`
+	Geometry  *GeometryDTO `json:"geometry,omitempty"`
+	Severity  string       `json:"severity"`
+	Category  string       `json:"category"`
+	IsActive  bool         `json:"is_active"`
+	CreatedAt string       `json:"created_at"`
+
+	StartsAt   string         `json:"starts_at,omitempty"`
+	EndsAt     string         `json:"ends_at,omitempty"`
+	Recurrence *RecurrenceDTO `json:"recurrence,omitempty"`
+
+	NotifyCooldownSeconds *int `json:"notify_cooldown_seconds,omitempty"` // не задано — глобальная настройка NOTIFY_COOLDOWN
 }
 
 type IncidentListResponse struct {
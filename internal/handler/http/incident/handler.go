@@ -29,7 +29,7 @@ func NewIncidentHandler(service *usecase.IncidentService, lg *logger.Logger) *In
 
 // CreateIncident godoc
 // @Summary Create Incidents
-// @Description Создаёт новый инцидент с указанным заголовком, координатами и радиусом
+// @Description Создаёт новый инцидент с указанным заголовком, координатами и радиусом либо с зоной GeoJSON Polygon/MultiPolygon. Необязательные starts_at, ends_at и recurrence задают расписание активности
 // @Tags incident
 // @Accept json
 // @Produce json
@@ -60,6 +60,27 @@ func (h *IncidentHandler) CreateIncident(w http.ResponseWriter, r *http.Request)
 		incidentDTO.Radius,
 		true,
 	)
+	if incidentDTO.Geometry != nil {
+		geom, err := GeometryFromDTO(incidentDTO.Geometry)
+		if err != nil {
+			h.lg.Error("CreateIncident: invalid geometry", "error", err)
+			httphelper.WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+		inc.SetGeometry(geom)
+	}
+	if incidentDTO.Severity != "" {
+		inc.Severity = incidentDTO.Severity
+	}
+	if incidentDTO.Category != "" {
+		inc.Category = incidentDTO.Category
+	}
+	inc.StartsAt = incidentDTO.StartsAt
+	inc.EndsAt = incidentDTO.EndsAt
+	inc.Recurrence = RecurrenceFromDTO(incidentDTO.Recurrence)
+	if incidentDTO.NotifyCooldownSeconds != nil {
+		inc.NotifyCooldown = CooldownFromDTO(*incidentDTO.NotifyCooldownSeconds)
+	}
 
 	if err := h.Service.CreateIncident(r.Context(), inc); err != nil {
 		h.lg.Error("CreateIncident: failed to create incident", "error", err)
@@ -108,13 +129,15 @@ func (h *IncidentHandler) GetIncident(w http.ResponseWriter, r *http.Request) {
 
 // GetListIncidents godoc
 // @Summary Get All Incidents
-// @Description Получает все активные и неактивные инциденты с поддержкой пагинации
+// @Description Получает все активные и неактивные инциденты с поддержкой пагинации и фильтрацией по важности и категории
 // @Tags incident
 // @Produce json
 // @Param limit query int false "Limit for pagination" default(10)
 // @Param offset query int false "Offset for pagination" default(0)
+// @Param severity query string false "Severities, comma separated (info,warning,critical)"
+// @Param category query string false "Categories, comma separated (fire,flood,police,traffic,medical,weather,other)"
 // @Success 200 {object} incident.IncidentListResponse
-// @Failure 400 {object} httphelper.APIResponse "Invalid limit/offset"
+// @Failure 400 {object} httphelper.APIResponse "Invalid limit/offset or filter"
 // @Failure 500 {object} httphelper.APIResponse "Internal server error"
 // @Router /incidents [get]
 func (h *IncidentHandler) GetListIncidents(w http.ResponseWriter, r *http.Request) {
@@ -146,7 +169,14 @@ func (h *IncidentHandler) GetListIncidents(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	incs, total, err := h.Service.ListIncidents(r.Context(), offset, limit)
+	filter, err := ParseListFilter(r.URL.Query())
+	if err != nil {
+		h.lg.Error("GetListIncidents: invalid filter", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	incs, total, err := h.Service.ListIncidents(r.Context(), filter, offset, limit)
 	if err != nil {
 		h.lg.Error("GetListIncidents: failed to list incidents", "offset", offset, "limit", limit, "error", err)
 		httphelper.WriteError(w, err, http.StatusInternalServerError)
@@ -186,7 +216,7 @@ func (h *IncidentHandler) GetActiveIncidents(w http.ResponseWriter, r *http.Requ
 
 // DeactivateIncident godoc
 // @Summary Deactivate Incident by ID
-// @Description Деактивирует инцидент, не удаляя его полностью
+// @Description Деактивирует инцидент, не удаляя его полностью; ends_at становится текущим временем
 // @Tags incident
 // @Produce json
 // @Param id path string true "Incident UUID"
@@ -276,10 +306,50 @@ func (h *IncidentHandler) UpdateIncident(w http.ResponseWriter, r *http.Request)
 	if incidentDTO.Radius != nil {
 		existing.Radius = *incidentDTO.Radius
 	}
+	if incidentDTO.Geometry != nil {
+		geom, err := GeometryFromDTO(incidentDTO.Geometry)
+		if err != nil {
+			h.lg.Error("UpdateIncident: invalid geometry", "error", err)
+			httphelper.WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+		existing.SetGeometry(geom)
+	}
+	if incidentDTO.Severity != nil {
+		existing.Severity = *incidentDTO.Severity
+	}
+	if incidentDTO.Category != nil {
+		existing.Category = *incidentDTO.Category
+	}
+	if incidentDTO.StartsAt != nil {
+		existing.StartsAt = incidentDTO.StartsAt
+	}
+	if incidentDTO.EndsAt != nil {
+		existing.EndsAt = incidentDTO.EndsAt
+	}
+	if incidentDTO.Recurrence != nil {
+		existing.Recurrence = RecurrenceFromDTO(incidentDTO.Recurrence)
+	}
+	if incidentDTO.NotifyCooldownSeconds != nil {
+		existing.NotifyCooldown = CooldownFromDTO(*incidentDTO.NotifyCooldownSeconds)
+	}
+	now := time.Now()
 	if incidentDTO.IsActive != nil {
-		existing.IsActive = *incidentDTO.IsActive
+		existing.SetActive(*incidentDTO.IsActive, now)
+	}
+	if err := existing.ValidateSchedule(); err != nil {
+		h.lg.Error("UpdateIncident: invalid schedule", "incident_id", id, "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	// сервис выставляет is_active по расписанию, поэтому включение вне окна повторения молча не сохранилось бы
+	if incidentDTO.IsActive != nil && existing.ActiveAt(now) != *incidentDTO.IsActive {
+		err := errors.New("is_active contradicts the incident schedule: the recurrence window is closed now")
+		h.lg.Error("UpdateIncident: is_active contradicts schedule", "incident_id", id)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
 	}
-	existing.UpdatedAt = time.Now()
+	existing.UpdatedAt = now
 
 	if err := h.Service.UpdateIncident(r.Context(), existing); err != nil {
 		h.lg.Error("UpdateIncident: failed to update incident", "incident_id", id, "error", err)
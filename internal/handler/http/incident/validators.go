@@ -3,6 +3,11 @@ package incident
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
 )
 
 func ValidateCreateIncident(req *CreateIncidentRequest) error {
@@ -16,6 +21,24 @@ func ValidateCreateIncident(req *CreateIncidentRequest) error {
 
 		return fmt.Errorf("longitude must be between -180 and 180, got %f", req.Lng)
 	}
+	if err := validateSchedule(req.StartsAt, req.EndsAt, req.Recurrence); err != nil {
+		return err
+	}
+	if req.Severity != "" && !incident.IsSeverity(req.Severity) {
+		return fmt.Errorf("unknown severity %q, expected info, warning or critical", req.Severity)
+	}
+	if req.Category != "" && !incident.IsCategory(req.Category) {
+		return fmt.Errorf("unknown category %q", req.Category)
+	}
+	if req.NotifyCooldownSeconds != nil && *req.NotifyCooldownSeconds < 0 {
+		return fmt.Errorf("notify_cooldown_seconds must be non-negative, got %d", *req.NotifyCooldownSeconds)
+	}
+	if req.Geometry != nil {
+		if _, err := GeometryFromDTO(req.Geometry); err != nil {
+			return err
+		}
+		return nil
+	}
 	if req.Radius <= 0 {
 		return fmt.Errorf("radius must be non-negative or null, got %f", req.Radius)
 	}
@@ -35,7 +58,54 @@ func ValidateUpdateIncident(req *UpdateIncidentRequest) error {
 	if req.Radius != nil && *req.Radius < 0 {
 		return fmt.Errorf("radius must be non-negative, got %f", *req.Radius)
 	}
-	return nil
+	if req.NotifyCooldownSeconds != nil && *req.NotifyCooldownSeconds < -1 {
+		return fmt.Errorf("notify_cooldown_seconds must be non-negative or -1, got %d", *req.NotifyCooldownSeconds)
+	}
+	if req.Geometry != nil {
+		if _, err := GeometryFromDTO(req.Geometry); err != nil {
+			return err
+		}
+	}
+	if req.Severity != nil && !incident.IsSeverity(*req.Severity) {
+		return fmt.Errorf("unknown severity %q, expected info, warning or critical", *req.Severity)
+	}
+	if req.Category != nil && !incident.IsCategory(*req.Category) {
+		return fmt.Errorf("unknown category %q", *req.Category)
+	}
+	return validateSchedule(req.StartsAt, req.EndsAt, req.Recurrence)
+}
+
+func validateSchedule(startsAt, endsAt *time.Time, recurrence *RecurrenceDTO) error {
+	inc := incident.Incident{StartsAt: startsAt, EndsAt: endsAt, Recurrence: RecurrenceFromDTO(recurrence)}
+	return inc.ValidateSchedule()
+}
+
+// ParseListFilter разбирает фильтр списка из параметров severity и category (значения через запятую)
+func ParseListFilter(query url.Values) (incident.ListFilter, error) {
+	var filter incident.ListFilter
+	for _, s := range splitList(query.Get("severity")) {
+		if !incident.IsSeverity(s) {
+			return filter, fmt.Errorf("unknown severity %q, expected info, warning or critical", s)
+		}
+		filter.Severities = append(filter.Severities, s)
+	}
+	for _, c := range splitList(query.Get("category")) {
+		if !incident.IsCategory(c) {
+			return filter, fmt.Errorf("unknown category %q", c)
+		}
+		filter.Categories = append(filter.Categories, c)
+	}
+	return filter, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
 }
 
 func ValidateLimitOffset(limit, offset int) error {
@@ -1,6 +1,9 @@
 package location
 
-import "github.com/Soujuruya/01_SPEC/internal/domain/location"
+import (
+	"github.com/Soujuruya/01_SPEC/internal/domain/location"
+	"github.com/Soujuruya/01_SPEC/internal/usecase"
+)
 
 func LocationToResponse(loc *location.Location) *LocationResponse {
 	return &LocationResponse{
@@ -11,5 +14,103 @@ func LocationToResponse(loc *location.Location) *LocationResponse {
 		Timestamp:   loc.Timestamp,
 		IsCheck:     loc.IsCheck,
 		IncidentIDs: loc.IncidentIDs,
+		Matches:     MatchesToResponse(loc.Matches),
+		Confidence:  loc.Confidence,
+		Transitions: TransitionsToResponse(loc.Transitions),
+		Nearby:      NearbyToResponse(loc.Nearby),
+
+		PassedThroughIncidentIDs: loc.PassedThroughIncidentIDs,
+
+		FixDTO: FixToDTO(loc.Fix),
+
+		Notification: NotificationToResponse(loc.Notification),
+	}
+}
+
+func NotificationToResponse(n location.Notification) NotificationResponse {
+	suppressed := make([]SuppressedHitResponse, len(n.Suppressed))
+	for i, h := range n.Suppressed {
+		suppressed[i] = SuppressedHitResponse{
+			EventType:  h.EventType,
+			IncidentID: h.IncidentID,
+			Event:      h.Event,
+			Reason:     h.Reason,
+			Until:      h.Until,
+		}
+	}
+	return NotificationResponse{Sent: n.Sent, Reason: n.Reason, Suppressed: suppressed}
+}
+
+func MatchesToResponse(matches []location.Match) []MatchResponse {
+	resp := make([]MatchResponse, len(matches))
+	for i, m := range matches {
+		resp[i] = MatchResponse{
+			IncidentID: m.IncidentID,
+			Confidence: m.Confidence,
+		}
+	}
+	return resp
+}
+
+func FixFromDTO(dto FixDTO) location.Fix {
+	return location.Fix{
+		AccuracyMeters: dto.AccuracyMeters,
+		Altitude:       dto.Altitude,
+		Speed:          dto.Speed,
+		Heading:        dto.Heading,
+	}
+}
+
+func FixToDTO(fix location.Fix) FixDTO {
+	return FixDTO{
+		AccuracyMeters: fix.AccuracyMeters,
+		Altitude:       fix.Altitude,
+		Speed:          fix.Speed,
+		Heading:        fix.Heading,
+	}
+}
+
+func NearbyToResponse(nearby []location.NearbyIncident) []NearbyResponse {
+	resp := make([]NearbyResponse, len(nearby))
+	for i, n := range nearby {
+		resp[i] = NearbyResponse{
+			IncidentID:     n.IncidentID,
+			DistanceMeters: n.DistanceMeters,
+			BearingDegrees: n.BearingDegrees,
+		}
+	}
+	return resp
+}
+
+func LocationsToBatchResponse(locs []*location.Location) CheckLocationBatchResponse {
+	results := make([]*LocationResponse, len(locs))
+	for i, loc := range locs {
+		results[i] = LocationToResponse(loc)
+	}
+	return CheckLocationBatchResponse{Results: results}
+}
+
+func PointsFromBatchRequest(req *CheckLocationBatchRequest) []usecase.LocationPoint {
+	points := make([]usecase.LocationPoint, len(req.Points))
+	for i, p := range req.Points {
+		points[i] = usecase.LocationPoint{
+			UserID:    p.UserID,
+			Lat:       p.Lat,
+			Lng:       p.Lng,
+			Timestamp: p.Timestamp,
+			Fix:       FixFromDTO(p.FixDTO),
+		}
+	}
+	return points
+}
+
+func TransitionsToResponse(transitions []location.Transition) []TransitionResponse {
+	resp := make([]TransitionResponse, len(transitions))
+	for i, t := range transitions {
+		resp[i] = TransitionResponse{
+			IncidentID: t.IncidentID,
+			Event:      t.Event,
+		}
 	}
+	return resp
 }
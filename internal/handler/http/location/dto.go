@@ -10,14 +10,81 @@ type CheckLocationRequest struct {
 	UserID uuid.UUID `json:"user_id"`
 	Lat    float64   `json:"lat"`
 	Lng    float64   `json:"lng"`
+	FixDTO
+}
+
+type CheckLocationPoint struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Lat       float64   `json:"lat"`
+	Lng       float64   `json:"lng"`
+	Timestamp time.Time `json:"timestamp"` // время фиксации точки на клиенте
+	FixDTO
+}
+
+// FixDTO необязательные данные GNSS-фиксации
+type FixDTO struct {
+	AccuracyMeters *float64 `json:"accuracy_m,omitempty" example:"25"` // радиус круга точности в метрах
+	Altitude       *float64 `json:"altitude,omitempty"`                // высота в метрах
+	Speed          *float64 `json:"speed,omitempty"`                   // скорость в м/с
+	Heading        *float64 `json:"heading,omitempty"`                 // направление движения, 0..360 от севера
+}
+
+type CheckLocationBatchRequest struct {
+	Points []CheckLocationPoint `json:"points"`
+}
+
+type CheckLocationBatchResponse struct {
+	Results []*LocationResponse `json:"results"`
 }
 
 type LocationResponse struct {
-	ID          uuid.UUID   `json:"id"`
-	UserID      uuid.UUID   `json:"user_id"`
-	Lat         float64     `json:"lat"`
-	Lng         float64     `json:"lng"`
-	Timestamp   time.Time   `json:"timestamp"`
-	IsCheck     bool        `json:"is_check"`
-	IncidentIDs []uuid.UUID `json:"incident_ids"`
+	ID          uuid.UUID            `json:"id"`
+	UserID      uuid.UUID            `json:"user_id"`
+	Lat         float64              `json:"lat"`
+	Lng         float64              `json:"lng"`
+	Timestamp   time.Time            `json:"timestamp"`
+	IsCheck     bool                 `json:"is_check"`
+	IncidentIDs []uuid.UUID          `json:"incident_ids"`
+	Matches     []MatchResponse      `json:"matches"`
+	Confidence  string               `json:"confidence" enums:"certain,possible,outside"`
+	Transitions []TransitionResponse `json:"transitions"`
+	Nearby      []NearbyResponse     `json:"nearby"`
+
+	PassedThroughIncidentIDs []uuid.UUID `json:"passed_through_incident_ids"`
+
+	FixDTO
+
+	Notification NotificationResponse `json:"notification"`
+}
+
+// NotificationResponse отправлено ли уведомление получателям вебхуков
+type NotificationResponse struct {
+	Sent       bool                    `json:"sent"`
+	Reason     string                  `json:"reason,omitempty" enums:"cooldown,no_change,late"`
+	Suppressed []SuppressedHitResponse `json:"suppressed"`
+}
+
+// SuppressedHitResponse событие по зоне, подавленное окном повтора
+type SuppressedHitResponse struct {
+	EventType  string     `json:"event_type" enums:"geofence,proximity"`
+	IncidentID uuid.UUID  `json:"incident_id"`
+	Event      string     `json:"event" enums:"ENTER,EXIT,DWELL,PASSED_THROUGH"`
+	Reason     string     `json:"reason" enums:"cooldown"`
+	Until      *time.Time `json:"until,omitempty"`
+}
+
+type MatchResponse struct {
+	IncidentID uuid.UUID `json:"incident_id"`
+	Confidence string    `json:"confidence" enums:"certain,possible"`
+}
+
+type NearbyResponse struct {
+	IncidentID     uuid.UUID `json:"incident_id"`
+	DistanceMeters float64   `json:"distance_m"`
+	BearingDegrees float64   `json:"bearing"`
+}
+
+type TransitionResponse struct {
+	IncidentID uuid.UUID `json:"incident_id"`
+	Event      string    `json:"event" enums:"ENTER,EXIT,DWELL"`
 }
@@ -2,8 +2,11 @@ package location
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/Soujuruya/01_SPEC/internal/config"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/httphelper"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
 	"github.com/Soujuruya/01_SPEC/internal/usecase"
@@ -11,19 +14,21 @@ import (
 
 type LocationHandler struct {
 	Service *usecase.LocationService
+	cfg     *config.Config
 	lg      *logger.Logger
 }
 
-func NewLocationHandler(service *usecase.LocationService, lg *logger.Logger) *LocationHandler {
+func NewLocationHandler(service *usecase.LocationService, cfg *config.Config, lg *logger.Logger) *LocationHandler {
 	return &LocationHandler{
 		Service: service,
+		cfg:     cfg,
 		lg:      lg,
 	}
 }
 
 // CheckLocation godoc
 // @Summary Check user location incidents
-// @Description Возвращает локации инцидентов, в которые попал пользователь
+// @Description Возвращает локации инцидентов, в которые попал пользователь, переходы ENTER/EXIT/DWELL относительно прошлой проверки, зоны, к которым пользователь приближается, и зоны, пересечённые между прошлой и текущей точкой. Если передан accuracy_m, попадание оценивается как certain/possible/outside по доле круга точности внутри зоны
 // @Tags location
 // @Accept json
 // @Produce json
@@ -31,6 +36,7 @@ func NewLocationHandler(service *usecase.LocationService, lg *logger.Logger) *Lo
 // @Success 200 {object} location.LocationResponse
 // @Failure 400 {object} httphelper.APIResponse
 // @Failure 500 {object} httphelper.APIResponse
+// @Failure 503 {object} httphelper.APIResponse
 // @Router /location/check [post]
 func (h *LocationHandler) CheckLocation(w http.ResponseWriter, r *http.Request) {
 	var req CheckLocationRequest
@@ -40,8 +46,19 @@ func (h *LocationHandler) CheckLocation(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	loc, err := h.Service.CheckLocation(r.Context(), req.UserID, req.Lat, req.Lng)
+	if err := ValidateCheckLocation(&req); err != nil {
+		h.lg.Error("LocationHandler.CheckLocation: validation failed", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	loc, err := h.Service.CheckLocation(r.Context(), req.UserID, req.Lat, req.Lng, FixFromDTO(req.FixDTO))
 	if err != nil {
+		if errors.Is(err, errs.ErrLocked) {
+			h.lg.Warn("LocationHandler.CheckLocation: user is locked by another check", "user_id", req.UserID)
+			httphelper.WriteError(w, err, http.StatusServiceUnavailable)
+			return
+		}
 		h.lg.Error("LocationHandler.CheckLocation: service returned error", "error", err, "user_id", req.UserID)
 		httphelper.WriteError(w, err, http.StatusInternalServerError)
 		return
@@ -50,3 +67,45 @@ func (h *LocationHandler) CheckLocation(w http.ResponseWriter, r *http.Request)
 	h.lg.Debug("LocationHandler.CheckLocation: location returned", "user_id", req.UserID, "location_id", loc.ID)
 	httphelper.WriteJSON(w, LocationToResponse(loc), http.StatusOK)
 }
+
+// CheckLocationBatch godoc
+// @Summary Check buffered user locations
+// @Description Проверяет пачку точек, накопленных клиентами офлайн (точки могут принадлежать разным пользователям). Каждая точка сверяется с инцидентами, активными в момент её timestamp; результаты возвращаются в порядке запроса
+// @Tags location
+// @Accept json
+// @Produce json
+// @Param request body location.CheckLocationBatchRequest true "Buffered locations"
+// @Success 200 {object} location.CheckLocationBatchResponse
+// @Failure 400 {object} httphelper.APIResponse
+// @Failure 500 {object} httphelper.APIResponse
+// @Failure 503 {object} httphelper.APIResponse
+// @Router /location/check/batch [post]
+func (h *LocationHandler) CheckLocationBatch(w http.ResponseWriter, r *http.Request) {
+	var req CheckLocationBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.lg.Error("LocationHandler.CheckLocationBatch: failed to decode request", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateCheckLocationBatch(&req, h.cfg.LocationBatchMax); err != nil {
+		h.lg.Error("LocationHandler.CheckLocationBatch: validation failed", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	locs, err := h.Service.CheckLocationBatch(r.Context(), PointsFromBatchRequest(&req))
+	if err != nil {
+		if errors.Is(err, errs.ErrLocked) {
+			h.lg.Warn("LocationHandler.CheckLocationBatch: users are locked by another check", "count", len(req.Points))
+			httphelper.WriteError(w, err, http.StatusServiceUnavailable)
+			return
+		}
+		h.lg.Error("LocationHandler.CheckLocationBatch: service returned error", "error", err, "count", len(req.Points))
+		httphelper.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.lg.Debug("LocationHandler.CheckLocationBatch: locations returned", "count", len(locs))
+	httphelper.WriteJSON(w, LocationsToBatchResponse(locs), http.StatusOK)
+}
@@ -0,0 +1,67 @@
+package location
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// допустимое расхождение часов клиента и сервера
+const maxClockSkew = time.Minute
+
+func ValidateCheckLocation(req *CheckLocationRequest) error {
+	if req.Lat < -90 || req.Lat > 90 {
+		return fmt.Errorf("latitude must be between -90 and 90, got %f", req.Lat)
+	}
+	if req.Lng < -180 || req.Lng > 180 {
+		return fmt.Errorf("longitude must be between -180 and 180, got %f", req.Lng)
+	}
+	return ValidateFix(req.FixDTO)
+}
+
+func ValidateFix(fix FixDTO) error {
+	if fix.AccuracyMeters != nil && *fix.AccuracyMeters < 0 {
+		return fmt.Errorf("accuracy_m must be non-negative, got %f", *fix.AccuracyMeters)
+	}
+	if fix.Speed != nil && *fix.Speed < 0 {
+		return fmt.Errorf("speed must be non-negative, got %f", *fix.Speed)
+	}
+	if fix.Heading != nil && (*fix.Heading < 0 || *fix.Heading >= 360) {
+		return fmt.Errorf("heading must be between 0 and 360, got %f", *fix.Heading)
+	}
+	return nil
+}
+
+func ValidateCheckLocationBatch(req *CheckLocationBatchRequest, maxPoints int) error {
+	if len(req.Points) == 0 {
+		return errors.New("points cannot be empty")
+	}
+	if len(req.Points) > maxPoints {
+		return fmt.Errorf("too many points: maximum %d, got %d", maxPoints, len(req.Points))
+	}
+
+	now := time.Now()
+	for i, p := range req.Points {
+		if p.UserID == uuid.Nil {
+			return fmt.Errorf("point %d: user_id is required", i)
+		}
+		if p.Lat < -90 || p.Lat > 90 {
+			return fmt.Errorf("point %d: latitude must be between -90 and 90, got %f", i, p.Lat)
+		}
+		if p.Lng < -180 || p.Lng > 180 {
+			return fmt.Errorf("point %d: longitude must be between -180 and 180, got %f", i, p.Lng)
+		}
+		if p.Timestamp.IsZero() {
+			return fmt.Errorf("point %d: timestamp is required", i)
+		}
+		if p.Timestamp.After(now.Add(maxClockSkew)) {
+			return fmt.Errorf("point %d: timestamp is in the future", i)
+		}
+		if err := ValidateFix(p.FixDTO); err != nil {
+			return fmt.Errorf("point %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -6,13 +6,19 @@ import (
 
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Logger пишет начало и конец запроса с trace_id спана, открытого Tracing;
+// без него trace_id генерируется только для логов
 func Logger(log *logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			traceID := uuid.NewString()
+			if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+				traceID = sc.TraceID().String()
+			}
 
 			log.Info("request started",
 				"trace_id", traceID,
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/pkg/metrics"
+)
+
+// unmatchedRoute метка запросов, не подошедших ни к одному маршруту: сырой путь дал бы неограниченное число серий
+const unmatchedRoute = "unmatched"
+
+// statusRecorder запоминает код ответа обработчика
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Metrics считает запросы и их длительность по шаблону маршрута ServeMux (r.Pattern), а не по пути,
+// чтобы id в пути не плодили серии
+func Metrics() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(rec, r)
+
+			// ServeMux заполняет Pattern у того же запроса, когда находит маршрут
+			route := r.Pattern
+			if route == "" {
+				route = unmatchedRoute
+			}
+			metrics.HTTPRequests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+			metrics.HTTPDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/location"
+)
+
+func StatsToResponse(st *location.Stats) StatsResponse {
+	incidents := make([]IncidentStatsResponse, len(st.Incidents))
+	for i, inc := range st.Incidents {
+		incidents[i] = IncidentStatsResponse{
+			IncidentID: inc.IncidentID,
+			Title:      inc.Title,
+			UserCount:  inc.UserCount,
+			HitCount:   inc.HitCount,
+		}
+	}
+
+	series := make([]StatsBucketResponse, len(st.Series))
+	for i, b := range st.Series {
+		series[i] = StatsBucketResponse{
+			Start:     b.Start.UTC(),
+			UserCount: b.UserCount,
+			HitCount:  b.HitCount,
+		}
+	}
+
+	return StatsResponse{
+		From:          st.From,
+		To:            st.To,
+		WindowSeconds: int64(st.To.Sub(st.From) / time.Second),
+		BucketSeconds: int64(st.Bucket / time.Second),
+		UserCount:     st.UserCount,
+		Exact:         st.Exact,
+		Incidents:     incidents,
+		Series:        series,
+	}
+}
@@ -1,5 +1,31 @@
 package stats
 
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
 type StatsResponse struct {
-	UserCount int `json:"user_count"`
+	From          time.Time               `json:"from"`
+	To            time.Time               `json:"to"`
+	WindowSeconds int64                   `json:"window_seconds"`
+	BucketSeconds int64                   `json:"bucket_seconds"`
+	UserCount     int                     `json:"user_count"` // уникальные пользователи, попавшие хотя бы в одну зону
+	Exact         bool                    `json:"exact"`      // false — числа пользователей оценены HyperLogLog (погрешность около 1%)
+	Incidents     []IncidentStatsResponse `json:"incidents"`
+	Series        []StatsBucketResponse   `json:"series"`
+}
+
+type IncidentStatsResponse struct {
+	IncidentID uuid.UUID `json:"incident_id"`
+	Title      string    `json:"title,omitempty"`
+	UserCount  int       `json:"user_count"`
+	HitCount   int       `json:"hit_count"`
+}
+
+type StatsBucketResponse struct {
+	Start     time.Time `json:"start"`
+	UserCount int       `json:"user_count"`
+	HitCount  int       `json:"hit_count"`
 }
@@ -1,7 +1,10 @@
 package stats
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/Soujuruya/01_SPEC/internal/config"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/httphelper"
@@ -25,21 +28,60 @@ func NewStatsHandler(service *usecase.StatsService, cfg *config.Config, lg *logg
 
 // GetIncidentsStats godoc
 // @Summary Get Incidents Stats
-// @Description Возвращает кол-во уникальных пользователей за последние N минут, попавших в инцидент
+// @Description Возвращает число уникальных пользователей, попавших хотя бы в одну зону инцидента за окно window, разбивку по инцидентам и ряд по интервалам bucket для графиков.
+// @Description Окна и интервалы, кратные минуте, считаются по счётчикам HyperLogLog в Redis (оценка); exact=true или более старые окна — точно по сохранённым проверкам
 // @Tags stats
 // @Produce json
+// @Param window query string false "Stats window as Go duration, e.g. 30m or 24h (default STATS_TIME_WINDOW_MINUTES)"
+// @Param bucket query string false "Time series bucket as Go duration, e.g. 1m (default window/12)"
+// @Param exact query bool false "Count exactly over stored locations instead of Redis HyperLogLog counters" default(false)
 // @Success 200 {object} stats.StatsResponse
+// @Failure 400 {object} httphelper.APIResponse "Invalid window or bucket"
 // @Failure 500 {object} httphelper.APIResponse
 // @Router /incidents/stats [get]
 func (h *StatsHandler) GetIncidentsStats(w http.ResponseWriter, r *http.Request) {
-	count, err := h.Service.GetUserCount(r.Context(), h.cfg.StatsTimeWindowMinutes)
+	window := time.Duration(h.cfg.StatsTimeWindowMinutes) * time.Minute
+	var err error
+
+	if v := r.URL.Query().Get("window"); v != "" {
+		if window, err = time.ParseDuration(v); err != nil {
+			h.lg.Error("StatsHandler.GetIncidentsStats: invalid window", "value", v, "error", err)
+			httphelper.WriteError(w, fmt.Errorf("invalid window format, expected duration like 30m or 24h"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	bucket := DefaultBucket(window)
+	if v := r.URL.Query().Get("bucket"); v != "" {
+		if bucket, err = time.ParseDuration(v); err != nil {
+			h.lg.Error("StatsHandler.GetIncidentsStats: invalid bucket", "value", v, "error", err)
+			httphelper.WriteError(w, fmt.Errorf("invalid bucket format, expected duration like 1m or 1h"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	exact := false
+	if v := r.URL.Query().Get("exact"); v != "" {
+		if exact, err = strconv.ParseBool(v); err != nil {
+			h.lg.Error("StatsHandler.GetIncidentsStats: invalid exact", "value", v, "error", err)
+			httphelper.WriteError(w, fmt.Errorf("invalid exact format, expected true or false"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := ValidateStatsParams(window, bucket, h.cfg.StatsMaxWindow); err != nil {
+		h.lg.Error("StatsHandler.GetIncidentsStats: invalid params", "window", window, "bucket", bucket, "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	st, err := h.Service.GetStats(r.Context(), window, bucket, exact)
 	if err != nil {
-		h.lg.Error("StatsHandler.GetIncidentsStats: failed to get user count", "error", err)
+		h.lg.Error("StatsHandler.GetIncidentsStats: failed to get stats", "error", err)
 		httphelper.WriteError(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	h.lg.Debug("StatsHandler.GetIncidentsStats: user count returned", "count", count, "minutes", h.cfg.StatsTimeWindowMinutes)
-	resp := StatsResponse{UserCount: count}
-	httphelper.WriteJSON(w, resp, http.StatusOK)
+	h.lg.Debug("StatsHandler.GetIncidentsStats: stats returned", "user_count", st.UserCount, "window", window, "bucket", bucket, "exact", st.Exact)
+	httphelper.WriteJSON(w, StatsToResponse(st), http.StatusOK)
 }
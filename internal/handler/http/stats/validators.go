@@ -0,0 +1,40 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxBuckets сколько интервалов может быть в ряду
+const maxBuckets = 1000
+
+// defaultBuckets на сколько интервалов делится окно, если bucket не задан
+const defaultBuckets = 12
+
+// DefaultBucket интервал по умолчанию: окно делится на defaultBuckets частей, кратных минуте,
+// чтобы статистику можно было взять из минутных счётчиков. Окна короче минуты делятся посекундно
+func DefaultBucket(window time.Duration) time.Duration {
+	if window >= time.Minute {
+		return max((window / defaultBuckets).Truncate(time.Minute), time.Minute)
+	}
+	return max((window / defaultBuckets).Truncate(time.Second), time.Second)
+}
+
+func ValidateStatsParams(window, bucket, maxWindow time.Duration) error {
+	if window < time.Second || window%time.Second != 0 {
+		return fmt.Errorf("window must be a whole number of seconds and at least 1s, got %v", window)
+	}
+	if window > maxWindow {
+		return fmt.Errorf("window must not exceed %v, got %v", maxWindow, window)
+	}
+	if bucket < time.Second || bucket%time.Second != 0 {
+		return fmt.Errorf("bucket must be a whole number of seconds and at least 1s, got %v", bucket)
+	}
+	if bucket > window {
+		return fmt.Errorf("bucket must not exceed window %v, got %v", window, bucket)
+	}
+	if n := (window + bucket - 1) / bucket; n > maxBuckets {
+		return fmt.Errorf("window %v split by bucket %v gives %d buckets, at most %d allowed", window, bucket, n, maxBuckets)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/subscription"
+)
+
+func SubscriptionToResponse(s *subscription.Subscription) SubscriptionResponse {
+	return SubscriptionResponse{
+		ID:            s.ID.String(),
+		URL:           s.URL,
+		EventTypes:    s.EventTypes,
+		IncidentIDs:   s.IncidentIDs,
+		Categories:    s.Categories,
+		MinSeverity:   s.MinSeverity,
+		Area:          AreaToDTO(s.Area),
+		IsActive:      s.IsActive,
+		PayloadFormat: s.PayloadFormat,
+		CreatedAt:     s.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     s.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func SubscriptionsToListResponse(subs []*subscription.Subscription) SubscriptionListResponse {
+	resp := make([]SubscriptionResponse, len(subs))
+	for i, s := range subs {
+		resp[i] = SubscriptionToResponse(s)
+	}
+	return SubscriptionListResponse{
+		Subscriptions: resp,
+		Total:         len(subs),
+	}
+}
+
+func SubscriptionFromCreateRequest(req *CreateSubscriptionRequest) *subscription.Subscription {
+	s := subscription.NewSubscription(req.URL, req.Secret)
+	if req.EventTypes != nil {
+		s.EventTypes = req.EventTypes
+	}
+	if req.IncidentIDs != nil {
+		s.IncidentIDs = req.IncidentIDs
+	}
+	if req.Categories != nil {
+		s.Categories = req.Categories
+	}
+	s.MinSeverity = req.MinSeverity
+	s.Area = AreaFromDTO(req.Area)
+	if req.PayloadFormat != "" {
+		s.PayloadFormat = req.PayloadFormat
+	}
+	return s
+}
+
+// ApplyUpdate переносит в подписку заданные поля запроса
+func ApplyUpdate(s *subscription.Subscription, req *UpdateSubscriptionRequest) {
+	if req.URL != nil {
+		s.URL = *req.URL
+	}
+	if req.Secret != nil {
+		s.Secret = *req.Secret
+	}
+	if req.EventTypes != nil {
+		s.EventTypes = *req.EventTypes
+	}
+	if req.IncidentIDs != nil {
+		s.IncidentIDs = *req.IncidentIDs
+	}
+	if req.Categories != nil {
+		s.Categories = *req.Categories
+	}
+	if req.MinSeverity != nil {
+		s.MinSeverity = *req.MinSeverity
+	}
+	if req.Area != nil {
+		s.Area = AreaFromDTO(req.Area)
+	}
+	if req.IsActive != nil {
+		s.IsActive = *req.IsActive
+	}
+	if req.PayloadFormat != nil {
+		s.PayloadFormat = *req.PayloadFormat
+	}
+}
+
+func AreaFromDTO(dto *AreaDTO) *subscription.Area {
+	if dto == nil {
+		return nil
+	}
+	return &subscription.Area{Lat: dto.Lat, Lng: dto.Lng, Radius: dto.Radius}
+}
+
+func AreaToDTO(a *subscription.Area) *AreaDTO {
+	if a == nil {
+		return nil
+	}
+	return &AreaDTO{Lat: a.Lat, Lng: a.Lng, Radius: a.Radius}
+}
+
+func SubscriptionToRotateResponse(s *subscription.Subscription) RotateSecretResponse {
+	resp := RotateSecretResponse{
+		ID:     s.ID.String(),
+		Secret: s.Secret,
+	}
+	if s.PreviousSecretExpiresAt != nil {
+		resp.PreviousSecretExpiresAt = s.PreviousSecretExpiresAt.Format(time.RFC3339)
+	}
+	return resp
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"github.com/google/uuid"
+)
+
+// AreaDTO круговая область, в которой должен находиться пользователь
+type AreaDTO struct {
+	Lat    float64 `json:"lat"`
+	Lng    float64 `json:"lng"`
+	Radius float64 `json:"radius"` // в метрах
+}
+
+type CreateSubscriptionRequest struct {
+	URL           string      `json:"url" example:"https://news.example.com/hooks/geo"`
+	Secret        string      `json:"secret,omitempty"`                                   // если не задан, генерируется
+	EventTypes    []string    `json:"event_types,omitempty" example:"geofence,proximity"` // incident приходит только если указан явно
+	IncidentIDs   []uuid.UUID `json:"incident_ids,omitempty"`
+	Categories    []string    `json:"categories,omitempty" example:"fire,flood"`
+	MinSeverity   string      `json:"min_severity,omitempty" enums:"info,warning,critical"`
+	Area          *AreaDTO    `json:"area,omitempty"`
+	PayloadFormat string      `json:"payload_format,omitempty" enums:"json,cloudevents,cloudevents-binary"` // по умолчанию json
+}
+
+type UpdateSubscriptionRequest struct {
+	URL           *string      `json:"url,omitempty"`
+	Secret        *string      `json:"secret,omitempty"`
+	EventTypes    *[]string    `json:"event_types,omitempty"`
+	IncidentIDs   *[]uuid.UUID `json:"incident_ids,omitempty"`
+	Categories    *[]string    `json:"categories,omitempty"`
+	MinSeverity   *string      `json:"min_severity,omitempty" enums:"info,warning,critical"`
+	Area          *AreaDTO     `json:"area,omitempty"`
+	IsActive      *bool        `json:"is_active,omitempty"`
+	PayloadFormat *string      `json:"payload_format,omitempty" enums:"json,cloudevents,cloudevents-binary"`
+}
+
+type SubscriptionResponse struct {
+	ID            string      `json:"id"`
+	URL           string      `json:"url"`
+	Secret        string      `json:"secret,omitempty"` // возвращается только при создании
+	EventTypes    []string    `json:"event_types"`
+	IncidentIDs   []uuid.UUID `json:"incident_ids"`
+	Categories    []string    `json:"categories"`
+	MinSeverity   string      `json:"min_severity,omitempty"`
+	Area          *AreaDTO    `json:"area,omitempty"`
+	IsActive      bool        `json:"is_active"`
+	PayloadFormat string      `json:"payload_format"`
+	CreatedAt     string      `json:"created_at"`
+	UpdatedAt     string      `json:"updated_at"`
+}
+
+type SubscriptionListResponse struct {
+	Subscriptions []SubscriptionResponse `json:"subscriptions"`
+	Total         int                    `json:"total"`
+}
+
+type RotateSecretRequest struct {
+	Secret string `json:"secret,omitempty"` // если не задан, генерируется
+}
+
+type RotateSecretResponse struct {
+	ID                      string `json:"id"`
+	Secret                  string `json:"secret"`
+	PreviousSecretExpiresAt string `json:"previous_secret_expires_at,omitempty"` // до этого времени доставки подписываются и прежним секретом
+}
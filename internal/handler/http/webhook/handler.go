@@ -0,0 +1,276 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/httphelper"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/Soujuruya/01_SPEC/internal/usecase"
+	"github.com/google/uuid"
+)
+
+// RotateSecretPath суффикс пути ротации секрета подписки
+const RotateSecretPath = "/rotate-secret"
+
+type WebhookHandler struct {
+	Service *usecase.SubscriptionService
+	lg      *logger.Logger
+}
+
+func NewWebhookHandler(service *usecase.SubscriptionService, lg *logger.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		Service: service,
+		lg:      lg,
+	}
+}
+
+// CreateSubscription godoc
+// @Summary Create Webhook Subscription
+// @Description Регистрирует получателя вебхуков. Пустые event_types, incident_ids и categories означают «все»; секрет генерируется, если не задан, и возвращается только в ответе на создание
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param request body webhook.CreateSubscriptionRequest true "Subscription data"
+// @Success 201 {object} webhook.SubscriptionResponse
+// @Failure 400 {object} httphelper.APIResponse
+// @Failure 409 {object} httphelper.APIResponse "Subscription already exists"
+// @Failure 500 {object} httphelper.APIResponse
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var req CreateSubscriptionRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.lg.Error("CreateSubscription: failed to decode request", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateCreateSubscription(&req); err != nil {
+		h.lg.Error("CreateSubscription: validation failed", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	sub := SubscriptionFromCreateRequest(&req)
+	if err := h.Service.CreateSubscription(r.Context(), sub); err != nil {
+		if errors.Is(err, errs.ErrDuplicate) {
+			h.lg.Warn("CreateSubscription: subscription already exists", "url", sub.URL)
+			httphelper.WriteError(w, err, http.StatusConflict)
+			return
+		}
+		h.lg.Error("CreateSubscription: failed to create subscription", "error", err)
+		httphelper.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.lg.Info("CreateSubscription: subscription created", "subscription_id", sub.ID, "url", sub.URL)
+	resp := SubscriptionToResponse(sub)
+	resp.Secret = sub.Secret
+	httphelper.WriteJSON(w, resp, http.StatusCreated)
+}
+
+// GetSubscription godoc
+// @Summary Get Webhook Subscription by ID
+// @Description Получает подписку по UUID
+// @Tags webhook
+// @Produce json
+// @Param id path string true "Subscription UUID"
+// @Success 200 {object} webhook.SubscriptionResponse
+// @Failure 400 {object} httphelper.APIResponse "Invalid UUID"
+// @Failure 404 {object} httphelper.APIResponse "Subscription not found"
+// @Failure 500 {object} httphelper.APIResponse "Internal server error"
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := httphelper.ParseUUIDFromPath(r, "/api/v1/webhooks/")
+	if err != nil {
+		h.lg.Error("GetSubscription: invalid UUID in path", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.Service.GetSubscription(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.lg.Warn("GetSubscription: subscription not found", "subscription_id", id)
+			httphelper.WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		h.lg.Error("GetSubscription: failed to fetch subscription", "subscription_id", id, "error", err)
+		httphelper.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.lg.Debug("GetSubscription: success", "subscription_id", id)
+	httphelper.WriteJSON(w, SubscriptionToResponse(sub), http.StatusOK)
+}
+
+// ListSubscriptions godoc
+// @Summary Get All Webhook Subscriptions
+// @Description Получает все подписки на вебхуки, включая отключённые
+// @Tags webhook
+// @Produce json
+// @Success 200 {object} webhook.SubscriptionListResponse
+// @Failure 500 {object} httphelper.APIResponse "Internal server error"
+// @Router /webhooks [get]
+func (h *WebhookHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.Service.ListSubscriptions(r.Context())
+	if err != nil {
+		h.lg.Error("ListSubscriptions: failed to list subscriptions", "error", err)
+		httphelper.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.lg.Debug("ListSubscriptions: success", "count", len(subs))
+	httphelper.WriteJSON(w, SubscriptionsToListResponse(subs), http.StatusOK)
+}
+
+// UpdateSubscription godoc
+// @Summary Update Webhook Subscription by ID
+// @Description Обновляет подписку (частичное обновление)
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription UUID"
+// @Param request body webhook.UpdateSubscriptionRequest true "Subscription update data"
+// @Success 200 {object} webhook.SubscriptionResponse
+// @Failure 400 {object} httphelper.APIResponse "Invalid UUID or request body"
+// @Failure 404 {object} httphelper.APIResponse "Subscription not found"
+// @Failure 500 {object} httphelper.APIResponse "Internal server error"
+// @Router /webhooks/{id} [patch]
+func (h *WebhookHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := httphelper.ParseUUIDFromPath(r, "/api/v1/webhooks/")
+	if err != nil {
+		h.lg.Error("UpdateSubscription: invalid UUID in path", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.lg.Error("UpdateSubscription: failed to decode request body", "error", err)
+		httphelper.WriteError(w, fmt.Errorf("invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateUpdateSubscription(&req); err != nil {
+		h.lg.Error("UpdateSubscription: validation failed", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	existing, err := h.Service.GetSubscription(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.lg.Warn("UpdateSubscription: subscription not found", "subscription_id", id)
+			httphelper.WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		h.lg.Error("UpdateSubscription: failed to get existing subscription", "subscription_id", id, "error", err)
+		httphelper.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	ApplyUpdate(existing, &req)
+	existing.UpdatedAt = time.Now()
+
+	if err := h.Service.UpdateSubscription(r.Context(), existing); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.lg.Warn("UpdateSubscription: subscription not found", "subscription_id", id)
+			httphelper.WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		h.lg.Error("UpdateSubscription: failed to update subscription", "subscription_id", id, "error", err)
+		httphelper.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.lg.Info("UpdateSubscription: success", "subscription_id", id)
+	httphelper.WriteJSON(w, SubscriptionToResponse(existing), http.StatusOK)
+}
+
+// DeleteSubscription godoc
+// @Summary Delete Webhook Subscription by ID
+// @Description Удаляет подписку; события, уже поставленные в очередь, ей не доставляются
+// @Tags webhook
+// @Param id path string true "Subscription UUID"
+// @Success 204 "No Content"
+// @Failure 400 {object} httphelper.APIResponse "Invalid UUID"
+// @Failure 404 {object} httphelper.APIResponse "Subscription not found"
+// @Failure 500 {object} httphelper.APIResponse "Internal server error"
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, err := httphelper.ParseUUIDFromPath(r, "/api/v1/webhooks/")
+	if err != nil {
+		h.lg.Error("DeleteSubscription: invalid UUID in path", "error", err)
+		httphelper.WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteSubscription(r.Context(), id); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.lg.Warn("DeleteSubscription: subscription not found", "subscription_id", id)
+			httphelper.WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		h.lg.Error("DeleteSubscription: failed to delete", "subscription_id", id, "error", err)
+		httphelper.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.lg.Info("DeleteSubscription: success", "subscription_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RotateSecret godoc
+// @Summary Rotate Webhook Subscription Secret
+// @Description Выдаёт подписке новый секрет (генерируется, если не передан). Прежний секрет остаётся действующим WEBHOOK_SECRET_GRACE_PERIOD: до этого времени X-Signature содержит подписи обоими секретами
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription UUID"
+// @Param request body webhook.RotateSecretRequest false "New secret"
+// @Success 200 {object} webhook.RotateSecretResponse
+// @Failure 400 {object} httphelper.APIResponse "Invalid UUID or request body"
+// @Failure 404 {object} httphelper.APIResponse "Subscription not found"
+// @Failure 500 {object} httphelper.APIResponse "Internal server error"
+// @Router /webhooks/{id}/rotate-secret [post]
+func (h *WebhookHandler) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/webhooks/"), RotateSecretPath)
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.lg.Error("RotateSecret: invalid UUID in path", "error", err)
+		httphelper.WriteError(w, fmt.Errorf("invalid id format"), http.StatusBadRequest)
+		return
+	}
+
+	var req RotateSecretRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			h.lg.Error("RotateSecret: failed to decode request body", "error", err)
+			httphelper.WriteError(w, fmt.Errorf("invalid request body"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	sub, err := h.Service.RotateSecret(r.Context(), id, req.Secret)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			h.lg.Warn("RotateSecret: subscription not found", "subscription_id", id)
+			httphelper.WriteError(w, err, http.StatusNotFound)
+			return
+		}
+		h.lg.Error("RotateSecret: failed to rotate secret", "subscription_id", id, "error", err)
+		httphelper.WriteError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.lg.Info("RotateSecret: success", "subscription_id", id)
+	httphelper.WriteJSON(w, SubscriptionToRotateResponse(sub), http.StatusOK)
+}
@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
+	"github.com/Soujuruya/01_SPEC/internal/domain/subscription"
+	"github.com/google/uuid"
+)
+
+func ValidateCreateSubscription(req *CreateSubscriptionRequest) error {
+	if err := validateURL(req.URL); err != nil {
+		return err
+	}
+	if req.PayloadFormat != "" {
+		if err := validatePayloadFormat(req.PayloadFormat); err != nil {
+			return err
+		}
+	}
+	return validateFilters(req.EventTypes, req.IncidentIDs, req.Categories, req.MinSeverity, req.Area)
+}
+
+func ValidateUpdateSubscription(req *UpdateSubscriptionRequest) error {
+	if req.URL != nil {
+		if err := validateURL(*req.URL); err != nil {
+			return err
+		}
+	}
+	if req.Secret != nil && *req.Secret == "" {
+		return errors.New("secret cannot be empty")
+	}
+	if req.PayloadFormat != nil {
+		if err := validatePayloadFormat(*req.PayloadFormat); err != nil {
+			return err
+		}
+	}
+
+	var (
+		eventTypes  []string
+		incidentIDs []uuid.UUID
+		categories  []string
+		minSeverity string
+	)
+	if req.EventTypes != nil {
+		eventTypes = *req.EventTypes
+	}
+	if req.IncidentIDs != nil {
+		incidentIDs = *req.IncidentIDs
+	}
+	if req.Categories != nil {
+		categories = *req.Categories
+	}
+	if req.MinSeverity != nil {
+		minSeverity = *req.MinSeverity
+	}
+	return validateFilters(eventTypes, incidentIDs, categories, minSeverity, req.Area)
+}
+
+func validateURL(raw string) error {
+	if raw == "" {
+		return errors.New("url cannot be empty")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) url, got %q", raw)
+	}
+	return nil
+}
+
+func validatePayloadFormat(v string) error {
+	if !subscription.IsPayloadFormat(v) {
+		return fmt.Errorf("unknown payload_format %q, expected json, cloudevents or cloudevents-binary", v)
+	}
+	return nil
+}
+
+func validateFilters(eventTypes []string, incidentIDs []uuid.UUID, categories []string, minSeverity string, area *AreaDTO) error {
+	for _, t := range eventTypes {
+		if !subscription.IsEventType(t) {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+	}
+	for _, id := range incidentIDs {
+		if id == uuid.Nil {
+			return errors.New("incident_ids cannot contain empty id")
+		}
+	}
+	for _, c := range categories {
+		if !incident.IsCategory(c) {
+			return fmt.Errorf("unknown category %q", c)
+		}
+	}
+	if minSeverity != "" && !incident.IsSeverity(minSeverity) {
+		return fmt.Errorf("unknown min_severity %q, expected info, warning or critical", minSeverity)
+	}
+	if area != nil {
+		if area.Lat < -90 || area.Lat > 90 {
+			return fmt.Errorf("area latitude must be between -90 and 90, got %f", area.Lat)
+		}
+		if area.Lng < -180 || area.Lng > 180 {
+			return fmt.Errorf("area longitude must be between -180 and 180, got %f", area.Lng)
+		}
+		if area.Radius <= 0 {
+			return fmt.Errorf("area radius must be positive, got %f", area.Radius)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package integration
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Состояния автомата
+const (
+	CircuitClosed   = "closed"    // отправки идут как обычно
+	CircuitOpen     = "open"      // получатель считается недоступным, отправки не выполняются
+	CircuitHalfOpen = "half_open" // после паузы пропускается одна пробная отправка
+)
+
+// CircuitOpenError отправка не выполнялась: автомат получателя разомкнут
+type CircuitOpenError struct {
+	Host    string
+	RetryAt time.Time // когда автомат пропустит пробную отправку
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %s is open until %s", e.Host, e.RetryAt.Format(time.RFC3339))
+}
+
+// BreakerOptions пороги автомата
+type BreakerOptions struct {
+	FailureRate float64       // доля неудач в окне, при которой автомат размыкается
+	MinRequests int           // размер окна последних отправок; до его заполнения автомат не размыкается
+	CoolDown    time.Duration // сколько автомат остаётся разомкнутым перед пробной отправкой
+}
+
+// CircuitState состояние автомата одного получателя. Только хост: в пути и query адреса
+// подписки бывают токены получателя
+type CircuitState struct {
+	Host     string     `json:"host"`
+	State    string     `json:"state"`
+	Requests int        `json:"requests"`            // отправок в окне
+	Failures int        `json:"failures"`            // неудач в окне
+	OpenedAt *time.Time `json:"opened_at,omitempty"` // когда автомат разомкнулся
+	RetryAt  *time.Time `json:"retry_at,omitempty"`  // когда будет пробная отправка
+}
+
+type circuit struct {
+	state    string
+	results  []bool // кольцевой буфер последних исходов, true — неудача
+	next     int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// CircuitBreaker автоматы по хостам получателей вебхуков: подписки на один хост
+// разделяют его доступность
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	opts     BreakerOptions
+	circuits map[string]*circuit
+}
+
+func NewCircuitBreaker(opts BreakerOptions) *CircuitBreaker {
+	return &CircuitBreaker{
+		opts:     opts,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+func (b *CircuitBreaker) get(host string) *circuit {
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{state: CircuitClosed, results: make([]bool, 0, b.opts.MinRequests)}
+		b.circuits[host] = c
+	}
+	return c
+}
+
+// Allow разрешает отправку на host. В разомкнутом состоянии возвращает *CircuitOpenError;
+// после паузы пропускает одну пробную отправку, остальные ждут её исхода
+func (b *CircuitBreaker) Allow(host string, now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.get(host)
+	switch c.state {
+	case CircuitClosed:
+		return nil
+	case CircuitOpen:
+		retryAt := c.openedAt.Add(b.opts.CoolDown)
+		if now.Before(retryAt) {
+			return &CircuitOpenError{Host: host, RetryAt: retryAt}
+		}
+		c.state = CircuitHalfOpen
+	}
+
+	if c.probing {
+		return &CircuitOpenError{Host: host, RetryAt: now.Add(b.opts.CoolDown)}
+	}
+	c.probing = true
+	return nil
+}
+
+// Record учитывает исход разрешённой отправки
+func (b *CircuitBreaker) Record(host string, failed bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.get(host)
+	if c.state == CircuitHalfOpen {
+		c.probing = false
+		if failed {
+			c.open(now)
+			return
+		}
+		c.reset(CircuitClosed)
+		return
+	}
+	if c.state != CircuitClosed {
+		return
+	}
+
+	if len(c.results) < b.opts.MinRequests {
+		c.results = append(c.results, failed)
+	} else {
+		if c.results[c.next] {
+			c.failures--
+		}
+		c.results[c.next] = failed
+		c.next = (c.next + 1) % b.opts.MinRequests
+	}
+	if failed {
+		c.failures++
+	}
+
+	if len(c.results) == b.opts.MinRequests && float64(c.failures)/float64(len(c.results)) >= b.opts.FailureRate {
+		c.open(now)
+	}
+}
+
+// Cancel снимает пробную отправку, прерванную не по вине получателя
+func (b *CircuitBreaker) Cancel(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, ok := b.circuits[host]; ok {
+		c.probing = false
+	}
+}
+
+// States состояния всех известных автоматов, упорядоченные по хосту
+func (b *CircuitBreaker) States() []CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	states := make([]CircuitState, 0, len(b.circuits))
+	for host, c := range b.circuits {
+		s := CircuitState{Host: host, State: c.state, Requests: len(c.results), Failures: c.failures}
+		if c.state != CircuitClosed {
+			openedAt, retryAt := c.openedAt, c.openedAt.Add(b.opts.CoolDown)
+			s.OpenedAt, s.RetryAt = &openedAt, &retryAt
+		}
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Host < states[j].Host })
+	return states
+}
+
+func (c *circuit) open(now time.Time) {
+	c.reset(CircuitOpen)
+	c.openedAt = now
+}
+
+func (c *circuit) reset(state string) {
+	c.state = state
+	c.results = c.results[:0]
+	c.next = 0
+	c.failures = 0
+}
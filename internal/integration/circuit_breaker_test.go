@@ -0,0 +1,194 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const testHost = "hooks.example.com"
+
+// clock часы теста: время автомату передаётся явно
+type clock struct{ now time.Time }
+
+func (c *clock) advance(d time.Duration) time.Time {
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+func newTestBreaker() (*CircuitBreaker, *clock) {
+	b := NewCircuitBreaker(BreakerOptions{FailureRate: 0.5, MinRequests: 4, CoolDown: time.Minute})
+	return b, &clock{now: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func record(t *testing.T, b *CircuitBreaker, c *clock, failures ...bool) {
+	t.Helper()
+	for _, failed := range failures {
+		if err := b.Allow(testHost, c.now); err != nil {
+			t.Fatalf("Allow before opening: %v", err)
+		}
+		b.Record(testHost, failed, c.advance(time.Second))
+	}
+}
+
+func state(t *testing.T, b *CircuitBreaker) CircuitState {
+	t.Helper()
+	states := b.States()
+	if len(states) != 1 || states[0].Host != testHost {
+		t.Fatalf("States() = %+v, want one circuit for %s", states, testHost)
+	}
+	return states[0]
+}
+
+func openError(t *testing.T, err error) *CircuitOpenError {
+	t.Helper()
+	var open *CircuitOpenError
+	if !errors.As(err, &open) {
+		t.Fatalf("Allow() = %v, want *CircuitOpenError", err)
+	}
+	return open
+}
+
+func TestCircuitBreakerOpensAtFailureRate(t *testing.T) {
+	b, c := newTestBreaker()
+
+	// до заполнения окна автомат не размыкается даже при сплошных неудачах
+	record(t, b, c, true, true, true)
+	if s := state(t, b); s.State != CircuitClosed || s.Requests != 3 || s.Failures != 3 {
+		t.Fatalf("before MinRequests: %+v, want closed with 3/3 failures", s)
+	}
+
+	record(t, b, c, true)
+	s := state(t, b)
+	if s.State != CircuitOpen || s.OpenedAt == nil || !s.OpenedAt.Equal(c.now) || !s.RetryAt.Equal(c.now.Add(time.Minute)) {
+		t.Fatalf("after MinRequests: %+v, want open at %v", s, c.now)
+	}
+	if open := openError(t, b.Allow(testHost, c.now)); open.Host != testHost || !open.RetryAt.Equal(c.now.Add(time.Minute)) {
+		t.Fatalf("CircuitOpenError = %+v", open)
+	}
+}
+
+func TestCircuitBreakerWindowSlides(t *testing.T) {
+	b, c := newTestBreaker()
+
+	// 1 неудача из 4 — ниже порога
+	record(t, b, c, true, false, false, false)
+	if s := state(t, b); s.State != CircuitClosed || s.Failures != 1 {
+		t.Fatalf("1/4 failures: %+v, want closed", s)
+	}
+
+	// новая удача вытесняет старую неудачу из кольцевого буфера
+	record(t, b, c, false)
+	if s := state(t, b); s.State != CircuitClosed || s.Requests != 4 || s.Failures != 0 {
+		t.Fatalf("after sliding: %+v, want closed with 0/4 failures", s)
+	}
+
+	// две неудачи подряд дают 2/4 — ровно порог
+	record(t, b, c, true)
+	if s := state(t, b); s.State != CircuitClosed {
+		t.Fatalf("1/4 failures: %+v, want closed", s)
+	}
+	record(t, b, c, true)
+	if s := state(t, b); s.State != CircuitOpen {
+		t.Fatalf("2/4 failures: %+v, want open", s)
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	b, c := newTestBreaker()
+	record(t, b, c, true, true, true, true)
+
+	c.advance(59 * time.Second)
+	openError(t, b.Allow(testHost, c.now))
+
+	c.advance(time.Second)
+	if err := b.Allow(testHost, c.now); err != nil {
+		t.Fatalf("probe after CoolDown: %v", err)
+	}
+	if s := state(t, b); s.State != CircuitHalfOpen {
+		t.Fatalf("during probe: %+v, want half_open", s)
+	}
+	// пока пробная отправка не завершилась, остальные ждут
+	for range 3 {
+		openError(t, b.Allow(testHost, c.now))
+	}
+}
+
+func TestCircuitBreakerProbeOutcome(t *testing.T) {
+	t.Run("success closes", func(t *testing.T) {
+		b, c := newTestBreaker()
+		record(t, b, c, true, true, true, true)
+
+		if err := b.Allow(testHost, c.advance(time.Minute)); err != nil {
+			t.Fatalf("probe: %v", err)
+		}
+		b.Record(testHost, false, c.advance(time.Second))
+
+		if s := state(t, b); s.State != CircuitClosed || s.Requests != 0 || s.OpenedAt != nil {
+			t.Fatalf("after successful probe: %+v, want closed with empty window", s)
+		}
+		if err := b.Allow(testHost, c.now); err != nil {
+			t.Fatalf("Allow after closing: %v", err)
+		}
+	})
+
+	t.Run("failure reopens", func(t *testing.T) {
+		b, c := newTestBreaker()
+		record(t, b, c, true, true, true, true)
+
+		if err := b.Allow(testHost, c.advance(time.Minute)); err != nil {
+			t.Fatalf("probe: %v", err)
+		}
+		b.Record(testHost, true, c.advance(time.Second))
+
+		s := state(t, b)
+		if s.State != CircuitOpen || !s.OpenedAt.Equal(c.now) {
+			t.Fatalf("after failed probe: %+v, want open since %v", s, c.now)
+		}
+		// новая пауза отсчитывается от неудачной пробы
+		openError(t, b.Allow(testHost, c.advance(59*time.Second)))
+		if err := b.Allow(testHost, c.advance(time.Second)); err != nil {
+			t.Fatalf("second probe: %v", err)
+		}
+	})
+}
+
+func TestCircuitBreakerCancelReleasesProbe(t *testing.T) {
+	b, c := newTestBreaker()
+	record(t, b, c, true, true, true, true)
+
+	if err := b.Allow(testHost, c.advance(time.Minute)); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	// пробу прервали на нашей стороне: исход не учитывается, следующая отправка снова пробная
+	b.Cancel(testHost)
+	if s := state(t, b); s.State != CircuitHalfOpen {
+		t.Fatalf("after Cancel: %+v, want half_open", s)
+	}
+	if err := b.Allow(testHost, c.now); err != nil {
+		t.Fatalf("probe after Cancel: %v", err)
+	}
+	openError(t, b.Allow(testHost, c.now))
+
+	// Cancel неизвестного хоста ничего не создаёт
+	b.Cancel("other.example.com")
+	if len(b.States()) != 1 {
+		t.Fatalf("States() = %+v, want one circuit", b.States())
+	}
+}
+
+func TestCircuitBreakerHostsAreIndependent(t *testing.T) {
+	b, c := newTestBreaker()
+	record(t, b, c, true, true, true, true)
+
+	if err := b.Allow("other.example.com", c.now); err != nil {
+		t.Fatalf("other host: %v", err)
+	}
+	states := b.States()
+	if len(states) != 2 || states[0].Host != testHost || states[1].Host != "other.example.com" {
+		t.Fatalf("States() = %+v, want both hosts ordered", states)
+	}
+	if states[1].State != CircuitClosed {
+		t.Fatalf("other host state = %s, want closed", states[1].State)
+	}
+}
@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
+	"github.com/Soujuruya/01_SPEC/internal/domain/location"
+	"github.com/google/uuid"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	ContentTypeCloudEvents = "application/cloudevents+json"
+
+	// CloudEventTypePrefix общий префикс атрибута type
+	CloudEventTypePrefix = "io.01spec."
+)
+
+// CloudEvent событие в формате CloudEvents 1.0 (structured mode)
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time,omitempty"`
+	Subject         string          `json:"subject,omitempty"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// CloudEventType атрибут type для события:
+//
+//	io.01spec.location.geofence   — попадание в зону инцидента: ENTER/EXIT/DWELL и проезд через зону
+//	io.01spec.location.proximity  — пользователь приближается к зоне
+//	io.01spec.incident.<action>   — изменение инцидента: created, updated, activated, deactivated
+func CloudEventType(p WebhookPayload) string {
+	switch p.EventType {
+	case location.EventGeofence, location.EventProximity:
+		return CloudEventTypePrefix + "location." + p.EventType
+	case incident.EventLifecycle:
+		return CloudEventTypePrefix + "incident." + p.Action
+	}
+	return CloudEventTypePrefix + p.EventType
+}
+
+// NewCloudEvent оборачивает тело WebhookPayload (data) в CloudEvent. subject — пользователь
+// для событий по локации и инцидент для событий об изменении инцидента
+func NewCloudEvent(source string, p WebhookPayload, data []byte) CloudEvent {
+	e := CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              p.EventID.String(),
+		Source:          source,
+		Type:            CloudEventType(p),
+		Time:            p.OccurredAt,
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	if e.Time == "" && p.Timestamp != 0 {
+		e.Time = time.Unix(p.Timestamp, 0).UTC().Format(time.RFC3339)
+	}
+	switch {
+	case p.EventType == incident.EventLifecycle && len(p.IncidentIDs) > 0:
+		e.Subject = p.IncidentIDs[0].String()
+	case p.UserID != uuid.Nil:
+		e.Subject = p.UserID.String()
+	}
+	return e
+}
+
+// SetBinaryHeaders переносит атрибуты в заголовки ce-* (binary mode); тело запроса — data
+func (e CloudEvent) SetBinaryHeaders(h http.Header) {
+	h.Set("Content-Type", e.DataContentType)
+	h.Set("ce-specversion", e.SpecVersion)
+	h.Set("ce-id", e.ID)
+	h.Set("ce-source", e.Source)
+	h.Set("ce-type", e.Type)
+	if e.Time != "" {
+		h.Set("ce-time", e.Time)
+	}
+	if e.Subject != "" {
+		h.Set("ce-subject", e.Subject)
+	}
+}
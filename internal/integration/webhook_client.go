@@ -4,55 +4,241 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	neturl "net/url"
+	"strconv"
 	"time"
 
+	"github.com/Soujuruya/01_SPEC/internal/domain/subscription"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/tracing"
+	"github.com/Soujuruya/01_SPEC/pkg/webhooksig"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// HeaderIdempotencyKey заголовок с ID события, по которому получатель отбрасывает повторы
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// maxErrorBody сколько байт ответа получателя сохраняется в ошибке доставки
+const maxErrorBody = 512
+
+// DeliveryError получатель ответил статусом вне 2xx
+type DeliveryError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // из заголовка Retry-After; 0 — получатель срок не указал
+}
+
+func (e *DeliveryError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("webhook receiver responded with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("webhook receiver responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+// SecretProvider секреты, которыми подписывается доставка события
+type SecretProvider interface {
+	Secrets(ctx context.Context, payload WebhookPayload) ([]string, error)
+}
+
 type WebhookClient struct {
-	url    string
-	client *http.Client
-	lg     *logger.Logger
+	url     string
+	client  *http.Client
+	secrets SecretProvider
+	breaker *CircuitBreaker
+	source  string
+	lg      *logger.Logger
 }
 
-func NewWebhookClient(url string, timeout time.Duration, lg *logger.Logger) *WebhookClient {
+// NewWebhookClient breaker может быть nil — тогда отправки не ограничиваются автоматом;
+// source — атрибут source событий в формате CloudEvents
+func NewWebhookClient(url string, timeout time.Duration, secrets SecretProvider, breaker *CircuitBreaker, source string, lg *logger.Logger) *WebhookClient {
 	return &WebhookClient{
 		url: url,
 		client: &http.Client{
 			Timeout: timeout,
 		},
-		lg: lg,
+		secrets: secrets,
+		breaker: breaker,
+		source:  source,
+		lg:      lg,
 	}
 }
 
-func (c *WebhookClient) Send(ctx context.Context, payload WebhookPayload, lg *logger.Logger) error {
-	body, err := json.Marshal(payload)
+// Send доставляет событие на payload.Target, а если он не задан — на адрес по умолчанию.
+// Тело в формате payload.Format подписывается HMAC-SHA256 секретами получателя (см. pkg/webhooksig).
+// Пока автомат получателя разомкнут, запрос не выполняется и возвращается *CircuitOpenError.
+// Отправка — клиентский спан в трассе ctx, получатель продолжает её по заголовку traceparent
+func (c *WebhookClient) Send(ctx context.Context, payload WebhookPayload, lg *logger.Logger) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "webhook send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("webhook.event_id", payload.EventID.String()),
+			attribute.String("webhook.event_type", payload.EventType),
+			attribute.Int("webhook.retry", payload.Retry),
+		),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	if payload.SubscriptionID != nil {
+		span.SetAttributes(attribute.String("webhook.subscription_id", payload.SubscriptionID.String()))
+	}
+
+	var secrets []string
+	if c.secrets != nil {
+		var err error
+		if secrets, err = c.secrets.Secrets(ctx, payload); err != nil {
+			lg.Error("WebhookClient: failed to resolve signing secrets", "error", err, "subscription_id", payload.SubscriptionID)
+			return err
+		}
+	}
+
+	url := c.url
+	if payload.Target != "" {
+		url, payload.Target = payload.Target, ""
+	}
+	host := hostOf(url)
+	span.SetAttributes(attribute.String("server.address", host))
+	format := payload.Format
+	payload.Attempts, payload.Format, payload.TraceContext = nil, "", nil
+
+	body, header, err := c.encode(payload, format)
 	if err != nil {
 		lg.Error("WebhookClient: failed to marshal payload", "error", err, "payload", payload)
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if c.breaker == nil {
+		return c.post(ctx, url, body, header, secrets, payload, lg)
+	}
+	if err := c.breaker.Allow(host, time.Now()); err != nil {
+		return err
+	}
+	err = c.post(ctx, url, body, header, secrets, payload, lg)
+	if ctx.Err() != nil {
+		// отправку прервали на нашей стороне, получатель тут ни при чём
+		c.breaker.Cancel(host)
+		return err
+	}
+	c.breaker.Record(host, receiverFailed(err), time.Now())
+	return err
+}
+
+// encode тело и заголовки запроса в формате подписки
+func (c *WebhookClient) encode(payload WebhookPayload, format string) ([]byte, http.Header, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	switch format {
+	case subscription.FormatCloudEvents:
+		body, err := json.Marshal(NewCloudEvent(c.source, payload, data))
+		if err != nil {
+			return nil, nil, err
+		}
+		header.Set("Content-Type", ContentTypeCloudEvents)
+		return body, header, nil
+	case subscription.FormatCloudEventsBinary:
+		NewCloudEvent(c.source, payload, nil).SetBinaryHeaders(header)
+		return data, header, nil
+	default:
+		header.Set("Content-Type", "application/json")
+		return data, header, nil
+	}
+}
+
+func (c *WebhookClient) post(ctx context.Context, url string, body []byte, header http.Header, secrets []string, payload WebhookPayload, lg *logger.Logger) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		lg.Error("WebhookClient: failed to create request", "error", err)
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	if payload.EventID != uuid.Nil {
+		req.Header.Set(HeaderIdempotencyKey, payload.EventID.String())
+	}
+	webhooksig.SignRequest(req, body, time.Now(), secrets...)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		lg.Error("WebhookClient: request failed", "error", err, "url", c.url, "payload", payload)
+		lg.Error("WebhookClient: request failed", "error", err, "url", url, "payload", payload)
 		return err
 	}
 	defer resp.Body.Close()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
 	if resp.StatusCode >= 300 {
-		lg.Warn("WebhookClient: non-2xx response", "status", resp.StatusCode, "url", c.url, "payload", payload)
-		return http.ErrHandlerTimeout
+		lg.Warn("WebhookClient: non-2xx response", "status", resp.StatusCode, "url", url, "payload", payload)
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &DeliveryError{
+			StatusCode: resp.StatusCode,
+			Body:       string(snippet),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
-	lg.Info("WebhookClient: webhook sent successfully", "url", c.url, "payload", payload)
+	lg.Info("WebhookClient: webhook sent successfully", "url", url, "payload", payload)
 	return nil
 }
+
+// hostOf хост адреса доставки для спана: в пути и query адресов вебхуков бывают токены
+func hostOf(raw string) string {
+	u, err := neturl.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// receiverFailed ошибка говорит о недоступности получателя: сеть, таймаут, 5xx или 429.
+// Остальные 4xx означают, что получатель жив и отклонил само событие
+func receiverFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	var de *DeliveryError
+	if errors.As(err, &de) {
+		return de.StatusCode >= 500 || de.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// maxRetryAfter предел Retry-After: дальше срок всё равно ограничивает RETRY_MAX_DELAY воркера,
+// а без предела большое число секунд переполняет time.Duration
+const maxRetryAfter = 24 * time.Hour
+
+// parseRetryAfter разбирает Retry-After в секундах либо в виде HTTP-даты, не больше maxRetryAfter
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(min(secs, int64(maxRetryAfter/time.Second))) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil && at.After(now) {
+		return min(at.Sub(now), maxRetryAfter)
+	}
+	return 0
+}
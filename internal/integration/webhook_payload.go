@@ -1,12 +1,134 @@
 package integration
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/deadletter"
+	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
+	"github.com/Soujuruya/01_SPEC/internal/domain/location"
+	"github.com/google/uuid"
+)
+
+// eventNamespace пространство имён для детерминированных ID событий
+var eventNamespace = uuid.MustParse("6f1c2a4e-8d3b-4f5a-9c7e-2b1d0e9f8a6c")
 
 type WebhookPayload struct {
-	UserID      uuid.UUID   `json:"user_id"`
-	Lat         float64     `json:"lat"`
-	Lng         float64     `json:"lng"`
-	IncidentIDs []uuid.UUID `json:"incident_ids"`
-	Timestamp   int64       `json:"timestamp"`
-	Retry       int         `json:"retry"`
+	EventID     uuid.UUID                 `json:"event_id"` // одинаков у всех копий и повторов события; уходит в заголовке Idempotency-Key
+	EventType   string                    `json:"event_type"`
+	Action      string                    `json:"action,omitempty"`      // для событий incident: created, updated, activated, deactivated
+	LocationID  *uuid.UUID                `json:"location_id,omitempty"` // проверка локации, породившая событие; у событий incident нет
+	OccurredAt  string                    `json:"occurred_at"`           // время проверки в RFC3339
+	UserID      uuid.UUID                 `json:"user_id"`
+	Lat         float64                   `json:"lat"`
+	Lng         float64                   `json:"lng"`
+	IncidentIDs []uuid.UUID               `json:"incident_ids"`
+	Transitions []location.Transition     `json:"transitions,omitempty"`
+	Nearby      []location.NearbyIncident `json:"nearby,omitempty"`
+
+	PassedThroughIncidentIDs []uuid.UUID `json:"passed_through_incident_ids,omitempty"`
+
+	Confidence     string           `json:"confidence,omitempty"`
+	Matches        []location.Match `json:"matches,omitempty"`
+	AccuracyMeters *float64         `json:"accuracy_m,omitempty"`
+
+	Severity  string                  `json:"severity,omitempty"` // наивысшая важность среди инцидентов события
+	Incidents []location.IncidentInfo `json:"incidents,omitempty"`
+
+	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty"` // подписка, для которой предназначена копия события
+	Target         string     `json:"target,omitempty"`          // адрес доставки; получателю не отправляется
+	Format         string     `json:"format,omitempty"`          // формат доставки подписки; получателю не отправляется
+
+	// TraceContext трасса проверки или запроса, породивших событие (W3C traceparent); доставка продолжает её,
+	// а получателю уходит заголовком traceparent, а не в теле
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+
+	Timestamp int64                `json:"timestamp"`
+	Retry     int                  `json:"retry"`
+	Attempts  []deadletter.Attempt `json:"attempts,omitempty"` // история неудачных попыток; получателю не отправляется
+}
+
+// EventID ID события: одна проверка локации даёт ровно одно событие каждого типа
+func EventID(locationID uuid.UUID, eventType string) uuid.UUID {
+	return uuid.NewSHA1(eventNamespace, []byte(locationID.String()+":"+eventType))
+}
+
+// NewWebhookPayload собирает событие указанного типа по результату проверки локации
+func NewWebhookPayload(loc *location.Location, eventType string) WebhookPayload {
+	payload := WebhookPayload{
+		EventID:     EventID(loc.ID, eventType),
+		EventType:   eventType,
+		LocationID:  &loc.ID,
+		OccurredAt:  loc.Timestamp.UTC().Format(time.RFC3339),
+		UserID:      loc.UserID,
+		Lat:         loc.Lat,
+		Lng:         loc.Lng,
+		IncidentIDs: loc.IncidentIDs,
+		Timestamp:   time.Now().Unix(),
+		Retry:       0,
+	}
+
+	referenced := make(map[uuid.UUID]bool)
+	switch eventType {
+	case location.EventGeofence:
+		// события, подавленные окном повтора, получателям не отправляются
+		payload.Transitions = loc.NotifiedTransitions()
+		payload.PassedThroughIncidentIDs = loc.NotifiedPassedThrough()
+		payload.Confidence = loc.Confidence
+		payload.Matches = loc.Matches
+		payload.AccuracyMeters = loc.AccuracyMeters
+
+		for _, id := range loc.IncidentIDs {
+			referenced[id] = true
+		}
+		for _, t := range payload.Transitions {
+			referenced[t.IncidentID] = true
+		}
+		for _, id := range payload.PassedThroughIncidentIDs {
+			referenced[id] = true
+		}
+	case location.EventProximity:
+		payload.Nearby = loc.Nearby
+
+		for _, n := range loc.Nearby {
+			referenced[n.IncidentID] = true
+		}
+	}
+
+	for _, info := range loc.Incidents {
+		if !referenced[info.IncidentID] {
+			continue
+		}
+		payload.Incidents = append(payload.Incidents, info)
+		if incident.SeverityRank(info.Severity) > incident.SeverityRank(payload.Severity) {
+			payload.Severity = info.Severity
+		}
+	}
+	return payload
+}
+
+// IncidentEventID ID события об изменении инцидента: одно изменение даёт одно событие
+func IncidentEventID(inc *incident.Incident, action string, at time.Time) uuid.UUID {
+	return uuid.NewSHA1(eventNamespace, []byte(inc.ID.String()+":"+action+":"+at.UTC().Format(time.RFC3339Nano)))
+}
+
+// NewIncidentPayload собирает событие об изменении инцидента; координаты события — центр зоны
+func NewIncidentPayload(inc *incident.Incident, action string, at time.Time) WebhookPayload {
+	info := location.IncidentInfo{
+		IncidentID: inc.ID,
+		Title:      inc.Title,
+		Severity:   inc.Severity,
+		Category:   inc.Category,
+	}
+	return WebhookPayload{
+		EventID:     IncidentEventID(inc, action, at),
+		EventType:   incident.EventLifecycle,
+		Action:      action,
+		OccurredAt:  at.UTC().Format(time.RFC3339),
+		Lat:         inc.Lat,
+		Lng:         inc.Lng,
+		IncidentIDs: []uuid.UUID{inc.ID},
+		Severity:    inc.Severity,
+		Incidents:   []location.IncidentInfo{info},
+		Timestamp:   at.Unix(),
+	}
 }
@@ -0,0 +1,192 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
+	"github.com/Soujuruya/01_SPEC/internal/domain/subscription"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
+	"github.com/google/uuid"
+)
+
+// WebhookRoute статическое правило из конфига: события с инцидентами подходящей категории
+// и важности не ниже MinSeverity доставляются на URL. Пустые условия не ограничивают.
+// Без Secret доставки подписываются секретом по умолчанию
+type WebhookRoute struct {
+	URL           string   `json:"url"`
+	Secret        string   `json:"secret,omitempty"`
+	EventTypes    []string `json:"event_types,omitempty"`
+	Categories    []string `json:"categories,omitempty"`
+	MinSeverity   string   `json:"min_severity,omitempty"`
+	PayloadFormat string   `json:"payload_format,omitempty"`
+}
+
+// ParseWebhookRoutes разбирает правила из JSON-массива (переменная WEBHOOK_ROUTES)
+func ParseWebhookRoutes(raw string) ([]WebhookRoute, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var routes []WebhookRoute
+	if err := json.Unmarshal([]byte(raw), &routes); err != nil {
+		return nil, fmt.Errorf("invalid webhook routes: %w", err)
+	}
+	for i, r := range routes {
+		if r.URL == "" {
+			return nil, fmt.Errorf("webhook route %d: url is required", i)
+		}
+		for _, t := range r.EventTypes {
+			if !subscription.IsEventType(t) {
+				return nil, fmt.Errorf("webhook route %d: unknown event type %q", i, t)
+			}
+		}
+		if r.PayloadFormat != "" && !subscription.IsPayloadFormat(r.PayloadFormat) {
+			return nil, fmt.Errorf("webhook route %d: unknown payload_format %q", i, r.PayloadFormat)
+		}
+		if r.MinSeverity != "" && !incident.IsSeverity(r.MinSeverity) {
+			return nil, fmt.Errorf("webhook route %d: unknown min_severity %q", i, r.MinSeverity)
+		}
+		for _, c := range r.Categories {
+			if !incident.IsCategory(c) {
+				return nil, fmt.Errorf("webhook route %d: unknown category %q", i, c)
+			}
+		}
+	}
+	return routes, nil
+}
+
+// staticSubscription правило из конфига в виде подписки. Идентификатор стабилен между перезапусками,
+// пока правило остаётся на месте index и не меняется; правила с одним URL получают разные идентификаторы.
+// Секрет в идентификатор не входит: идентификатор уходит получателям в subscription_id
+func (r WebhookRoute) staticSubscription(index int, defaultSecret string) *subscription.Subscription {
+	secret := r.Secret
+	if secret == "" {
+		secret = defaultSecret
+	}
+	def := r
+	def.Secret = ""
+	data, _ := json.Marshal(def)
+
+	sub := subscription.NewSubscription(r.URL, secret)
+	sub.ID = uuid.NewSHA1(uuid.NameSpaceURL, []byte(fmt.Sprintf("%d:%s", index, data)))
+	if r.EventTypes != nil {
+		sub.EventTypes = r.EventTypes
+	}
+	if r.PayloadFormat != "" {
+		sub.PayloadFormat = r.PayloadFormat
+	}
+	sub.Categories = r.Categories
+	sub.MinSeverity = r.MinSeverity
+	return sub
+}
+
+// SubscriptionSource подписки, зарегистрированные через API
+type SubscriptionSource interface {
+	Matching(ctx context.Context, e subscription.Event) ([]*subscription.Subscription, error)
+	Secrets(ctx context.Context, id uuid.UUID) ([]string, error)
+}
+
+// Delivery доставка события одному получателю
+type Delivery struct {
+	SubscriptionID *uuid.UUID
+	URL            string
+	Format         string // формат тела доставки, см. subscription.Format*
+}
+
+// WebhookRouter подбирает получателей события среди подписок и статических правил
+type WebhookRouter struct {
+	static         []*subscription.Subscription
+	source         SubscriptionSource
+	fallback       string
+	fallbackSecret string
+	fallbackFormat string
+}
+
+// fallbackSecret подписывает доставки на fallback и статические правила без собственного секрета,
+// fallbackFormat — формат тела доставок на fallback
+func NewWebhookRouter(routes []WebhookRoute, source SubscriptionSource, fallback, fallbackSecret, fallbackFormat string) (*WebhookRouter, error) {
+	if fallback == "" && len(routes) == 0 && source == nil {
+		return nil, errors.New("webhook router needs a default url, a route or a subscription source")
+	}
+
+	static := make([]*subscription.Subscription, len(routes))
+	for i, r := range routes {
+		static[i] = r.staticSubscription(i, fallbackSecret)
+	}
+	return &WebhookRouter{
+		static:         static,
+		source:         source,
+		fallback:       fallback,
+		fallbackSecret: fallbackSecret,
+		fallbackFormat: fallbackFormat,
+	}, nil
+}
+
+// Targets все подходящие получатели, по одной доставке на подписку: у подписок с общим адресом свои секрет,
+// формат и состояние доставки. Если не подошёл никто — адрес по умолчанию.
+// События, на которые нужно подписываться явно (incident), на адрес по умолчанию не отправляются
+func (r *WebhookRouter) Targets(ctx context.Context, payload WebhookPayload) ([]Delivery, error) {
+	e := subscription.Event{
+		Type:      payload.EventType,
+		Lat:       payload.Lat,
+		Lng:       payload.Lng,
+		Incidents: payload.Incidents,
+	}
+
+	subs := make([]*subscription.Subscription, 0, len(r.static))
+	for _, sub := range r.static {
+		if sub.Matches(e) {
+			subs = append(subs, sub)
+		}
+	}
+	if r.source != nil {
+		matched, err := r.source.Matching(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, matched...)
+	}
+
+	var deliveries []Delivery
+	seen := make(map[uuid.UUID]bool)
+	for _, sub := range subs {
+		if seen[sub.ID] {
+			continue
+		}
+		seen[sub.ID] = true
+		id := sub.ID
+		deliveries = append(deliveries, Delivery{SubscriptionID: &id, URL: sub.URL, Format: sub.PayloadFormat})
+	}
+
+	if len(deliveries) == 0 && r.fallback != "" && !subscription.IsOptIn(payload.EventType) {
+		deliveries = append(deliveries, Delivery{URL: r.fallback, Format: r.fallbackFormat})
+	}
+	return deliveries, nil
+}
+
+// Secrets секреты, которыми подписывается доставка: подписки из API, статического правила
+// или адреса по умолчанию. Пустой список — доставка без подписи
+func (r *WebhookRouter) Secrets(ctx context.Context, payload WebhookPayload) ([]string, error) {
+	if payload.SubscriptionID == nil {
+		return nonEmpty(r.fallbackSecret), nil
+	}
+	for _, sub := range r.static {
+		if sub.ID == *payload.SubscriptionID {
+			return nonEmpty(sub.Secret), nil
+		}
+	}
+	if r.source == nil {
+		return nil, errs.ErrNotFound
+	}
+	return r.source.Secrets(ctx, *payload.SubscriptionID)
+}
+
+func nonEmpty(secret string) []string {
+	if secret == "" {
+		return nil
+	}
+	return []string{secret}
+}
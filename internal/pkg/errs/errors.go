@@ -5,4 +5,5 @@ import "errors"
 var (
 	ErrNotFound  = errors.New("record not found")
 	ErrDuplicate = errors.New("duplicate record")
+	ErrLocked    = errors.New("resource is locked")
 )
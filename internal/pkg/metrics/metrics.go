@@ -0,0 +1,92 @@
+// Package metrics метрики Prometheus, которые отдаёт /metrics
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "geoalert"
+
+// HTTP
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+)
+
+// Проверки локации
+var LocationChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "location",
+	Name:      "checks_total",
+	Help:      "Saved location checks by result: hit — the point is inside at least one incident zone, miss — outside all zones.",
+}, []string{"result"})
+
+// Кэш активных инцидентов
+var IncidentCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "incident_cache",
+	Name:      "requests_total",
+	Help:      "Active incidents cache lookups by result: hit, miss or error.",
+}, []string{"result"})
+
+// Вебхуки
+var (
+	WebhookQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "webhook",
+		Name:      "queue_depth",
+		Help:      "Webhook events by queue: pending — waiting to be sent, retry — waiting for the next attempt, processing — taken by workers.",
+	}, []string{"queue"})
+
+	WebhookDeliveryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "webhook",
+		Name:      "delivery_duration_seconds",
+		Help:      "Webhook delivery latency by result: success or failure.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"result"})
+
+	WebhookRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhook",
+		Name:      "retries_total",
+		Help:      "Failed webhook deliveries scheduled for another attempt.",
+	})
+
+	WebhookDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhook",
+		Name:      "dropped_total",
+		Help:      "Webhook events removed from the queue without delivery by reason: dead_letter, invalid_payload or subscription_gone.",
+	}, []string{"reason"})
+)
+
+// Значения меток
+const (
+	ResultHit     = "hit"
+	ResultMiss    = "miss"
+	ResultError   = "error"
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	QueuePending    = "pending"
+	QueueRetry      = "retry"
+	QueueProcessing = "processing"
+
+	DropDeadLetter       = "dead_letter"
+	DropInvalidPayload   = "invalid_payload"
+	DropSubscriptionGone = "subscription_gone"
+)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/Soujuruya/01_SPEC/internal/config"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/tracing"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -13,5 +14,6 @@ func NewClient(cfg *config.RedisConfig) *redis.Client {
 		Password: cfg.Password,
 		DB:       0,
 	})
+	rdb.AddHook(tracing.RedisHook{})
 	return rdb
 }
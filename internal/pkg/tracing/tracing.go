@@ -0,0 +1,100 @@
+// Package tracing трассировка OpenTelemetry: провайдер с экспортом спанов, распространение контекста
+// в формате W3C Trace Context (traceparent) и спаны вокруг запросов к Postgres и Redis
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Soujuruya/01_SPEC/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/Soujuruya/01_SPEC"
+
+// Tracer трейсер приложения; до Setup спаны не создаются
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup настраивает глобальные провайдер и пропагатор W3C Trace Context.
+// При TRACING_EXPORTER=none спаны не записываются, но trace_id у запросов есть и передаётся дальше.
+// Возвращает функцию, которая дописывает накопленные спаны и останавливает провайдер
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", cfg.ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing resource: %w", err)
+	}
+
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	switch cfg.Exporter {
+	case config.TracingExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("stdout exporter: %w", err)
+		}
+		// локально спаны нужны сразу, а не пачкой
+		opts = append(opts, sdktrace.WithSyncer(exp), sdktrace.WithSampler(sampler(cfg.SampleRatio)))
+	case config.TracingExporterOTLP:
+		var expOpts []otlptracehttp.Option
+		if cfg.OTLPEndpoint != "" {
+			expOpts = append(expOpts, otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint))
+		}
+		exp, err := otlptracehttp.New(ctx, expOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlp exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp), sdktrace.WithSampler(sampler(cfg.SampleRatio)))
+	default:
+		opts = append(opts, sdktrace.WithSampler(sdktrace.NeverSample()))
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// sampler уважает решение вызывающей стороны из traceparent, а новые трассы записывает с долей ratio
+func sampler(ratio float64) sdktrace.Sampler {
+	return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+}
+
+// Inject контекст трассировки из ctx в виде заголовков W3C (traceparent, tracestate); nil — трассировки нет
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract восстанавливает в ctx контекст трассировки, сохранённый Inject
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// traced в ctx есть трасса. Фоновые опросы (relay outbox, планировщик, ожидание очереди) идут вне трасс,
+// и спаны их запросов не создаются, иначе каждый опрос давал бы отдельную трассу из одного спана
+func traced(ctx context.Context) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
+}
+
+// fail отмечает спан ошибкой
+func fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
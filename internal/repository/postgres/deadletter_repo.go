@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/Soujuruya/01_SPEC/internal/domain/deadletter"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var deadLetterColumns = []string{
+	"id", "subscription_id", "url", "event_type", "user_id", "payload",
+	"last_error", "last_status_code", "attempts", "created_at",
+}
+
+type DeadLetterRepo struct {
+	pgxPool *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+	lg      *logger.Logger
+}
+
+func NewDeadLetterRepo(pgxPool *pgxpool.Pool, lg *logger.Logger) *DeadLetterRepo {
+	return &DeadLetterRepo{
+		pgxPool: pgxPool,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		lg:      lg,
+	}
+}
+
+// scanDeadLetter читает строку в порядке deadLetterColumns
+func scanDeadLetter(row pgx.Row) (*deadletter.DeadLetter, error) {
+	d := &deadletter.DeadLetter{}
+	var status *int
+	if err := row.Scan(
+		&d.ID, &d.SubscriptionID, &d.URL, &d.EventType, &d.UserID, &d.Payload,
+		&d.LastError, &status, &d.Attempts, &d.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if status != nil {
+		d.LastStatusCode = *status
+	}
+	return d, nil
+}
+
+func nullInt(v int) *int {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+func (r *DeadLetterRepo) Create(ctx context.Context, d *deadletter.DeadLetter) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	if d.Attempts == nil {
+		d.Attempts = []deadletter.Attempt{}
+	}
+
+	query, args, err := r.builder.
+		Insert("webhook_dead_letters").
+		Columns(deadLetterColumns...).
+		Values(
+			d.ID, d.SubscriptionID, d.URL, d.EventType, d.UserID, d.Payload,
+			d.LastError, nullInt(d.LastStatusCode), d.Attempts, d.CreatedAt,
+		).
+		ToSql()
+	if err != nil {
+		r.lg.Error("DeadLetterRepo.Create", "error building query", "error", err)
+		return err
+	}
+
+	if _, err := r.pgxPool.Exec(ctx, query, args...); err != nil {
+		r.lg.Error("DeadLetterRepo.Create", "error exec query", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *DeadLetterRepo) GetByID(ctx context.Context, id uuid.UUID) (*deadletter.DeadLetter, error) {
+	query, args, err := r.builder.
+		Select(deadLetterColumns...).
+		From("webhook_dead_letters").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		r.lg.Error("DeadLetterRepo.GetByID", "error building query", "error", err)
+		return nil, err
+	}
+
+	d, err := scanDeadLetter(r.pgxPool.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		r.lg.Error("DeadLetterRepo.GetByID", "error scanning row", "id", id, "error", err)
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// List страница записей от новых к старым и общее количество
+func (r *DeadLetterRepo) List(ctx context.Context, offset, limit int) ([]*deadletter.DeadLetter, int, error) {
+	query, args, err := r.builder.
+		Select(deadLetterColumns...).
+		From("webhook_dead_letters").
+		OrderBy("created_at DESC", "id").
+		Offset(uint64(offset)).
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		r.lg.Error("DeadLetterRepo.List", "error building query", "error", err)
+		return nil, 0, err
+	}
+
+	rows, err := r.pgxPool.Query(ctx, query, args...)
+	if err != nil {
+		r.lg.Error("DeadLetterRepo.List", "error executing query", "error", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	letters := []*deadletter.DeadLetter{}
+	for rows.Next() {
+		d, err := scanDeadLetter(rows)
+		if err != nil {
+			r.lg.Error("DeadLetterRepo.List", "error scanning row", "error", err)
+			return nil, 0, err
+		}
+		letters = append(letters, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.lg.Error("DeadLetterRepo.List", "rows error", "error", err)
+		return nil, 0, err
+	}
+
+	var total int
+	if err := r.pgxPool.QueryRow(ctx, "SELECT COUNT(*) FROM webhook_dead_letters").Scan(&total); err != nil {
+		r.lg.Error("DeadLetterRepo.List", "error counting rows", "error", err)
+		return nil, 0, err
+	}
+
+	return letters, total, nil
+}
+
+func (r *DeadLetterRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	query, args, err := r.builder.
+		Delete("webhook_dead_letters").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		r.lg.Error("DeadLetterRepo.Delete", "error building query", "error", err)
+		return err
+	}
+
+	res, err := r.pgxPool.Exec(ctx, query, args...)
+	if err != nil {
+		r.lg.Error("DeadLetterRepo.Delete", "error exec query", "id", id, "error", err)
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+// Purge удаляет все записи и возвращает их количество
+func (r *DeadLetterRepo) Purge(ctx context.Context) (int64, error) {
+	res, err := r.pgxPool.Exec(ctx, "DELETE FROM webhook_dead_letters")
+	if err != nil {
+		r.lg.Error("DeadLetterRepo.Purge", "error exec query", "error", err)
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
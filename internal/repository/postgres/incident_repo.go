@@ -2,18 +2,41 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
+	"github.com/Soujuruya/01_SPEC/internal/integration"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/tracing"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var incidentColumns = []string{
+	"id", "title", "lat", "lng", "radius", "geometry", "is_active", "created_at", "updated_at",
+	"starts_at", "ends_at", "recurrence", "severity", "category", "notify_cooldown_seconds",
+}
+
+// scheduledCond инцидент с расписанием: включается и выключается по времени
+var scheduledCond = squirrel.Expr("(starts_at IS NOT NULL OR ends_at IS NOT NULL OR recurrence IS NOT NULL)")
+
+// activeWindowCond инциденты, которые могут быть активны в момент now. Правило повторения
+// в SQL не проверяется, поэтому результат дополнительно фильтруется incident.ActiveAt
+func activeWindowCond(now time.Time) squirrel.Sqlizer {
+	return squirrel.And{
+		squirrel.Or{squirrel.Eq{"is_active": true}, scheduledCond},
+		squirrel.Or{squirrel.Eq{"starts_at": nil}, squirrel.LtOrEq{"starts_at": now}},
+		squirrel.Or{squirrel.Eq{"ends_at": nil}, squirrel.Gt{"ends_at": now}},
+	}
+}
+
 type IncidentRepo struct {
 	pgxPool *pgxpool.Pool
 	builder squirrel.StatementBuilderType
@@ -28,10 +51,50 @@ func NewIncidentRepo(pgxPool *pgxpool.Pool, log *logger.Logger) *IncidentRepo {
 	}
 }
 
-func (r *IncidentRepo) CountAll(ctx context.Context) (int, error) {
+// scanIncident читает строку в порядке incidentColumns
+func scanIncident(row pgx.Row) (*incident.Incident, error) {
+	i := &incident.Incident{}
+	var cooldown *int
+	if err := row.Scan(
+		&i.ID, &i.Title, &i.Lat, &i.Lng, &i.Radius, &i.Geometry,
+		&i.IsActive, &i.CreatedAt, &i.UpdatedAt,
+		&i.StartsAt, &i.EndsAt, &i.Recurrence, &i.Severity, &i.Category, &cooldown,
+	); err != nil {
+		return nil, err
+	}
+	if cooldown != nil {
+		d := time.Duration(*cooldown) * time.Second
+		i.NotifyCooldown = &d
+	}
+	return i, nil
+}
+
+// cooldownSeconds окно повтора в секундах для колонки notify_cooldown_seconds
+func cooldownSeconds(d *time.Duration) *int {
+	if d == nil {
+		return nil
+	}
+	secs := int(d.Seconds())
+	return &secs
+}
+
+// filterCond условия выборки по фильтру списка
+func filterCond(filter incident.ListFilter) squirrel.And {
+	cond := squirrel.And{}
+	if len(filter.Severities) > 0 {
+		cond = append(cond, squirrel.Eq{"severity": filter.Severities})
+	}
+	if len(filter.Categories) > 0 {
+		cond = append(cond, squirrel.Eq{"category": filter.Categories})
+	}
+	return cond
+}
+
+func (r *IncidentRepo) CountAll(ctx context.Context, filter incident.ListFilter) (int, error) {
 	query, args, err := r.builder.
 		Select("COUNT(*)").
 		From("incidents").
+		Where(filterCond(filter)).
 		ToSql()
 	if err != nil {
 		r.lg.Error("IncidentRepo.CountAll", "error building query", "error", err)
@@ -70,10 +133,11 @@ func (r *IncidentRepo) CountActiveIncidents(ctx context.Context) (int, error) {
 }
 
 func (r *IncidentRepo) GetActiveIncidents(ctx context.Context) ([]*incident.Incident, error) {
+	now := time.Now()
 	query, args, err := r.builder.
-		Select("id", "title", "lat", "lng", "radius", "is_active", "created_at", "updated_at").
+		Select(incidentColumns...).
 		From("incidents").
-		Where(squirrel.Eq{"is_active": true}).
+		Where(activeWindowCond(now)).
 		OrderBy("created_at DESC").
 		ToSql()
 	if err != nil {
@@ -90,11 +154,8 @@ func (r *IncidentRepo) GetActiveIncidents(ctx context.Context) ([]*incident.Inci
 
 	var incidents []*incident.Incident
 	for rows.Next() {
-		i := &incident.Incident{}
-		if err := rows.Scan(
-			&i.ID, &i.Title, &i.Lat, &i.Lng, &i.Radius,
-			&i.IsActive, &i.CreatedAt, &i.UpdatedAt,
-		); err != nil {
+		i, err := scanIncident(rows)
+		if err != nil {
 			r.lg.Error("IncidentRepo.GetActiveIncidents", "error scanning row", "error", err)
 			return nil, err
 		}
@@ -106,6 +167,49 @@ func (r *IncidentRepo) GetActiveIncidents(ctx context.Context) ([]*incident.Inci
 		return nil, err
 	}
 
+	return incident.ActiveAt(incidents, now), nil
+}
+
+func (r *IncidentRepo) ListActiveBetween(ctx context.Context, from, to time.Time) ([]*incident.Incident, error) {
+	query, args, err := r.builder.
+		Select(incidentColumns...).
+		From("incidents").
+		Where(squirrel.LtOrEq{"created_at": to}).
+		Where(squirrel.Or{
+			squirrel.Eq{"is_active": true},
+			squirrel.GtOrEq{"updated_at": from},
+			squirrel.And{scheduledCond, squirrel.Or{squirrel.Eq{"ends_at": nil}, squirrel.GtOrEq{"ends_at": from}}},
+		}).
+		Where(squirrel.Or{squirrel.Eq{"starts_at": nil}, squirrel.LtOrEq{"starts_at": to}}).
+		OrderBy("created_at DESC").
+		ToSql()
+	if err != nil {
+		r.lg.Error("IncidentRepo.ListActiveBetween", "error building query", "error", err)
+		return nil, err
+	}
+
+	rows, err := r.pgxPool.Query(ctx, query, args...)
+	if err != nil {
+		r.lg.Error("IncidentRepo.ListActiveBetween", "error executing query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []*incident.Incident
+	for rows.Next() {
+		i, err := scanIncident(rows)
+		if err != nil {
+			r.lg.Error("IncidentRepo.ListActiveBetween", "error scanning row", "error", err)
+			return nil, err
+		}
+		incidents = append(incidents, i)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.lg.Error("IncidentRepo.ListActiveBetween", "rows error", "error", err)
+		return nil, err
+	}
+
 	return incidents, nil
 }
 
@@ -120,8 +224,11 @@ func (r *IncidentRepo) Create(ctx context.Context, inc *incident.Incident) error
 
 	query, args, err := r.builder.
 		Insert("incidents").
-		Columns("id", "title", "lat", "lng", "radius", "is_active", "created_at", "updated_at").
-		Values(inc.ID, inc.Title, inc.Lat, inc.Lng, inc.Radius, inc.IsActive, inc.CreatedAt, inc.UpdatedAt).
+		Columns(incidentColumns...).
+		Values(
+			inc.ID, inc.Title, inc.Lat, inc.Lng, inc.Radius, inc.Geometry, inc.IsActive, inc.CreatedAt, inc.UpdatedAt,
+			inc.StartsAt, inc.EndsAt, inc.Recurrence, inc.Severity, inc.Category, cooldownSeconds(inc.NotifyCooldown),
+		).
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
 	if err != nil {
@@ -129,7 +236,14 @@ func (r *IncidentRepo) Create(ctx context.Context, inc *incident.Incident) error
 		return err
 	}
 
-	_, err = r.pgxPool.Exec(ctx, query, args...)
+	tx, err := r.pgxPool.Begin(ctx)
+	if err != nil {
+		r.lg.Error("IncidentRepo.Create", "error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, query, args...)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -140,12 +254,15 @@ func (r *IncidentRepo) Create(ctx context.Context, inc *incident.Incident) error
 		return err
 	}
 
-	return nil
+	if err := r.writeEvent(ctx, tx, "IncidentRepo.Create", inc, incident.ActionCreated, inc.CreatedAt); err != nil {
+		return err
+	}
+	return r.commit(ctx, tx, "IncidentRepo.Create")
 }
 
 func (r *IncidentRepo) GetByID(ctx context.Context, id uuid.UUID) (*incident.Incident, error) {
 	query, args, err := r.builder.
-		Select("id", "title", "lat", "lng", "radius", "is_active", "created_at", "updated_at").
+		Select(incidentColumns...).
 		From("incidents").
 		Where(squirrel.Eq{"id": id}).
 		PlaceholderFormat(squirrel.Dollar).
@@ -155,9 +272,8 @@ func (r *IncidentRepo) GetByID(ctx context.Context, id uuid.UUID) (*incident.Inc
 		return nil, err
 	}
 
-	row := r.pgxPool.QueryRow(ctx, query, args...)
-	inc := &incident.Incident{}
-	if err := row.Scan(&inc.ID, &inc.Title, &inc.Lat, &inc.Lng, &inc.Radius, &inc.IsActive, &inc.CreatedAt, &inc.UpdatedAt); err != nil {
+	inc, err := scanIncident(r.pgxPool.QueryRow(ctx, query, args...))
+	if err != nil {
 		r.lg.Error("IncidentRepo.GetByID", "not found", "id", id, "error", err)
 		return nil, errs.ErrNotFound
 	}
@@ -174,8 +290,15 @@ func (r *IncidentRepo) Update(ctx context.Context, inc *incident.Incident) error
 		Set("lat", inc.Lat).
 		Set("lng", inc.Lng).
 		Set("radius", inc.Radius).
+		Set("geometry", inc.Geometry).
 		Set("is_active", inc.IsActive).
 		Set("updated_at", inc.UpdatedAt).
+		Set("starts_at", inc.StartsAt).
+		Set("ends_at", inc.EndsAt).
+		Set("recurrence", inc.Recurrence).
+		Set("severity", inc.Severity).
+		Set("category", inc.Category).
+		Set("notify_cooldown_seconds", cooldownSeconds(inc.NotifyCooldown)).
 		Where(squirrel.Eq{"id": inc.ID}).
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
@@ -184,7 +307,14 @@ func (r *IncidentRepo) Update(ctx context.Context, inc *incident.Incident) error
 		return err
 	}
 
-	res, err := r.pgxPool.Exec(ctx, query, args...)
+	tx, err := r.pgxPool.Begin(ctx)
+	if err != nil {
+		r.lg.Error("IncidentRepo.Update", "error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, query, args...)
 	if err != nil {
 		r.lg.Error("IncidentRepo.Update", "error exec query", "id", inc.ID, "error", err)
 		return err
@@ -195,15 +325,21 @@ func (r *IncidentRepo) Update(ctx context.Context, inc *incident.Incident) error
 		return errs.ErrNotFound
 	}
 
-	return nil
+	if err := r.writeEvent(ctx, tx, "IncidentRepo.Update", inc, incident.ActionUpdated, inc.UpdatedAt); err != nil {
+		return err
+	}
+	return r.commit(ctx, tx, "IncidentRepo.Update")
 }
 
 func (r *IncidentRepo) Deactivate(ctx context.Context, id uuid.UUID) error {
+	now := time.Now()
 	query, args, err := r.builder.
 		Update("incidents").
 		Set("is_active", false).
-		Set("updated_at", time.Now()).
+		Set("updated_at", now).
+		Set("ends_at", squirrel.Expr("LEAST(COALESCE(ends_at, now()), now())")).
 		Where(squirrel.Eq{"id": id}).
+		Suffix("RETURNING " + strings.Join(incidentColumns, ", ")).
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
 	if err != nil {
@@ -211,23 +347,33 @@ func (r *IncidentRepo) Deactivate(ctx context.Context, id uuid.UUID) error {
 		return err
 	}
 
-	res, err := r.pgxPool.Exec(ctx, query, args...)
+	tx, err := r.pgxPool.Begin(ctx)
 	if err != nil {
-		r.lg.Error("IncidentRepo.Deactivate", "error exec query", "id", id, "error", err)
+		r.lg.Error("IncidentRepo.Deactivate", "error starting transaction", "error", err)
 		return err
 	}
+	defer tx.Rollback(ctx)
 
-	if res.RowsAffected() == 0 {
+	inc, err := scanIncident(tx.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
 		return errs.ErrNotFound
 	}
+	if err != nil {
+		r.lg.Error("IncidentRepo.Deactivate", "error exec query", "id", id, "error", err)
+		return err
+	}
 
-	return nil
+	if err := r.writeEvent(ctx, tx, "IncidentRepo.Deactivate", inc, incident.ActionDeactivated, now); err != nil {
+		return err
+	}
+	return r.commit(ctx, tx, "IncidentRepo.Deactivate")
 }
 
-func (r *IncidentRepo) List(ctx context.Context, offset, limit int) ([]*incident.Incident, error) {
+func (r *IncidentRepo) List(ctx context.Context, filter incident.ListFilter, offset, limit int) ([]*incident.Incident, error) {
 	query, args, err := r.builder.
-		Select("id", "title", "lat", "lng", "radius", "is_active", "created_at", "updated_at").
+		Select(incidentColumns...).
 		From("incidents").
+		Where(filterCond(filter)).
 		OrderBy("created_at DESC").
 		Offset(uint64(offset)).
 		Limit(uint64(limit)).
@@ -247,8 +393,8 @@ func (r *IncidentRepo) List(ctx context.Context, offset, limit int) ([]*incident
 
 	var incidents []*incident.Incident
 	for rows.Next() {
-		i := &incident.Incident{}
-		if err := rows.Scan(&i.ID, &i.Title, &i.Lat, &i.Lng, &i.Radius, &i.IsActive, &i.CreatedAt, &i.UpdatedAt); err != nil {
+		i, err := scanIncident(rows)
+		if err != nil {
 			r.lg.Error("IncidentRepo.List", "error scanning row", "error", err)
 			return nil, err
 		}
@@ -263,14 +409,14 @@ func (r *IncidentRepo) List(ctx context.Context, offset, limit int) ([]*incident
 	return incidents, nil
 }
 
-func (r *IncidentRepo) ListWithTotal(ctx context.Context, offset, limit int) ([]*incident.Incident, int, error) {
-	incs, err := r.List(ctx, offset, limit)
+func (r *IncidentRepo) ListWithTotal(ctx context.Context, filter incident.ListFilter, offset, limit int) ([]*incident.Incident, int, error) {
+	incs, err := r.List(ctx, filter, offset, limit)
 	if err != nil {
 		r.lg.Error("IncidentRepo.ListWithTotal", "error listing incidents", "error", err)
 		return nil, 0, err
 	}
 
-	total, err := r.CountAll(ctx)
+	total, err := r.CountAll(ctx, filter)
 	if err != nil {
 		r.lg.Error("IncidentRepo.ListWithTotal", "error counting incidents", "error", err)
 		return nil, 0, err
@@ -278,3 +424,127 @@ func (r *IncidentRepo) ListWithTotal(ctx context.Context, offset, limit int) ([]
 
 	return incs, total, nil
 }
+
+// ListScheduled инциденты с расписанием, состояние которых ещё может измениться
+func (r *IncidentRepo) ListScheduled(ctx context.Context, now time.Time) ([]*incident.Incident, error) {
+	query, args, err := r.builder.
+		Select(incidentColumns...).
+		From("incidents").
+		Where(scheduledCond).
+		Where(squirrel.Or{
+			squirrel.Eq{"is_active": true},
+			squirrel.Eq{"ends_at": nil},
+			squirrel.Gt{"ends_at": now},
+		}).
+		ToSql()
+	if err != nil {
+		r.lg.Error("IncidentRepo.ListScheduled", "error building query", "error", err)
+		return nil, err
+	}
+
+	rows, err := r.pgxPool.Query(ctx, query, args...)
+	if err != nil {
+		r.lg.Error("IncidentRepo.ListScheduled", "error executing query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []*incident.Incident
+	for rows.Next() {
+		i, err := scanIncident(rows)
+		if err != nil {
+			r.lg.Error("IncidentRepo.ListScheduled", "error scanning row", "error", err)
+			return nil, err
+		}
+		incidents = append(incidents, i)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.lg.Error("IncidentRepo.ListScheduled", "rows error", "error", err)
+		return nil, err
+	}
+
+	return incidents, nil
+}
+
+// SetActive переключает флаг активности, не трогая расписание. Флаг сравнивается в том же UPDATE,
+// поэтому из нескольких инстансов, заметивших одну границу окна, событие запишет только один
+func (r *IncidentRepo) SetActive(ctx context.Context, id uuid.UUID, active bool, switchedAt time.Time) (bool, error) {
+	query, args, err := r.builder.
+		Update("incidents").
+		Set("is_active", active).
+		Set("updated_at", time.Now()).
+		Where(squirrel.Eq{"id": id}).
+		Where(squirrel.NotEq{"is_active": active}).
+		Suffix("RETURNING " + strings.Join(incidentColumns, ", ")).
+		ToSql()
+	if err != nil {
+		r.lg.Error("IncidentRepo.SetActive", "error building query", "id", id, "error", err)
+		return false, err
+	}
+
+	tx, err := r.pgxPool.Begin(ctx)
+	if err != nil {
+		r.lg.Error("IncidentRepo.SetActive", "error starting transaction", "error", err)
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	inc, err := scanIncident(tx.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		r.lg.Error("IncidentRepo.SetActive", "error exec query", "id", id, "error", err)
+		return false, err
+	}
+
+	action := incident.ActionDeactivated
+	if active {
+		action = incident.ActionActivated
+	}
+	if err := r.writeEvent(ctx, tx, "IncidentRepo.SetActive", inc, action, switchedAt); err != nil {
+		return false, err
+	}
+	if err := r.commit(ctx, tx, "IncidentRepo.SetActive"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// writeEvent записывает событие об изменении инцидента в outbox в транзакции самого изменения.
+// ID события зависит от инцидента, action и at, поэтому повтор того же изменения второго события не даёт
+func (r *IncidentRepo) writeEvent(ctx context.Context, tx pgx.Tx, op string, inc *incident.Incident, action string, at time.Time) error {
+	payload := integration.NewIncidentPayload(inc, action, at)
+	payload.TraceContext = tracing.Inject(ctx)
+	data, err := json.Marshal(payload)
+	if err != nil {
+		r.lg.Error(op, "error marshaling event", "error", err, "incident_id", inc.ID)
+		return err
+	}
+
+	query, args, err := r.builder.
+		Insert("outbox").
+		Columns("id", "event_type", "payload", "created_at").
+		Values(payload.EventID, payload.EventType, data, time.Now()).
+		Suffix("ON CONFLICT (id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		r.lg.Error(op, "error building outbox query", "error", err)
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		r.lg.Error(op, "error writing outbox", "error", err, "incident_id", inc.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *IncidentRepo) commit(ctx context.Context, tx pgx.Tx, op string) error {
+	if err := tx.Commit(ctx); err != nil {
+		r.lg.Error(op, "error committing transaction", "error", err)
+		return err
+	}
+	return nil
+}
@@ -2,18 +2,26 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/Soujuruya/01_SPEC/internal/domain/location"
+	"github.com/Soujuruya/01_SPEC/internal/integration"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/tracing"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var locationColumns = []string{
+	"id", "user_id", "lat", "lng", "timestamp", "is_check", "incident_ids", "passed_through_incident_ids",
+	"accuracy_m", "altitude", "speed", "heading", "confidence",
+}
+
 type LocationRepo struct {
 	pgxPool *pgxpool.Pool
 	builder squirrel.StatementBuilderType
@@ -28,45 +36,130 @@ func NewLocationRepo(pgxPool *pgxpool.Pool, lg *logger.Logger) *LocationRepo {
 	}
 }
 
-// Save сохраняет новую проверку локации
+// locationValues значения в порядке locationColumns
+func locationValues(loc *location.Location) []any {
+	return []any{
+		loc.ID, loc.UserID, loc.Lat, loc.Lng, loc.Timestamp, loc.IsCheck, loc.IncidentIDs, loc.PassedThroughIncidentIDs,
+		loc.AccuracyMeters, loc.Altitude, loc.Speed, loc.Heading, loc.Confidence,
+	}
+}
+
+// Save сохраняет новую проверку локации вместе с её событиями вебхуков
 func (r *LocationRepo) Save(ctx context.Context, loc *location.Location) error {
-	if loc.ID == uuid.Nil {
-		loc.ID = uuid.New()
+	if err := r.insert(ctx, "LocationRepo.Save", []*location.Location{loc}); err != nil {
+		return err
+	}
+
+	r.lg.Debug("LocationRepo.Save: location saved successfully", "user_id", loc.UserID, "location_id", loc.ID)
+	return nil
+}
+
+// SaveBatch сохраняет несколько проверок одним INSERT
+func (r *LocationRepo) SaveBatch(ctx context.Context, locs []*location.Location) error {
+	if len(locs) == 0 {
+		return nil
 	}
-	if loc.Timestamp.IsZero() {
-		loc.Timestamp = time.Now()
+	if err := r.insert(ctx, "LocationRepo.SaveBatch", locs); err != nil {
+		return err
 	}
 
-	query, args, err := r.builder.
+	r.lg.Debug("LocationRepo.SaveBatch: locations saved successfully", "count", len(locs))
+	return nil
+}
+
+// insert записывает проверки и события из loc.Events в outbox одной транзакцией,
+// поэтому событие появляется тогда и только тогда, когда сохранена сама проверка
+func (r *LocationRepo) insert(ctx context.Context, op string, locs []*location.Location) error {
+	insert := r.builder.
 		Insert("locations").
-		Columns("id", "user_id", "lat", "lng", "timestamp", "is_check", "incident_ids").
-		Values(loc.ID, loc.UserID, loc.Lat, loc.Lng, loc.Timestamp, loc.IsCheck, loc.IncidentIDs).
-		PlaceholderFormat(squirrel.Dollar).
-		ToSql()
+		Columns(locationColumns...)
+	for _, loc := range locs {
+		if loc.ID == uuid.Nil {
+			loc.ID = uuid.New()
+		}
+		if loc.Timestamp.IsZero() {
+			loc.Timestamp = time.Now()
+		}
+		insert = insert.Values(locationValues(loc)...)
+	}
+
+	query, args, err := insert.ToSql()
+	if err != nil {
+		r.lg.Error(op+": error building query", "error", err, "count", len(locs))
+		return err
+	}
+
+	events, err := r.outboxInsert(ctx, locs)
 	if err != nil {
-		r.lg.Error("LocationRepo.Save: error building query", "error", err, "user_id", loc.UserID)
+		r.lg.Error(op+": error building outbox query", "error", err, "count", len(locs))
 		return err
 	}
 
-	_, err = r.pgxPool.Exec(ctx, query, args...)
+	tx, err := r.pgxPool.Begin(ctx)
 	if err != nil {
+		r.lg.Error(op+": error starting transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			r.lg.Warn("LocationRepo.Save: duplicate entry", "user_id", loc.UserID)
+			r.lg.Warn(op+": duplicate entry", "count", len(locs))
 			return errs.ErrDuplicate
 		}
-		r.lg.Error("LocationRepo.Save: error executing query", "error", err, "user_id", loc.UserID)
+		r.lg.Error(op+": error executing query", "error", err, "count", len(locs))
 		return err
 	}
 
-	r.lg.Debug("LocationRepo.Save: location saved successfully", "user_id", loc.UserID, "location_id", loc.ID)
+	if events != nil {
+		query, args, err := events.ToSql()
+		if err != nil {
+			r.lg.Error(op+": error building outbox query", "error", err)
+			return err
+		}
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			r.lg.Error(op+": error writing outbox", "error", err, "count", len(locs))
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.lg.Error(op+": error committing transaction", "error", err)
+		return err
+	}
 	return nil
 }
 
+// outboxInsert строит INSERT событий для outbox; nil, если событий нет.
+// События несут трассу из ctx, чтобы доставка вебхука продолжила трассу проверки
+func (r *LocationRepo) outboxInsert(ctx context.Context, locs []*location.Location) (*squirrel.InsertBuilder, error) {
+	traceContext := tracing.Inject(ctx)
+	var insert *squirrel.InsertBuilder
+	for _, loc := range locs {
+		for _, eventType := range loc.Events {
+			payload := integration.NewWebhookPayload(loc, eventType)
+			payload.TraceContext = traceContext
+			data, err := json.Marshal(payload)
+			if err != nil {
+				return nil, err
+			}
+			if insert == nil {
+				b := r.builder.Insert("outbox").Columns("id", "location_id", "event_type", "payload", "created_at").
+					Suffix("ON CONFLICT (id) DO NOTHING")
+				insert = &b
+			}
+			// ID сообщения совпадает с ID события, поэтому relay не опубликует одно событие дважды
+			*insert = insert.Values(payload.EventID, loc.ID, eventType, data, time.Now())
+		}
+	}
+	return insert, nil
+}
+
 // ListByUser возвращает последние проверки пользователя
 func (r *LocationRepo) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*location.Location, error) {
 	query, args, err := r.builder.
-		Select("id", "user_id", "lat", "lng", "timestamp", "is_check", "incident_ids").
+		Select(locationColumns...).
 		From("locations").
 		Where(squirrel.Eq{"user_id": userID}).
 		OrderBy("timestamp DESC").
@@ -88,7 +181,10 @@ func (r *LocationRepo) ListByUser(ctx context.Context, userID uuid.UUID, limit i
 	var locations []*location.Location
 	for rows.Next() {
 		loc := &location.Location{}
-		if err := rows.Scan(&loc.ID, &loc.UserID, &loc.Lat, &loc.Lng, &loc.Timestamp, &loc.IsCheck, &loc.IncidentIDs); err != nil {
+		if err := rows.Scan(
+			&loc.ID, &loc.UserID, &loc.Lat, &loc.Lng, &loc.Timestamp, &loc.IsCheck, &loc.IncidentIDs, &loc.PassedThroughIncidentIDs,
+			&loc.AccuracyMeters, &loc.Altitude, &loc.Speed, &loc.Heading, &loc.Confidence,
+		); err != nil {
 			r.lg.Error("LocationRepo.ListByUser: error scanning row", "error", err, "user_id", userID)
 			return nil, err
 		}
@@ -109,26 +205,117 @@ func (r *LocationRepo) ListByUser(ctx context.Context, userID uuid.UUID, limit i
 	return locations, nil
 }
 
-// CountUniqueUsers возвращает количество уникальных пользователей с указанного времени
-func (r *LocationRepo) CountUniqueUsers(ctx context.Context, since time.Time) (int, error) {
+// CountUsersInIncidents возвращает количество уникальных пользователей, попавших хотя бы в одну зону за [from, to)
+func (r *LocationRepo) CountUsersInIncidents(ctx context.Context, from, to time.Time) (int, error) {
 	query, args, err := r.builder.
 		Select("COUNT(DISTINCT user_id)").
 		From("locations").
-		Where(squirrel.GtOrEq{"timestamp": since}).
+		Where(squirrel.Eq{"is_check": true}).
+		Where(squirrel.GtOrEq{"timestamp": from}).
+		Where(squirrel.Lt{"timestamp": to}).
 		PlaceholderFormat(squirrel.Dollar).
 		ToSql()
 	if err != nil {
-		r.lg.Error("LocationRepo.CountUniqueUsers: error building query", "error", err)
+		r.lg.Error("LocationRepo.CountUsersInIncidents: error building query", "error", err)
 		return 0, err
 	}
 
 	var count int
 	err = r.pgxPool.QueryRow(ctx, query, args...).Scan(&count)
 	if err != nil {
-		r.lg.Error("LocationRepo.CountUniqueUsers: error executing query", "error", err)
+		r.lg.Error("LocationRepo.CountUsersInIncidents: error executing query", "error", err)
 		return 0, err
 	}
 
-	r.lg.Debug("LocationRepo.CountUniqueUsers: unique user count fetched", "count", count)
+	r.lg.Debug("LocationRepo.CountUsersInIncidents: unique user count fetched", "count", count)
 	return count, nil
 }
+
+// CountByIncident возвращает попадания за [from, to) по каждой зоне из incident_ids
+func (r *LocationRepo) CountByIncident(ctx context.Context, from, to time.Time) ([]location.IncidentStats, error) {
+	query, args, err := r.builder.
+		Select("hit.incident_id", "COALESCE(i.title, '')", "COUNT(DISTINCT l.user_id) AS users", "COUNT(*) AS hits").
+		From("locations l").
+		CrossJoin("unnest(l.incident_ids) AS hit(incident_id)").
+		LeftJoin("incidents i ON i.id = hit.incident_id").
+		Where(squirrel.GtOrEq{"l.timestamp": from}).
+		Where(squirrel.Lt{"l.timestamp": to}).
+		GroupBy("hit.incident_id", "i.title").
+		OrderBy("users DESC", "hits DESC", "hit.incident_id").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		r.lg.Error("LocationRepo.CountByIncident: error building query", "error", err)
+		return nil, err
+	}
+
+	rows, err := r.pgxPool.Query(ctx, query, args...)
+	if err != nil {
+		r.lg.Error("LocationRepo.CountByIncident: error executing query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []location.IncidentStats{}
+	for rows.Next() {
+		var st location.IncidentStats
+		if err := rows.Scan(&st.IncidentID, &st.Title, &st.UserCount, &st.HitCount); err != nil {
+			r.lg.Error("LocationRepo.CountByIncident: error scanning row", "error", err)
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	if err := rows.Err(); err != nil {
+		r.lg.Error("LocationRepo.CountByIncident: rows error", "error", err)
+		return nil, err
+	}
+
+	r.lg.Debug("LocationRepo.CountByIncident: breakdown fetched", "incidents", len(stats))
+	return stats, nil
+}
+
+// HitSeries возвращает попадания за [from, to) по интервалам bucket, отсчитанным от from.
+// Интервалы без попаданий в результат не попадают
+func (r *LocationRepo) HitSeries(ctx context.Context, from, to time.Time, bucket time.Duration) ([]location.StatsBucket, error) {
+	query, args, err := r.builder.
+		Select().
+		Column(squirrel.Expr("date_bin(make_interval(secs => ?), timestamp, ?) AS bucket", bucket.Seconds(), from)).
+		Column("COUNT(DISTINCT user_id)").
+		Column("COUNT(*)").
+		From("locations").
+		Where(squirrel.Eq{"is_check": true}).
+		Where(squirrel.GtOrEq{"timestamp": from}).
+		Where(squirrel.Lt{"timestamp": to}).
+		GroupBy("bucket").
+		OrderBy("bucket").
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
+	if err != nil {
+		r.lg.Error("LocationRepo.HitSeries: error building query", "error", err)
+		return nil, err
+	}
+
+	rows, err := r.pgxPool.Query(ctx, query, args...)
+	if err != nil {
+		r.lg.Error("LocationRepo.HitSeries: error executing query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := []location.StatsBucket{}
+	for rows.Next() {
+		var b location.StatsBucket
+		if err := rows.Scan(&b.Start, &b.UserCount, &b.HitCount); err != nil {
+			r.lg.Error("LocationRepo.HitSeries: error scanning row", "error", err)
+			return nil, err
+		}
+		series = append(series, b)
+	}
+	if err := rows.Err(); err != nil {
+		r.lg.Error("LocationRepo.HitSeries: rows error", "error", err)
+		return nil, err
+	}
+
+	r.lg.Debug("LocationRepo.HitSeries: series fetched", "buckets", len(series), "bucket", bucket)
+	return series, nil
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/Soujuruya/01_SPEC/internal/domain/outbox"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OutboxRepo struct {
+	pgxPool *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+	lg      *logger.Logger
+}
+
+func NewOutboxRepo(pgxPool *pgxpool.Pool, lg *logger.Logger) *OutboxRepo {
+	return &OutboxRepo{
+		pgxPool: pgxPool,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		lg:      lg,
+	}
+}
+
+// Relay блокирует строки через FOR UPDATE SKIP LOCKED, поэтому несколько экземпляров relay
+// не публикуют одно и то же сообщение одновременно
+func (r *OutboxRepo) Relay(ctx context.Context, limit int, publish func(ctx context.Context, msgs []outbox.Message) error) (int, error) {
+	query, args, err := r.builder.
+		Select("id", "location_id", "event_type", "payload", "created_at").
+		From("outbox").
+		Where(squirrel.Eq{"sent_at": nil}).
+		OrderBy("created_at").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
+	if err != nil {
+		r.lg.Error("OutboxRepo.Relay", "error building query", "error", err)
+		return 0, err
+	}
+
+	tx, err := r.pgxPool.Begin(ctx)
+	if err != nil {
+		r.lg.Error("OutboxRepo.Relay", "error starting transaction", "error", err)
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		r.lg.Error("OutboxRepo.Relay", "error exec query", "error", err)
+		return 0, err
+	}
+
+	var msgs []outbox.Message
+	for rows.Next() {
+		var m outbox.Message
+		if err := rows.Scan(&m.ID, &m.LocationID, &m.EventType, &m.Payload, &m.CreatedAt); err != nil {
+			rows.Close()
+			r.lg.Error("OutboxRepo.Relay", "error scanning row", "error", err)
+			return 0, err
+		}
+		msgs = append(msgs, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.lg.Error("OutboxRepo.Relay", "rows error", "error", err)
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+
+	if err := publish(ctx, msgs); err != nil {
+		return 0, err
+	}
+
+	ids := make([]uuid.UUID, len(msgs))
+	for i, m := range msgs {
+		ids[i] = m.ID
+	}
+	query, args, err = r.builder.
+		Update("outbox").
+		Set("sent_at", squirrel.Expr("now()")).
+		Where(squirrel.Eq{"id": ids}).
+		ToSql()
+	if err != nil {
+		r.lg.Error("OutboxRepo.Relay", "error building query", "error", err)
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		r.lg.Error("OutboxRepo.Relay", "error marking messages sent", "error", err)
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.lg.Error("OutboxRepo.Relay", "error committing transaction", "error", err)
+		return 0, err
+	}
+	return len(msgs), nil
+}
+
+func (r *OutboxRepo) Cleanup(ctx context.Context, before time.Time) (int64, error) {
+	query, args, err := r.builder.
+		Delete("outbox").
+		Where(squirrel.NotEq{"sent_at": nil}).
+		Where(squirrel.Lt{"sent_at": before}).
+		ToSql()
+	if err != nil {
+		r.lg.Error("OutboxRepo.Cleanup", "error building query", "error", err)
+		return 0, err
+	}
+
+	tag, err := r.pgxPool.Exec(ctx, query, args...)
+	if err != nil {
+		r.lg.Error("OutboxRepo.Cleanup", "error exec query", "error", err)
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostGISIncidentRepo работает с той же таблицей incidents, но попадание точки в зону
+// проверяет на стороне БД по колонке zone (geography + GiST индекс).
+// Круг хранится в zone многоугольником, поэтому расстояние в запросах постоянное и индекс применим
+type PostGISIncidentRepo struct {
+	*IncidentRepo
+}
+
+func NewPostGISIncidentRepo(pgxPool *pgxpool.Pool, lg *logger.Logger) *PostGISIncidentRepo {
+	return &PostGISIncidentRepo{
+		IncidentRepo: NewIncidentRepo(pgxPool, lg),
+	}
+}
+
+// FindContaining возвращает активные инциденты, в зону которых попадает точка
+func (r *PostGISIncidentRepo) FindContaining(ctx context.Context, lat, lng float64) ([]*incident.Incident, error) {
+	return r.FindWithin(ctx, lat, lng, 0)
+}
+
+// FindWithin возвращает активные инциденты, зона которых ближе meters к точке
+func (r *PostGISIncidentRepo) FindWithin(ctx context.Context, lat, lng, meters float64) ([]*incident.Incident, error) {
+	return r.findActive(ctx, "PostGISIncidentRepo.FindWithin", squirrel.Expr(
+		"ST_DWithin(zone, ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, ?)",
+		lng, lat, meters,
+	))
+}
+
+// FindCrossing возвращает активные инциденты, зону которых пересекает отрезок пути (дуга большого круга)
+func (r *PostGISIncidentRepo) FindCrossing(ctx context.Context, lat1, lng1, lat2, lng2 float64) ([]*incident.Incident, error) {
+	return r.findActive(ctx, "PostGISIncidentRepo.FindCrossing", squirrel.Expr(
+		"ST_Intersects(zone, ST_SetSRID(ST_MakeLine(ST_MakePoint(?, ?), ST_MakePoint(?, ?)), 4326)::geography)",
+		lng1, lat1, lng2, lat2,
+	))
+}
+
+func (r *PostGISIncidentRepo) findActive(ctx context.Context, op string, zoneCond squirrel.Sqlizer) ([]*incident.Incident, error) {
+	now := time.Now()
+	query, args, err := r.builder.
+		Select(incidentColumns...).
+		From("incidents").
+		Where(activeWindowCond(now)).
+		Where(zoneCond).
+		OrderBy("created_at DESC").
+		ToSql()
+	if err != nil {
+		r.lg.Error(op, "error building query", "error", err)
+		return nil, err
+	}
+
+	rows, err := r.pgxPool.Query(ctx, query, args...)
+	if err != nil {
+		r.lg.Error(op, "error executing query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []*incident.Incident
+	for rows.Next() {
+		i, err := scanIncident(rows)
+		if err != nil {
+			r.lg.Error(op, "error scanning row", "error", err)
+			return nil, err
+		}
+		incidents = append(incidents, i)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.lg.Error(op, "rows error", "error", err)
+		return nil, err
+	}
+
+	return incident.ActiveAt(incidents, now), nil
+}
@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/Soujuruya/01_SPEC/internal/domain/subscription"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var subscriptionColumns = []string{
+	"id", "url", "secret", "previous_secret", "previous_secret_expires_at", "event_types", "incident_ids", "categories", "min_severity", "area",
+	"payload_format", "is_active", "created_at", "updated_at",
+}
+
+type SubscriptionRepo struct {
+	pgxPool *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+	lg      *logger.Logger
+}
+
+func NewSubscriptionRepo(pgxPool *pgxpool.Pool, lg *logger.Logger) *SubscriptionRepo {
+	return &SubscriptionRepo{
+		pgxPool: pgxPool,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		lg:      lg,
+	}
+}
+
+// scanSubscription читает строку в порядке subscriptionColumns
+func scanSubscription(row pgx.Row) (*subscription.Subscription, error) {
+	s := &subscription.Subscription{}
+	var minSeverity, previousSecret *string
+	if err := row.Scan(
+		&s.ID, &s.URL, &s.Secret, &previousSecret, &s.PreviousSecretExpiresAt, &s.EventTypes, &s.IncidentIDs, &s.Categories, &minSeverity, &s.Area,
+		&s.PayloadFormat, &s.IsActive, &s.CreatedAt, &s.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if minSeverity != nil {
+		s.MinSeverity = *minSeverity
+	}
+	if previousSecret != nil {
+		s.PreviousSecret = *previousSecret
+	}
+	return s, nil
+}
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func (r *SubscriptionRepo) Create(ctx context.Context, s *subscription.Subscription) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now()
+	}
+	s.UpdatedAt = s.CreatedAt
+
+	query, args, err := r.builder.
+		Insert("subscriptions").
+		Columns(subscriptionColumns...).
+		Values(
+			s.ID, s.URL, s.Secret, nullString(s.PreviousSecret), s.PreviousSecretExpiresAt, s.EventTypes, s.IncidentIDs, s.Categories, nullString(s.MinSeverity), s.Area,
+			s.PayloadFormat, s.IsActive, s.CreatedAt, s.UpdatedAt,
+		).
+		ToSql()
+	if err != nil {
+		r.lg.Error("SubscriptionRepo.Create", "error building query", "error", err)
+		return err
+	}
+
+	_, err = r.pgxPool.Exec(ctx, query, args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			r.lg.Error("SubscriptionRepo.Create", "duplicate entry", "error", errs.ErrDuplicate)
+			return errs.ErrDuplicate
+		}
+		r.lg.Error("SubscriptionRepo.Create", "error exec query", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *SubscriptionRepo) GetByID(ctx context.Context, id uuid.UUID) (*subscription.Subscription, error) {
+	query, args, err := r.builder.
+		Select(subscriptionColumns...).
+		From("subscriptions").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		r.lg.Error("SubscriptionRepo.GetByID", "error building query", "error", err)
+		return nil, err
+	}
+
+	s, err := scanSubscription(r.pgxPool.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errs.ErrNotFound
+		}
+		r.lg.Error("SubscriptionRepo.GetByID", "error scanning row", "id", id, "error", err)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (r *SubscriptionRepo) Update(ctx context.Context, s *subscription.Subscription) error {
+	s.UpdatedAt = time.Now()
+
+	query, args, err := r.builder.
+		Update("subscriptions").
+		Set("url", s.URL).
+		Set("secret", s.Secret).
+		Set("previous_secret", nullString(s.PreviousSecret)).
+		Set("previous_secret_expires_at", s.PreviousSecretExpiresAt).
+		Set("event_types", s.EventTypes).
+		Set("incident_ids", s.IncidentIDs).
+		Set("categories", s.Categories).
+		Set("min_severity", nullString(s.MinSeverity)).
+		Set("area", s.Area).
+		Set("payload_format", s.PayloadFormat).
+		Set("is_active", s.IsActive).
+		Set("updated_at", s.UpdatedAt).
+		Where(squirrel.Eq{"id": s.ID}).
+		ToSql()
+	if err != nil {
+		r.lg.Error("SubscriptionRepo.Update", "error building query", "error", err)
+		return err
+	}
+
+	res, err := r.pgxPool.Exec(ctx, query, args...)
+	if err != nil {
+		r.lg.Error("SubscriptionRepo.Update", "error exec query", "id", s.ID, "error", err)
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *SubscriptionRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	query, args, err := r.builder.
+		Delete("subscriptions").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		r.lg.Error("SubscriptionRepo.Delete", "error building query", "error", err)
+		return err
+	}
+
+	res, err := r.pgxPool.Exec(ctx, query, args...)
+	if err != nil {
+		r.lg.Error("SubscriptionRepo.Delete", "error exec query", "id", id, "error", err)
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *SubscriptionRepo) List(ctx context.Context) ([]*subscription.Subscription, error) {
+	return r.list(ctx, "SubscriptionRepo.List", squirrel.And{})
+}
+
+func (r *SubscriptionRepo) ListActive(ctx context.Context) ([]*subscription.Subscription, error) {
+	return r.list(ctx, "SubscriptionRepo.ListActive", squirrel.Eq{"is_active": true})
+}
+
+func (r *SubscriptionRepo) list(ctx context.Context, op string, cond squirrel.Sqlizer) ([]*subscription.Subscription, error) {
+	query, args, err := r.builder.
+		Select(subscriptionColumns...).
+		From("subscriptions").
+		Where(cond).
+		OrderBy("created_at DESC").
+		ToSql()
+	if err != nil {
+		r.lg.Error(op, "error building query", "error", err)
+		return nil, err
+	}
+
+	rows, err := r.pgxPool.Query(ctx, query, args...)
+	if err != nil {
+		r.lg.Error(op, "error executing query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []*subscription.Subscription{}
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			r.lg.Error(op, "error scanning row", "error", err)
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.lg.Error(op, "rows error", "error", err)
+		return nil, err
+	}
+
+	return subs, nil
+}
@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/location"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// CooldownStore хранит окно повтора ключом <prefix>:<user_id>:<event_type>:<incident_id>,
+// который истекает вместе с окном
+type CooldownStore struct {
+	rdb    *redis.Client
+	prefix string
+	lg     *logger.Logger
+}
+
+func NewCooldownStore(rdb *redis.Client, prefix string, lg *logger.Logger) *CooldownStore {
+	return &CooldownStore{
+		rdb:    rdb,
+		prefix: prefix,
+		lg:     lg,
+	}
+}
+
+func (s *CooldownStore) key(userID uuid.UUID, k location.CooldownKey) string {
+	return s.prefix + ":" + userID.String() + ":" + k.EventType + ":" + k.IncidentID.String()
+}
+
+func (s *CooldownStore) Active(ctx context.Context, userID uuid.UUID, keys []location.CooldownKey) (map[location.CooldownKey]time.Time, error) {
+	active := make(map[location.CooldownKey]time.Time)
+	if len(keys) == 0 {
+		return active, nil
+	}
+
+	cmds := make([]*redis.DurationCmd, len(keys))
+	_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, k := range keys {
+			cmds[i] = pipe.PTTL(ctx, s.key(userID, k))
+		}
+		return nil
+	})
+	if err != nil {
+		s.lg.Error("CooldownStore.Active: failed to read cooldowns", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	for i, cmd := range cmds {
+		// -2 — ключа нет, -1 — ключ без TTL (не создаётся этим хранилищем)
+		if ttl := cmd.Val(); ttl > 0 {
+			active[keys[i]] = now.Add(ttl)
+		}
+	}
+	return active, nil
+}
+
+func (s *CooldownStore) Start(ctx context.Context, userID uuid.UUID, until map[location.CooldownKey]time.Time) error {
+	now := time.Now()
+	_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for k, at := range until {
+			// окна точек, присланных с опозданием, могли уже истечь
+			if ttl := at.Sub(now); ttl > 0 {
+				pipe.Set(ctx, s.key(userID, k), now.Unix(), ttl)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.lg.Error("CooldownStore.Start: failed to write cooldowns", "user_id", userID, "error", err)
+		return err
+	}
+
+	s.lg.Debug("CooldownStore.Start: cooldowns started", "user_id", userID, "count", len(until))
+	return nil
+}
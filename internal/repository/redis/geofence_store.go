@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/location"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// GeofenceStore хранит состояние пользователя в hash <prefix>:<user_id>, поле — id инцидента
+type GeofenceStore struct {
+	rdb    *redis.Client
+	prefix string
+	ttl    time.Duration
+	lg     *logger.Logger
+}
+
+func NewGeofenceStore(rdb *redis.Client, prefix string, ttl time.Duration, lg *logger.Logger) *GeofenceStore {
+	return &GeofenceStore{
+		rdb:    rdb,
+		prefix: prefix,
+		ttl:    ttl,
+		lg:     lg,
+	}
+}
+
+func (s *GeofenceStore) key(userID uuid.UUID) string {
+	return s.prefix + ":" + userID.String()
+}
+
+func (s *GeofenceStore) Get(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]location.GeofenceState, error) {
+	fields, err := s.rdb.HGetAll(ctx, s.key(userID)).Result()
+	if err != nil {
+		s.lg.Error("GeofenceStore.Get: failed to read state", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	states := make(map[uuid.UUID]location.GeofenceState, len(fields))
+	for field, value := range fields {
+		id, err := uuid.Parse(field)
+		if err != nil {
+			s.lg.Warn("GeofenceStore.Get: skipping invalid incident id", "user_id", userID, "field", field)
+			continue
+		}
+
+		var state location.GeofenceState
+		if err := json.Unmarshal([]byte(value), &state); err != nil {
+			s.lg.Warn("GeofenceStore.Get: skipping invalid state", "user_id", userID, "incident_id", id, "error", err)
+			continue
+		}
+		states[id] = state
+	}
+
+	return states, nil
+}
+
+func (s *GeofenceStore) Save(ctx context.Context, userID uuid.UUID, states map[uuid.UUID]location.GeofenceState) error {
+	key := s.key(userID)
+
+	values := make(map[string]any, len(states))
+	for id, state := range states {
+		data, err := json.Marshal(state)
+		if err != nil {
+			s.lg.Error("GeofenceStore.Save: failed to marshal state", "user_id", userID, "incident_id", id, "error", err)
+			return err
+		}
+		values[id.String()] = data
+	}
+
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		if len(values) > 0 {
+			pipe.HSet(ctx, key, values)
+			pipe.Expire(ctx, key, s.ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		s.lg.Error("GeofenceStore.Save: failed to write state", "user_id", userID, "error", err)
+		return err
+	}
+
+	s.lg.Debug("GeofenceStore.Save: state saved", "user_id", userID, "zones", len(states))
+	return nil
+}
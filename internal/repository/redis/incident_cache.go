@@ -8,6 +8,7 @@ import (
 
 	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/metrics"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -36,18 +37,22 @@ func (c *IncidentCache) GetActive(ctx context.Context) ([]*incident.Incident, er
 	data, err := c.rdb.Get(ctx, c.key).Bytes()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
+			metrics.IncidentCacheRequests.WithLabelValues(metrics.ResultMiss).Inc()
 			c.lg.Debug("GetActive: cache miss", "key", c.key)
 			return nil, err
 		}
+		metrics.IncidentCacheRequests.WithLabelValues(metrics.ResultError).Inc()
 		c.lg.Error("GetActive: failed to get from Redis", "key", c.key, "error", err)
 		return nil, err
 	}
 
 	var incs []*incident.Incident
 	if err := json.Unmarshal(data, &incs); err != nil {
+		metrics.IncidentCacheRequests.WithLabelValues(metrics.ResultError).Inc()
 		c.lg.Error("GetActive: failed to unmarshal cached data", "key", c.key, "error", err)
 		return nil, err
 	}
+	metrics.IncidentCacheRequests.WithLabelValues(metrics.ResultHit).Inc()
 
 	c.lg.Debug("GetActive: cache hit", "key", c.key, "count", len(incs))
 	return incs, nil
@@ -5,47 +5,66 @@ import (
 	"encoding/json"
 	"time"
 
-	"github.com/Soujuruya/01_SPEC/internal/domain/location"
-	"github.com/Soujuruya/01_SPEC/internal/integration"
+	"github.com/Soujuruya/01_SPEC/internal/domain/outbox"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
 	"github.com/redis/go-redis/v9"
 )
 
+// publishScript кладёт сообщение outbox в очередь, только если оно ещё не публиковалось.
+// KEYS[1] — ключ отметки о публикации, KEYS[2] — очередь; ARGV[1] — событие, ARGV[2] — TTL отметки в мс
+var publishScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], 1, 'NX', 'PX', tonumber(ARGV[2])) then
+	redis.call('LPUSH', KEYS[2], ARGV[1])
+	return 1
+end
+return 0
+`)
+
 type WebhookQueue struct {
-	rdb *redis.Client
-	key string
-	lg  *logger.Logger
+	rdb      *redis.Client
+	key      string
+	dedupTTL time.Duration
+	lg       *logger.Logger
 }
 
-func NewWebhookQueue(rdb *redis.Client, key string, lg *logger.Logger) *WebhookQueue {
+// NewWebhookQueue dedupTTL — сколько помнить опубликованные сообщения outbox
+func NewWebhookQueue(rdb *redis.Client, key string, dedupTTL time.Duration, lg *logger.Logger) *WebhookQueue {
 	return &WebhookQueue{
-		rdb: rdb,
-		key: key,
-		lg:  lg,
+		rdb:      rdb,
+		key:      key,
+		dedupTTL: dedupTTL,
+		lg:       lg,
 	}
 }
 
-func (q *WebhookQueue) Enqueue(ctx context.Context, loc *location.Location) error {
-	payload := integration.WebhookPayload{
-		UserID:      loc.UserID,
-		Lat:         loc.Lat,
-		Lng:         loc.Lng,
-		IncidentIDs: loc.IncidentIDs,
-		Timestamp:   time.Now().Unix(),
-		Retry:       0,
+// Publish перекладывает сообщения outbox в очередь. Если relay упал после публикации, но до отметки
+// в базе, те же сообщения придут повторно и будут пропущены по отметке с их ID
+func (q *WebhookQueue) Publish(ctx context.Context, msgs []outbox.Message) error {
+	pipe := q.rdb.Pipeline()
+	cmds := make([]*redis.Cmd, len(msgs))
+	for i, m := range msgs {
+		cmds[i] = publishScript.Eval(ctx, pipe, []string{q.key + ":sent:" + m.ID.String(), q.key}, []byte(m.Payload), q.dedupTTL.Milliseconds())
 	}
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		q.lg.Error("WebhookQueue.Enqueue: failed to marshal payload", "user_id", loc.UserID, "error", err)
+	if _, err := pipe.Exec(ctx); err != nil {
+		q.lg.Error("WebhookQueue.Publish: failed to push to Redis queue", "key", q.key, "count", len(msgs), "error", err)
 		return err
 	}
 
-	if err := q.rdb.LPush(ctx, q.key, data).Err(); err != nil {
-		q.lg.Error("WebhookQueue.Enqueue: failed to push to Redis queue", "key", q.key, "user_id", loc.UserID, "error", err)
-		return err
+	published := 0
+	for _, cmd := range cmds {
+		if n, _ := cmd.Int(); n == 1 {
+			published++
+		}
 	}
+	q.lg.Debug("WebhookQueue.Publish: outbox messages published", "key", q.key, "count", len(msgs), "published", published)
+	return nil
+}
 
-	q.lg.Debug("WebhookQueue.Enqueue: task enqueued successfully", "key", q.key, "user_id", loc.UserID, "incident_count", len(loc.IncidentIDs))
+// Push кладёт в очередь уже собранное событие, например при повторной отправке из dead letters
+func (q *WebhookQueue) Push(ctx context.Context, payload json.RawMessage) error {
+	if err := q.rdb.LPush(ctx, q.key, []byte(payload)).Err(); err != nil {
+		q.lg.Error("WebhookQueue.Push: failed to push to Redis queue", "key", q.key, "error", err)
+		return err
+	}
 	return nil
 }
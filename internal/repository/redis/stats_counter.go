@@ -0,0 +1,260 @@
+package redis
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/location"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// hitsTotal поле hash попаданий с числом всех проверок с попаданием за минуту
+const hitsTotal = "total"
+
+// pfMergeChunk сколько ключей объединяется одной командой PFMERGE
+const pfMergeChunk = 500
+
+// StatsCounter счётчики попаданий по минутам:
+//
+//	<prefix>:users:<minute>              — HyperLogLog пользователей, попавших хотя бы в одну зону
+//	<prefix>:users:<minute>:<incident>   — HyperLogLog пользователей, попавших в зону
+//	<prefix>:hits:<minute>               — hash: total и число попаданий по каждой зоне
+//	<prefix>:titles                      — hash: названия зон для разбивки
+//
+// <minute> — unix-время начала минуты. Ключи минуты истекают через retention после её начала
+type StatsCounter struct {
+	rdb       *redis.Client
+	prefix    string
+	retention time.Duration
+	lg        *logger.Logger
+}
+
+func NewStatsCounter(rdb *redis.Client, prefix string, retention time.Duration, lg *logger.Logger) *StatsCounter {
+	return &StatsCounter{
+		rdb:       rdb,
+		prefix:    prefix,
+		retention: retention,
+		lg:        lg,
+	}
+}
+
+func (c *StatsCounter) usersKey(minute time.Time) string {
+	return c.prefix + ":users:" + strconv.FormatInt(minute.Unix(), 10)
+}
+
+func (c *StatsCounter) incidentUsersKey(minute time.Time, id uuid.UUID) string {
+	return c.usersKey(minute) + ":" + id.String()
+}
+
+func (c *StatsCounter) hitsKey(minute time.Time) string {
+	return c.prefix + ":hits:" + strconv.FormatInt(minute.Unix(), 10)
+}
+
+func (c *StatsCounter) titlesKey() string { return c.prefix + ":titles" }
+
+func (c *StatsCounter) Record(ctx context.Context, locs []*location.Location) error {
+	now := time.Now()
+	recorded := 0
+	_, err := c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		titles := make(map[string]any)
+		for _, loc := range locs {
+			minute := loc.Timestamp.Truncate(time.Minute)
+			expireAt := minute.Add(c.retention)
+			// точки, присланные с опозданием, могут быть старше хранимых минут
+			if !loc.HasIncidents() || !expireAt.After(now) {
+				continue
+			}
+
+			user := loc.UserID.String()
+			usersKey, hitsKey := c.usersKey(minute), c.hitsKey(minute)
+			pipe.PFAdd(ctx, usersKey, user)
+			pipe.ExpireAt(ctx, usersKey, expireAt)
+			pipe.HIncrBy(ctx, hitsKey, hitsTotal, 1)
+			for _, id := range loc.IncidentIDs {
+				key := c.incidentUsersKey(minute, id)
+				pipe.PFAdd(ctx, key, user)
+				pipe.ExpireAt(ctx, key, expireAt)
+				pipe.HIncrBy(ctx, hitsKey, id.String(), 1)
+			}
+			pipe.ExpireAt(ctx, hitsKey, expireAt)
+
+			for _, info := range loc.Incidents {
+				titles[info.IncidentID.String()] = info.Title
+			}
+			recorded++
+		}
+		if len(titles) > 0 {
+			pipe.HSet(ctx, c.titlesKey(), titles)
+			pipe.Expire(ctx, c.titlesKey(), c.retention)
+		}
+		return nil
+	})
+	if err != nil {
+		c.lg.Error("StatsCounter.Record: failed to update counters", "error", err, "count", len(locs))
+		return err
+	}
+
+	c.lg.Debug("StatsCounter.Record: counters updated", "recorded", recorded)
+	return nil
+}
+
+func (c *StatsCounter) Stats(ctx context.Context, from, to time.Time, bucket time.Duration) (*location.Stats, error) {
+	var minutes []time.Time
+	for m := from; m.Before(to); m = m.Add(time.Minute) {
+		minutes = append(minutes, m)
+	}
+
+	hitCmds := make([]*redis.MapStringStringCmd, len(minutes))
+	_, err := c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, m := range minutes {
+			hitCmds[i] = pipe.HGetAll(ctx, c.hitsKey(m))
+		}
+		return nil
+	})
+	if err != nil {
+		c.lg.Error("StatsCounter.Stats: failed to read hits", "error", err)
+		return nil, err
+	}
+
+	// минуты без попаданий не имеют ключей HyperLogLog, их незачем объединять
+	var userKeys []string
+	bucketKeys := make(map[int][]string)
+	series := make(map[int]*location.StatsBucket)
+	incidents := make(map[uuid.UUID]*location.IncidentStats)
+	incidentKeys := make(map[uuid.UUID][]string)
+	for i, cmd := range hitCmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue
+		}
+		m := minutes[i]
+		userKeys = append(userKeys, c.usersKey(m))
+
+		n := int(m.Sub(from) / bucket)
+		bucketKeys[n] = append(bucketKeys[n], c.usersKey(m))
+		b, ok := series[n]
+		if !ok {
+			b = &location.StatsBucket{Start: from.Add(time.Duration(n) * bucket)}
+			series[n] = b
+		}
+
+		for field, value := range fields {
+			hits, _ := strconv.Atoi(value)
+			if field == hitsTotal {
+				b.HitCount += hits
+				continue
+			}
+			id, err := uuid.Parse(field)
+			if err != nil {
+				continue
+			}
+			st, ok := incidents[id]
+			if !ok {
+				st = &location.IncidentStats{IncidentID: id}
+				incidents[id] = st
+			}
+			st.HitCount += hits
+			incidentKeys[id] = append(incidentKeys[id], c.incidentUsersKey(m, id))
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(incidents))
+	fields := make([]string, 0, len(incidents))
+	for id := range incidents {
+		ids = append(ids, id)
+		fields = append(fields, id.String())
+	}
+
+	var (
+		totalCmd    *redis.IntCmd
+		titlesCmd   *redis.SliceCmd
+		incidentCmd = make(map[uuid.UUID]*redis.IntCmd, len(incidents))
+		bucketCmd   = make(map[int]*redis.IntCmd, len(bucketKeys))
+	)
+	_, err = c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		totalCmd = c.union(ctx, pipe, userKeys)
+		for id, keys := range incidentKeys {
+			incidentCmd[id] = c.union(ctx, pipe, keys)
+		}
+		for n, keys := range bucketKeys {
+			bucketCmd[n] = pipe.PFCount(ctx, keys...)
+		}
+		if len(fields) > 0 {
+			titlesCmd = pipe.HMGet(ctx, c.titlesKey(), fields...)
+		}
+		return nil
+	})
+	if err != nil {
+		c.lg.Error("StatsCounter.Stats: failed to count users", "error", err)
+		return nil, err
+	}
+
+	stats := &location.Stats{
+		From:      from,
+		To:        to,
+		Bucket:    bucket,
+		UserCount: intVal(totalCmd),
+		Incidents: make([]location.IncidentStats, 0, len(incidents)),
+		Series:    make([]location.StatsBucket, 0, len(series)),
+	}
+
+	var titles []any
+	if titlesCmd != nil {
+		titles = titlesCmd.Val()
+	}
+	for i, id := range ids {
+		st := incidents[id]
+		st.UserCount = intVal(incidentCmd[id])
+		if i < len(titles) {
+			st.Title, _ = titles[i].(string)
+		}
+		stats.Incidents = append(stats.Incidents, *st)
+	}
+	sort.Slice(stats.Incidents, func(i, j int) bool {
+		a, b := stats.Incidents[i], stats.Incidents[j]
+		if a.UserCount != b.UserCount {
+			return a.UserCount > b.UserCount
+		}
+		if a.HitCount != b.HitCount {
+			return a.HitCount > b.HitCount
+		}
+		return a.IncidentID.String() < b.IncidentID.String()
+	})
+
+	for n, b := range series {
+		b.UserCount = intVal(bucketCmd[n])
+		stats.Series = append(stats.Series, *b)
+	}
+	sort.Slice(stats.Series, func(i, j int) bool { return stats.Series[i].Start.Before(stats.Series[j].Start) })
+
+	c.lg.Debug("StatsCounter.Stats: stats counted", "user_count", stats.UserCount, "incidents", len(stats.Incidents), "minutes", len(userKeys))
+	return stats, nil
+}
+
+// union ставит в pipe оценку числа пользователей в объединении keys: PFMERGE во временный ключ, PFCOUNT и удаление.
+// nil — объединять нечего
+func (c *StatsCounter) union(ctx context.Context, pipe redis.Pipeliner, keys []string) *redis.IntCmd {
+	if len(keys) == 0 {
+		return nil
+	}
+	tmp := c.prefix + ":tmp:" + uuid.NewString()
+	for i := 0; i < len(keys); i += pfMergeChunk {
+		pipe.PFMerge(ctx, tmp, keys[i:min(i+pfMergeChunk, len(keys))]...)
+	}
+	// если pipeline оборвётся до DEL, временный ключ не останется навсегда
+	pipe.Expire(ctx, tmp, time.Minute)
+	count := pipe.PFCount(ctx, tmp)
+	pipe.Del(ctx, tmp)
+	return count
+}
+
+func intVal(cmd *redis.IntCmd) int {
+	if cmd == nil {
+		return 0
+	}
+	return int(cmd.Val())
+}
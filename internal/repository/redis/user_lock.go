@@ -0,0 +1,78 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// unlockScript снимает блокировку, только если её держит тот же владелец: после истечения lease
+// блокировку мог взять другой запрос. KEYS[1] — ключ блокировки; ARGV[1] — токен владельца
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+const (
+	lockRetryMin = 5 * time.Millisecond
+	lockRetryMax = 100 * time.Millisecond
+)
+
+// UserLock блокировка пользователя ключом <prefix>:<user_id>. Ключ истекает через lease, поэтому
+// упавший инстанс не держит пользователя дольше lease; столько же Lock ждёт освобождения
+type UserLock struct {
+	rdb    *redis.Client
+	prefix string
+	lease  time.Duration
+	lg     *logger.Logger
+}
+
+func NewUserLock(rdb *redis.Client, prefix string, lease time.Duration, lg *logger.Logger) *UserLock {
+	return &UserLock{
+		rdb:    rdb,
+		prefix: prefix,
+		lease:  lease,
+		lg:     lg,
+	}
+}
+
+func (l *UserLock) Lock(ctx context.Context, userID uuid.UUID) (func(), error) {
+	key := l.prefix + ":" + userID.String()
+	token := uuid.NewString()
+	deadline := time.Now().Add(l.lease)
+
+	for wait := lockRetryMin; ; wait = min(wait*2, lockRetryMax) {
+		ok, err := l.rdb.SetNX(ctx, key, token, l.lease).Result()
+		if err != nil {
+			l.lg.Error("UserLock.Lock: failed to acquire lock", "user_id", userID, "error", err)
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if time.Now().Add(wait).After(deadline) {
+			l.lg.Warn("UserLock.Lock: lock is held too long", "user_id", userID, "lease", l.lease)
+			return nil, errs.ErrLocked
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	unlock := func() {
+		// отменённый запрос всё равно должен освободить пользователя
+		if err := unlockScript.Run(context.WithoutCancel(ctx), l.rdb, []string{key}, token).Err(); err != nil {
+			l.lg.Error("UserLock.Unlock: failed to release lock", "user_id", userID, "error", err)
+		}
+	}
+	return unlock, nil
+}
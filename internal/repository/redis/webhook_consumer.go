@@ -0,0 +1,257 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// promoteScript атомарно переносит наступившие повторы из отложенного набора в очередь.
+// KEYS[1] — набор, KEYS[2] — очередь, ARGV[1] — текущее время в мс, ARGV[2] — предел за вызов
+var promoteScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, item in ipairs(due) do
+	redis.call('ZREM', KEYS[1], item)
+	redis.call('LPUSH', KEYS[2], item)
+end
+return #due
+`)
+
+// reclaimScript возвращает в начало очереди всё из списка обработки потребителя без heartbeat.
+// KEYS[1] — список обработки, KEYS[2] — очередь, KEYS[3] — множество потребителей, KEYS[4] — heartbeat;
+// ARGV[1] — имя потребителя. -1 — потребитель успел ожить
+var reclaimScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[4]) == 1 then
+	return -1
+end
+local n = 0
+while redis.call('LMOVE', KEYS[1], KEYS[2], 'RIGHT', 'RIGHT') do
+	n = n + 1
+end
+redis.call('SREM', KEYS[3], ARGV[1])
+return n
+`)
+
+const promoteBatch = 100
+
+// WebhookConsumer надёжное чтение очереди вебхуков: BLMOVE переносит событие в личный список
+// обработки потребителя, и оно удаляется оттуда только после подтверждения (Ack). Живость
+// потребителя подтверждается ключом heartbeat с TTL visibility; списки обработки потребителей,
+// у которых heartbeat истёк, возвращаются в очередь (Reclaim).
+// Неудачные доставки откладываются в sorted set со временем следующей попытки
+type WebhookConsumer struct {
+	rdb        *redis.Client
+	key        string
+	retryKey   string
+	name       string
+	visibility time.Duration
+	dedupTTL   time.Duration
+	lg         *logger.Logger
+}
+
+// NewWebhookConsumer dedupTTL — сколько помнить доставленные события, чтобы не отправить их повторно
+func NewWebhookConsumer(rdb *redis.Client, key, retryKey string, visibility, dedupTTL time.Duration, lg *logger.Logger) *WebhookConsumer {
+	host, _ := os.Hostname()
+	return &WebhookConsumer{
+		rdb:        rdb,
+		key:        key,
+		retryKey:   retryKey,
+		name:       fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8]),
+		visibility: visibility,
+		dedupTTL:   dedupTTL,
+		lg:         lg,
+	}
+}
+
+func (c *WebhookConsumer) Name() string { return c.name }
+
+// Visibility через сколько без heartbeat события потребителя возвращаются в очередь
+func (c *WebhookConsumer) Visibility() time.Duration { return c.visibility }
+
+func (c *WebhookConsumer) consumersKey() string { return c.key + ":consumers" }
+
+func (c *WebhookConsumer) processingKey(name string) string { return c.key + ":processing:" + name }
+
+func (c *WebhookConsumer) heartbeatKey(name string) string { return c.key + ":heartbeat:" + name }
+
+// Heartbeat регистрирует потребителя и продлевает его heartbeat
+func (c *WebhookConsumer) Heartbeat(ctx context.Context) error {
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, c.consumersKey(), c.name)
+		pipe.Set(ctx, c.heartbeatKey(c.name), time.Now().Unix(), c.visibility)
+		return nil
+	})
+	if err != nil {
+		c.lg.Error("WebhookConsumer.Heartbeat: failed", "consumer", c.name, "error", err)
+	}
+	return err
+}
+
+// Fetch ждёт событие не дольше wait и переносит его в список обработки. "" — очередь пуста
+func (c *WebhookConsumer) Fetch(ctx context.Context, wait time.Duration) (string, error) {
+	data, err := c.rdb.BLMove(ctx, c.key, c.processingKey(c.name), "RIGHT", "LEFT", wait).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return data, err
+}
+
+// Ack подтверждает обработку события
+func (c *WebhookConsumer) Ack(ctx context.Context, raw string) error {
+	if err := c.rdb.LRem(ctx, c.processingKey(c.name), 1, raw).Err(); err != nil {
+		c.lg.Error("WebhookConsumer.Ack: failed", "consumer", c.name, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (c *WebhookConsumer) deliveredKey(delivery string) string {
+	return c.key + ":delivered:" + delivery
+}
+
+// Delivered событие уже доставлено получателю; delivery — ключ пары событие+получатель
+func (c *WebhookConsumer) Delivered(ctx context.Context, delivery string) (bool, error) {
+	n, err := c.rdb.Exists(ctx, c.deliveredKey(delivery)).Result()
+	if err != nil {
+		c.lg.Error("WebhookConsumer.Delivered: failed", "consumer", c.name, "error", err)
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// AckDelivered подтверждает событие и одновременно запоминает, что оно доставлено
+func (c *WebhookConsumer) AckDelivered(ctx context.Context, raw, delivery string) error {
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, c.deliveredKey(delivery), time.Now().Unix(), c.dedupTTL)
+		pipe.LRem(ctx, c.processingKey(c.name), 1, raw)
+		return nil
+	})
+	if err != nil {
+		c.lg.Error("WebhookConsumer.AckDelivered: failed", "consumer", c.name, "error", err)
+	}
+	return err
+}
+
+// Requeue подтверждает событие и одновременно ставит в очередь items (например, копии для подписчиков)
+func (c *WebhookConsumer) Requeue(ctx context.Context, raw string, items ...[]byte) error {
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, item := range items {
+			pipe.LPush(ctx, c.key, item)
+		}
+		pipe.LRem(ctx, c.processingKey(c.name), 1, raw)
+		return nil
+	})
+	if err != nil {
+		c.lg.Error("WebhookConsumer.Requeue: failed", "consumer", c.name, "error", err)
+	}
+	return err
+}
+
+// Defer подтверждает событие и одновременно откладывает его новую версию data до момента at
+func (c *WebhookConsumer) Defer(ctx context.Context, raw string, data []byte, at time.Time) error {
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, c.retryKey, redis.Z{Score: float64(at.UnixMilli()), Member: data})
+		pipe.LRem(ctx, c.processingKey(c.name), 1, raw)
+		return nil
+	})
+	if err != nil {
+		c.lg.Error("WebhookConsumer.Defer: failed", "consumer", c.name, "error", err)
+	}
+	return err
+}
+
+// Promote возвращает в очередь отложенные события, время которых наступило
+func (c *WebhookConsumer) Promote(ctx context.Context, now time.Time) (int, error) {
+	total := 0
+	for {
+		n, err := promoteScript.Run(ctx, c.rdb, []string{c.retryKey, c.key},
+			strconv.FormatInt(now.UnixMilli(), 10), promoteBatch).Int()
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < promoteBatch {
+			return total, nil
+		}
+	}
+}
+
+// Reclaim возвращает в очередь события потребителей, переставших присылать heartbeat
+func (c *WebhookConsumer) Reclaim(ctx context.Context) (int, error) {
+	names, err := c.rdb.SMembers(ctx, c.consumersKey()).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, name := range names {
+		if name == c.name {
+			continue
+		}
+		n, err := reclaimScript.Run(ctx, c.rdb,
+			[]string{c.processingKey(name), c.key, c.consumersKey(), c.heartbeatKey(name)}, name).Int()
+		if err != nil {
+			return total, err
+		}
+		if n > 0 {
+			c.lg.Warn("WebhookConsumer.Reclaim: returned stuck webhooks to queue", "dead_consumer", name, "count", n)
+			total += n
+		}
+	}
+	return total, nil
+}
+
+// QueueDepth число событий в очереди вебхуков
+type QueueDepth struct {
+	Pending    int64 // ждут отправки
+	Retry      int64 // отложены до следующей попытки
+	Processing int64 // забраны потребителями и ещё не подтверждены
+}
+
+// Depth размер очереди, отложенных повторов и списков обработки всех потребителей
+func (c *WebhookConsumer) Depth(ctx context.Context) (QueueDepth, error) {
+	var pending, retry *redis.IntCmd
+	var names *redis.StringSliceCmd
+	_, err := c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pending = pipe.LLen(ctx, c.key)
+		retry = pipe.ZCard(ctx, c.retryKey)
+		names = pipe.SMembers(ctx, c.consumersKey())
+		return nil
+	})
+	if err != nil {
+		return QueueDepth{}, err
+	}
+
+	processing := make([]*redis.IntCmd, 0, len(names.Val()))
+	_, err = c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, name := range names.Val() {
+			processing = append(processing, pipe.LLen(ctx, c.processingKey(name)))
+		}
+		return nil
+	})
+	if err != nil {
+		return QueueDepth{}, err
+	}
+
+	depth := QueueDepth{Pending: pending.Val(), Retry: retry.Val()}
+	for _, cmd := range processing {
+		depth.Processing += cmd.Val()
+	}
+	return depth, nil
+}
+
+// Close снимает регистрацию; необработанные события возвращаются в начало очереди
+func (c *WebhookConsumer) Close(ctx context.Context) error {
+	if err := c.rdb.Del(ctx, c.heartbeatKey(c.name)).Err(); err != nil {
+		return err
+	}
+	return reclaimScript.Run(ctx, c.rdb,
+		[]string{c.processingKey(c.name), c.key, c.consumersKey(), c.heartbeatKey(c.name)}, c.name).Err()
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/Soujuruya/01_SPEC/internal/config"
+	"github.com/Soujuruya/01_SPEC/internal/handler/http/deadletter"
 	"github.com/Soujuruya/01_SPEC/internal/handler/http/health"
 	"github.com/Soujuruya/01_SPEC/internal/handler/http/incident"
 	"github.com/Soujuruya/01_SPEC/internal/handler/http/location"
 	"github.com/Soujuruya/01_SPEC/internal/handler/http/stats"
+	"github.com/Soujuruya/01_SPEC/internal/handler/http/webhook"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
@@ -25,6 +29,8 @@ func NewServer(cfg *config.Config,
 	incidentHandler *incident.IncidentHandler,
 	locationHandler *location.LocationHandler,
 	statsHandler *stats.StatsHandler,
+	webhookHandler *webhook.WebhookHandler,
+	deadLetterHandler *deadletter.DeadLetterHandler,
 	middlewares ...Middleware,
 ) *Server {
 
@@ -33,6 +39,9 @@ func NewServer(cfg *config.Config,
 	//DOCS
 	mux.Handle("/swagger/", httpSwagger.WrapHandler)
 
+	// Метрики Prometheus
+	mux.Handle("/metrics", promhttp.Handler())
+
 	// Health-check
 	mux.HandleFunc("/api/v1/system/health", healthHandler.HealthCheck)
 
@@ -73,10 +82,91 @@ func NewServer(cfg *config.Config,
 
 	// Проверка локации
 	mux.HandleFunc("/api/v1/location/check", locationHandler.CheckLocation)
+	mux.HandleFunc("/api/v1/location/check/batch", locationHandler.CheckLocationBatch)
 
 	// Статистика
 	mux.HandleFunc("/api/v1/incidents/stats", statsHandler.GetIncidentsStats)
 
+	// Подписки на вебхуки
+	mux.HandleFunc("/api/v1/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			webhookHandler.ListSubscriptions(w, r)
+		case http.MethodPost:
+			webhookHandler.CreateSubscription(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/webhooks/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path[len("/api/v1/webhooks/"):] == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, webhook.RotateSecretPath) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			webhookHandler.RotateSecret(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			webhookHandler.GetSubscription(w, r)
+		case http.MethodPut, http.MethodPatch:
+			webhookHandler.UpdateSubscription(w, r)
+		case http.MethodDelete:
+			webhookHandler.DeleteSubscription(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// Недоставленные вебхуки (админка)
+	mux.HandleFunc(deadletter.BasePath, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			deadLetterHandler.ListDeadLetters(w, r)
+		case http.MethodDelete:
+			deadLetterHandler.PurgeDeadLetters(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc(deadletter.BasePath+"/", func(w http.ResponseWriter, r *http.Request) {
+		rest := r.URL.Path[len(deadletter.BasePath+"/"):]
+		if rest == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if rest == deadletter.ReplayPath[1:] || strings.HasSuffix(rest, deadletter.ReplayPath) {
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if rest == deadletter.ReplayPath[1:] {
+				deadLetterHandler.ReplayAllDeadLetters(w, r)
+			} else {
+				deadLetterHandler.ReplayDeadLetter(w, r)
+			}
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			deadLetterHandler.GetDeadLetter(w, r)
+		case http.MethodDelete:
+			deadLetterHandler.DeleteDeadLetter(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	var handler http.Handler = mux
 	for i := len(middlewares) - 1; i >= 0; i-- {
 		handler = middlewares[i](handler)
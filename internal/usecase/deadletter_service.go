@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/deadletter"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const replayBatchSize = 100
+
+// DeadLetterService хранит недоставленные вебхуки и возвращает их в очередь по запросу
+type DeadLetterService struct {
+	Repo  deadletter.DeadLetterRepository
+	Queue deadletter.Queue
+	lg    *logger.Logger
+}
+
+func NewDeadLetterService(repo deadletter.DeadLetterRepository, queue deadletter.Queue, lg *logger.Logger) *DeadLetterService {
+	return &DeadLetterService{
+		Repo:  repo,
+		Queue: queue,
+		lg:    lg,
+	}
+}
+
+func (s *DeadLetterService) Record(ctx context.Context, d *deadletter.DeadLetter) error {
+	if err := s.Repo.Create(ctx, d); err != nil {
+		s.lg.Error("DeadLetterService.Record failed", "user_id", d.UserID, "url", d.URL, "error", err)
+		return err
+	}
+	s.lg.Warn("Webhook moved to dead letters", "dead_letter_id", d.ID, "url", d.URL, "attempts", len(d.Attempts), "last_error", d.LastError)
+	return nil
+}
+
+func (s *DeadLetterService) Get(ctx context.Context, id uuid.UUID) (*deadletter.DeadLetter, error) {
+	d, err := s.Repo.GetByID(ctx, id)
+	if err != nil {
+		s.lg.Error("DeadLetterService.Get failed", "dead_letter_id", id, "error", err)
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *DeadLetterService) List(ctx context.Context, offset, limit int) ([]*deadletter.DeadLetter, int, error) {
+	letters, total, err := s.Repo.List(ctx, offset, limit)
+	if err != nil {
+		s.lg.Error("DeadLetterService.List failed", "offset", offset, "limit", limit, "error", err)
+		return nil, 0, err
+	}
+	return letters, total, nil
+}
+
+func (s *DeadLetterService) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		s.lg.Error("DeadLetterService.Delete failed", "dead_letter_id", id, "error", err)
+		return err
+	}
+	return nil
+}
+
+// Replay возвращает событие в очередь с обнулённым счётчиком попыток и удаляет запись
+func (s *DeadLetterService) Replay(ctx context.Context, id uuid.UUID) error {
+	d, err := s.Repo.GetByID(ctx, id)
+	if err != nil {
+		s.lg.Error("DeadLetterService.Replay failed to get dead letter", "dead_letter_id", id, "error", err)
+		return err
+	}
+	return s.replay(ctx, d)
+}
+
+// ReplayAll возвращает в очередь все записи и сообщает, сколько удалось вернуть
+func (s *DeadLetterService) ReplayAll(ctx context.Context) (int, error) {
+	replayed := 0
+	for {
+		letters, _, err := s.Repo.List(ctx, 0, replayBatchSize)
+		if err != nil {
+			s.lg.Error("DeadLetterService.ReplayAll failed to list dead letters", "error", err)
+			return replayed, err
+		}
+		if len(letters) == 0 {
+			break
+		}
+
+		for _, d := range letters {
+			if err := s.replay(ctx, d); err != nil {
+				return replayed, err
+			}
+			replayed++
+		}
+	}
+
+	s.lg.Info("Replayed all dead letters", "count", replayed)
+	return replayed, nil
+}
+
+// Purge удаляет все записи без повторной отправки
+func (s *DeadLetterService) Purge(ctx context.Context) (int64, error) {
+	n, err := s.Repo.Purge(ctx)
+	if err != nil {
+		s.lg.Error("DeadLetterService.Purge failed", "error", err)
+		return 0, err
+	}
+	s.lg.Info("Purged dead letters", "count", n)
+	return n, nil
+}
+
+func (s *DeadLetterService) replay(ctx context.Context, d *deadletter.DeadLetter) error {
+	if err := s.Queue.Push(ctx, d.Payload); err != nil {
+		s.lg.Error("DeadLetterService failed to push payload back to queue", "dead_letter_id", d.ID, "error", err)
+		return err
+	}
+
+	// запись удаляется после постановки в очередь: при сбое событие может уйти дважды, но не потеряется
+	if err := s.Repo.Delete(ctx, d.ID); err != nil && !errors.Is(err, errs.ErrNotFound) {
+		s.lg.Error("DeadLetterService failed to delete replayed dead letter", "dead_letter_id", d.ID, "error", err)
+		return err
+	}
+
+	s.lg.Info("Dead letter replayed", "dead_letter_id", d.ID, "url", d.URL)
+	return nil
+}
@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+)
+
+// IncidentIndex держит в памяти процесса пространственный индекс активных инцидентов.
+// Оборачивает IncidentCache: инвалидация кэша сбрасывает и индекс, а между инвалидациями
+// индекс перестраивается не реже чем раз в ttl, чтобы подхватывать изменения с других инстансов
+type IncidentIndex struct {
+	repo        incident.IncidentRepository
+	cache       incident.IncidentCache
+	ttl         time.Duration
+	cellDegrees float64
+	lg          *logger.Logger
+
+	mu      sync.RWMutex
+	idx     *incident.SpatialIndex
+	builtAt time.Time
+	gen     uint64 // номер инвалидации: индекс, загруженный до неё, не сохраняется
+
+	// buildMu индекс перестраивает один запрос за раз
+	buildMu sync.Mutex
+}
+
+func NewIncidentIndex(
+	repo incident.IncidentRepository,
+	cache incident.IncidentCache,
+	ttl time.Duration,
+	cellDegrees float64,
+	lg *logger.Logger,
+) *IncidentIndex {
+	return &IncidentIndex{
+		repo:        repo,
+		cache:       cache,
+		ttl:         ttl,
+		cellDegrees: cellDegrees,
+		lg:          lg,
+	}
+}
+
+func (x *IncidentIndex) GetActive(ctx context.Context) ([]*incident.Incident, error) {
+	return x.cache.GetActive(ctx)
+}
+
+func (x *IncidentIndex) SetActive(ctx context.Context, incs []*incident.Incident) error {
+	return x.cache.SetActive(ctx, incs)
+}
+
+func (x *IncidentIndex) InvalidateActive(ctx context.Context) error {
+	x.mu.Lock()
+	x.idx = nil
+	x.gen++
+	x.mu.Unlock()
+
+	return x.cache.InvalidateActive(ctx)
+}
+
+// FindContaining ищет инциденты по индексу, при необходимости перестраивая его
+func (x *IncidentIndex) FindContaining(ctx context.Context, lat, lng float64) ([]*incident.Incident, error) {
+	idx, err := x.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return incident.ActiveAt(idx.FindContaining(lat, lng), time.Now()), nil
+}
+
+// FindWithin ищет инциденты в пределах meters от точки
+func (x *IncidentIndex) FindWithin(ctx context.Context, lat, lng, meters float64) ([]*incident.Incident, error) {
+	idx, err := x.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return incident.ActiveAt(idx.FindWithin(lat, lng, meters), time.Now()), nil
+}
+
+// FindCrossing ищет инциденты, зону которых пересекает отрезок пути
+func (x *IncidentIndex) FindCrossing(ctx context.Context, lat1, lng1, lat2, lng2 float64) ([]*incident.Incident, error) {
+	idx, err := x.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return incident.ActiveAt(idx.FindCrossing(lat1, lng1, lat2, lng2), time.Now()), nil
+}
+
+func (x *IncidentIndex) index(ctx context.Context) (*incident.SpatialIndex, error) {
+	x.mu.RLock()
+	idx, fresh := x.idx, time.Since(x.builtAt) < x.ttl
+	x.mu.RUnlock()
+	if idx != nil && fresh {
+		return idx, nil
+	}
+
+	// устаревший индекс продолжает отвечать, пока его перестраивает другой запрос;
+	// без индекса ждём, пока его построят
+	if idx != nil {
+		if !x.buildMu.TryLock() {
+			return idx, nil
+		}
+	} else {
+		x.buildMu.Lock()
+	}
+	defer x.buildMu.Unlock()
+
+	// индекс мог перестроить другой запрос, пока мы ждали блокировку
+	x.mu.RLock()
+	idx, fresh, gen := x.idx, time.Since(x.builtAt) < x.ttl, x.gen
+	x.mu.RUnlock()
+	if idx != nil && fresh {
+		return idx, nil
+	}
+
+	// загрузка из кэша или Postgres идёт без mu, запросы по текущему индексу не блокируются
+	incs, err := x.loadActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	built := incident.NewSpatialIndex(incs, x.cellDegrees)
+
+	x.mu.Lock()
+	if x.gen == gen {
+		x.idx, x.builtAt = built, time.Now()
+	}
+	x.mu.Unlock()
+
+	x.lg.Debug("IncidentIndex: index rebuilt", "count", built.Len())
+	return built, nil
+}
+
+func (x *IncidentIndex) loadActive(ctx context.Context) ([]*incident.Incident, error) {
+	incs, err := x.cache.GetActive(ctx)
+	if err == nil {
+		return incs, nil
+	}
+
+	incs, err = x.repo.GetActiveIncidents(ctx)
+	if err != nil {
+		x.lg.Error("IncidentIndex: failed to get active incidents", "error", err)
+		return nil, err
+	}
+
+	_ = x.cache.SetActive(ctx, incs)
+	return incs, nil
+}
@@ -2,12 +2,14 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
 	"github.com/google/uuid"
 )
 
+// IncidentService события об изменениях инцидентов записывает репозиторий вместе с самим изменением
 type IncidentService struct {
 	Repo  incident.IncidentRepository
 	Cache incident.IncidentCache
@@ -23,6 +25,9 @@ func NewIncidentService(repo incident.IncidentRepository, cache incident.Inciden
 }
 
 func (s *IncidentService) CreateIncident(ctx context.Context, inc *incident.Incident) error {
+	// у инцидента с расписанием активность определяется текущим временем
+	inc.IsActive = inc.ActiveAt(time.Now())
+
 	if err := s.Repo.Create(ctx, inc); err != nil {
 		s.lg.Error("CreateIncident failed", "incident_id", inc.ID, "error", err)
 		return err
@@ -44,6 +49,8 @@ func (s *IncidentService) GetIncident(ctx context.Context, id uuid.UUID) (*incid
 }
 
 func (s *IncidentService) UpdateIncident(ctx context.Context, inc *incident.Incident) error {
+	inc.IsActive = inc.ActiveAt(time.Now())
+
 	if err := s.Repo.Update(ctx, inc); err != nil {
 		s.lg.Error("UpdateIncident failed", "incident_id", inc.ID, "error", err)
 		return err
@@ -65,8 +72,8 @@ func (s *IncidentService) DeactivateIncident(ctx context.Context, id uuid.UUID)
 	return nil
 }
 
-func (s *IncidentService) ListIncidents(ctx context.Context, offset, limit int) ([]*incident.Incident, int, error) {
-	incs, total, err := s.Repo.ListWithTotal(ctx, offset, limit)
+func (s *IncidentService) ListIncidents(ctx context.Context, filter incident.ListFilter, offset, limit int) ([]*incident.Incident, int, error) {
+	incs, total, err := s.Repo.ListWithTotal(ctx, filter, offset, limit)
 	if err != nil {
 		s.lg.Error("ListIncidents failed", "offset", offset, "limit", limit, "error", err)
 		return nil, 0, err
@@ -78,6 +85,8 @@ func (s *IncidentService) ListIncidents(ctx context.Context, offset, limit int)
 func (s *IncidentService) GetActiveIncidents(ctx context.Context) ([]*incident.Incident, error) {
 	incs, err := s.Cache.GetActive(ctx)
 	if err == nil {
+		// в кэше могут остаться инциденты, окно которых закончилось после его заполнения
+		incs = incident.ActiveAt(incs, time.Now())
 		s.lg.Debug("GetActiveIncidents cache hit", "count", len(incs))
 		return incs, nil
 	}
@@ -102,3 +111,46 @@ func (s *IncidentService) CountActiveIncidents(ctx context.Context) (int, error)
 	s.lg.Debug("CountActiveIncidents success", "count", count)
 	return count, nil
 }
+
+// SyncSchedule приводит флаг is_active инцидентов с расписанием к текущему времени
+// и сбрасывает кэш активных, если что-то изменилось
+func (s *IncidentService) SyncSchedule(ctx context.Context) error {
+	incs, err := s.Repo.ListScheduled(ctx, time.Now())
+	if err != nil {
+		s.lg.Error("SyncSchedule failed to list scheduled incidents", "error", err)
+		return err
+	}
+
+	now := time.Now()
+	changed := 0
+	for _, inc := range incs {
+		active := inc.ActiveAt(now)
+		if active == inc.IsActive {
+			continue
+		}
+		switchedAt := inc.SwitchedAt(now)
+		if switchedAt.IsZero() {
+			// флаг разошёлся с расписанием не на границе окна: берём время последнего изменения строки,
+			// оно тоже одинаково на всех инстансах
+			switchedAt = inc.UpdatedAt
+		}
+		switched, err := s.Repo.SetActive(ctx, inc.ID, active, switchedAt)
+		if err != nil {
+			s.lg.Error("SyncSchedule failed to switch incident", "incident_id", inc.ID, "active", active, "error", err)
+			return err
+		}
+		if !switched {
+			// флаг уже переключил другой инстанс, он же записал событие
+			continue
+		}
+		changed++
+		inc.IsActive = active
+		s.lg.Info("Incident switched by schedule", "incident_id", inc.ID, "active", active)
+	}
+
+	if changed > 0 {
+		_ = s.Cache.InvalidateActive(ctx)
+	}
+	s.lg.Debug("SyncSchedule success", "scheduled", len(incs), "changed", changed)
+	return nil
+}
@@ -2,74 +2,613 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"sort"
 	"time"
 
 	"github.com/Soujuruya/01_SPEC/internal/domain/incident"
 	"github.com/Soujuruya/01_SPEC/internal/domain/location"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/metrics"
 	"github.com/google/uuid"
 )
 
+// LocationOptions настройки проверки локации
+type LocationOptions struct {
+	DwellAfter       time.Duration // через сколько времени в зоне отправлять DWELL
+	ProximityBuffer  float64       // в пределах скольких метров от границы зона считается приближающейся
+	IndexCellDegrees float64       // размер ячейки индекса для пакетной проверки
+	TrajectoryMaxGap time.Duration // максимальный интервал между точками для проверки пересечения зон в пути
+	CertainOverlap   float64       // доля круга точности внутри зоны, начиная с которой попадание certain
+	PossibleOverlap  float64       // доля круга точности внутри зоны, начиная с которой попадание possible
+	NotifyConfidence []string      // уровни уверенности, при которых попадание вызывает вебхук
+	NotifyCooldown   time.Duration // окно, в течение которого повторные уведомления по зоне не отправляются; 0 — без окна
+}
+
+// LocationPoint точка, накопленная клиентом офлайн
+type LocationPoint struct {
+	UserID    uuid.UUID
+	Lat       float64
+	Lng       float64
+	Timestamp time.Time
+	Fix       location.Fix
+}
+
 type LocationService struct {
-	Repo         location.LocationRepository
-	IncidentRepo incident.IncidentRepository
-	Queue        location.WebhookQueue
-	Lg           *logger.Logger
+	Repo      location.LocationRepository
+	Incidents incident.IncidentLocator
+	History   incident.IncidentHistory
+	Geofence  location.GeofenceStore
+	Proximity location.GeofenceStore
+	Cooldowns location.CooldownStore
+	Stats     location.StatsCounter
+	Locks     location.UserLocker
+	Opts      LocationOptions
+	Lg        *logger.Logger
+
+	notifyLevels map[string]bool
 }
 
 func NewLocationService(
 	repo location.LocationRepository,
-	incidentRepo incident.IncidentRepository,
-	queue location.WebhookQueue,
+	incidents incident.IncidentLocator,
+	history incident.IncidentHistory,
+	geofence location.GeofenceStore,
+	proximity location.GeofenceStore,
+	cooldowns location.CooldownStore,
+	stats location.StatsCounter,
+	locks location.UserLocker,
+	opts LocationOptions,
 	lg *logger.Logger,
 ) *LocationService {
 	return &LocationService{
-		Repo:         repo,
-		IncidentRepo: incidentRepo,
-		Queue:        queue,
-		Lg:           lg,
+		Repo:      repo,
+		Incidents: incidents,
+		History:   history,
+		Geofence:  geofence,
+		Proximity: proximity,
+		Cooldowns: cooldowns,
+		Stats:     stats,
+		Locks:     locks,
+		Opts:      opts,
+		Lg:        lg,
+
+		notifyLevels: levelSet(opts.NotifyConfidence),
 	}
 }
 
-// CheckLocation проверяет координаты пользователя
-func (s *LocationService) CheckLocation(ctx context.Context, userID uuid.UUID, lat, lng float64) (*location.Location, error) {
-	activeIncidents, err := s.IncidentRepo.GetActiveIncidents(ctx)
+func levelSet(levels []string) map[string]bool {
+	set := make(map[string]bool, len(levels))
+	for _, l := range levels {
+		set[l] = true
+	}
+	return set
+}
+
+// CheckLocation проверяет координаты пользователя с учётом точности фиксации
+func (s *LocationService) CheckLocation(ctx context.Context, userID uuid.UUID, lat, lng float64, fix location.Fix) (*location.Location, error) {
+	found, err := s.Incidents.FindWithin(ctx, lat, lng, s.searchRadius(fix))
+	if err != nil {
+		s.Lg.Error("LocationService.CheckLocation: failed to find incidents", "error", err, "user_id", userID)
+		return nil, err
+	}
+
+	// от чтения прежней точки и состояния до их сохранения пользователь заблокирован:
+	// иначе две одновременные проверки увидят одно состояние и обе отправят ENTER
+	unlock, err := s.lockUsers(ctx, []uuid.UUID{userID})
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	loc := s.evaluate(LocationPoint{UserID: userID, Lat: lat, Lng: lng, Timestamp: time.Now(), Fix: fix}, found)
+
+	prev, err := s.previous(ctx, userID, loc.Timestamp)
+	if err != nil {
+		s.Lg.Error("LocationService.CheckLocation: failed to get previous location", "error", err, "user_id", userID)
+		return nil, err
+	}
+	if s.followsWithinGap(prev, loc) {
+		crossed, err := s.Incidents.FindCrossing(ctx, prev.Lat, prev.Lng, lat, lng)
+		if err != nil {
+			s.Lg.Error("LocationService.CheckLocation: failed to find crossed incidents", "error", err, "user_id", userID)
+			return nil, err
+		}
+		loc.PassedThroughIncidentIDs = passedThrough(crossed, prev, loc)
+		addIncidentInfo(loc, crossed, loc.PassedThroughIncidentIDs)
+	}
+
+	st, err := s.loadState(ctx, userID)
 	if err != nil {
-		s.Lg.Error("LocationService.CheckLocation: failed to get active incidents", "error", err, "user_id", userID)
 		return nil, err
 	}
+	if err := s.loadCooldowns(ctx, loc, st); err != nil {
+		return nil, err
+	}
+	s.plan(loc, st)
+
+	// точка и события вебхуков записываются одной транзакцией (outbox)
+	if err := s.Repo.Save(ctx, loc); err != nil {
+		s.Lg.Error("LocationService.CheckLocation: failed to save location", "error", err, "user_id", userID, "location_id", loc.ID)
+		return nil, err
+	}
+
+	s.Lg.Debug("LocationService.CheckLocation: location saved", "user_id", userID, "location_id", loc.ID, "incidents_found", len(loc.IncidentIDs), "nearby", len(loc.Nearby), "events", loc.Events)
+
+	s.saveState(ctx, userID, st)
+	s.recordStats(ctx, []*location.Location{loc})
+	countChecks([]*location.Location{loc})
+	return loc, nil
+}
+
+// CheckLocationBatch проверяет точки, накопленные клиентами офлайн. Каждая точка сверяется
+// с инцидентами, активными в момент её timestamp; результаты возвращаются в порядке запроса
+func (s *LocationService) CheckLocationBatch(ctx context.Context, points []LocationPoint) ([]*location.Location, error) {
+	if len(points) == 0 {
+		return []*location.Location{}, nil
+	}
 
+	from, to := points[0].Timestamp, points[0].Timestamp
+	for _, p := range points[1:] {
+		if p.Timestamp.Before(from) {
+			from = p.Timestamp
+		}
+		if p.Timestamp.After(to) {
+			to = p.Timestamp
+		}
+	}
+
+	candidates, err := s.History.ListActiveBetween(ctx, from, to)
+	if err != nil {
+		s.Lg.Error("LocationService.CheckLocationBatch: failed to get incidents", "error", err, "from", from, "to", to)
+		return nil, err
+	}
+	idx := incident.NewSpatialIndex(candidates, s.Opts.IndexCellDegrees)
+
+	locs := make([]*location.Location, len(points))
+	for i, p := range points {
+		var found []*incident.Incident
+		for _, inc := range idx.FindWithin(p.Lat, p.Lng, s.searchRadius(p.Fix)) {
+			if inc.WasActiveAt(p.Timestamp) {
+				found = append(found, inc)
+			}
+		}
+		locs[i] = s.evaluate(p, found)
+	}
+
+	// точки обрабатываются в хронологическом порядке, как если бы они пришли вовремя
+	ordered := make([]*location.Location, len(locs))
+	copy(ordered, locs)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Timestamp.Before(ordered[j].Timestamp) })
+
+	users := make([]uuid.UUID, 0, len(points))
+	for _, p := range points {
+		users = append(users, p.UserID)
+	}
+	unlock, err := s.lockUsers(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	stored := make(map[uuid.UUID]*location.Location)
+	last := make(map[uuid.UUID]*location.Location)
+	states := make(map[uuid.UUID]*zoneState)
+	for _, loc := range ordered {
+		latest, ok := stored[loc.UserID]
+		if !ok {
+			if latest, err = s.latest(ctx, loc.UserID); err != nil {
+				s.Lg.Error("LocationService.CheckLocationBatch: failed to get previous location", "error", err, "user_id", loc.UserID)
+				return nil, err
+			}
+			stored[loc.UserID] = latest
+		}
+		prev, ok := last[loc.UserID]
+		if !ok && latest != nil && latest.Timestamp.Before(loc.Timestamp) {
+			prev = latest
+		}
+		last[loc.UserID] = loc
+
+		if s.followsWithinGap(prev, loc) {
+			var crossed []*incident.Incident
+			for _, inc := range idx.FindCrossing(prev.Lat, prev.Lng, loc.Lat, loc.Lng) {
+				if inc.WasActiveAt(loc.Timestamp) {
+					crossed = append(crossed, inc)
+				}
+			}
+			loc.PassedThroughIncidentIDs = passedThrough(crossed, prev, loc)
+			addIncidentInfo(loc, crossed, loc.PassedThroughIncidentIDs)
+		}
+
+		// состояние в Redis отражает уже более позднюю точку: сверка с ним дала бы ложные EXIT/ENTER
+		if latest != nil && !loc.Timestamp.After(latest.Timestamp) {
+			loc.Notification.Reason = location.ReasonLate
+			continue
+		}
+
+		st, ok := states[loc.UserID]
+		if !ok {
+			if st, err = s.loadState(ctx, loc.UserID); err != nil {
+				return nil, err
+			}
+			states[loc.UserID] = st
+		}
+		if err := s.loadCooldowns(ctx, loc, st); err != nil {
+			return nil, err
+		}
+		s.plan(loc, st)
+	}
+
+	if err := s.Repo.SaveBatch(ctx, locs); err != nil {
+		s.Lg.Error("LocationService.CheckLocationBatch: failed to save locations", "error", err, "count", len(locs))
+		return nil, err
+	}
+
+	s.Lg.Debug("LocationService.CheckLocationBatch: locations saved", "count", len(locs), "candidates", len(candidates))
+
+	for userID, st := range states {
+		s.saveState(ctx, userID, st)
+	}
+	s.recordStats(ctx, locs)
+	countChecks(locs)
+	return locs, nil
+}
+
+// searchRadius в каком радиусе искать зоны: учитываются и зоны поблизости, и круг точности
+func (s *LocationService) searchRadius(fix location.Fix) float64 {
+	return max(s.Opts.ProximityBuffer, fix.Accuracy())
+}
+
+// confidence оценивает попадание по доле круга точности внутри зоны
+func (s *LocationService) confidence(overlap float64) string {
+	switch {
+	case overlap >= s.Opts.CertainOverlap:
+		return location.ConfidenceCertain
+	case overlap > 0 && overlap >= s.Opts.PossibleOverlap:
+		return location.ConfidencePossible
+	default:
+		return location.ConfidenceOutside
+	}
+}
+
+// evaluate раскладывает найденные инциденты на попадания и зоны поблизости
+func (s *LocationService) evaluate(p LocationPoint, found []*incident.Incident) *location.Location {
 	loc := &location.Location{
 		ID:          uuid.New(),
-		UserID:      userID,
-		Lat:         lat,
-		Lng:         lng,
-		Timestamp:   time.Now(),
+		UserID:      p.UserID,
+		Lat:         p.Lat,
+		Lng:         p.Lng,
+		Timestamp:   p.Timestamp,
 		IncidentIDs: []uuid.UUID{},
+		Matches:     []location.Match{},
+		Confidence:  location.ConfidenceOutside,
+		Transitions: []location.Transition{},
+		Nearby:      []location.NearbyIncident{},
+
+		PassedThroughIncidentIDs: []uuid.UUID{},
+
+		Fix: p.Fix,
+
+		Incidents: []location.IncidentInfo{},
+
+		Notification: location.Notification{Suppressed: []location.SuppressedHit{}},
 	}
 
-	for _, inc := range activeIncidents {
-		if inc.IsPointInRadius(lat, lng) {
+	for _, inc := range found {
+		conf := s.confidence(inc.Overlap(p.Lat, p.Lng, p.Fix.Accuracy()))
+		if conf != location.ConfidenceOutside {
 			loc.IncidentIDs = append(loc.IncidentIDs, inc.ID)
+			loc.Matches = append(loc.Matches, location.Match{IncidentID: inc.ID, Confidence: conf})
+			if conf == location.ConfidenceCertain || loc.Confidence == location.ConfidenceOutside {
+				loc.Confidence = conf
+			}
+			loc.Incidents = append(loc.Incidents, incidentInfo(inc))
+			continue
+		}
+		if inc.Contains(p.Lat, p.Lng) {
+			continue
 		}
+
+		distance, edgeLat, edgeLng := inc.EdgeDistance(p.Lat, p.Lng)
+		if distance > s.Opts.ProximityBuffer {
+			continue
+		}
+		loc.Nearby = append(loc.Nearby, location.NearbyIncident{
+			IncidentID:     inc.ID,
+			DistanceMeters: distance,
+			BearingDegrees: incident.BearingDegrees(p.Lat, p.Lng, edgeLat, edgeLng),
+		})
+		loc.Incidents = append(loc.Incidents, incidentInfo(inc))
 	}
 
 	loc.IsCheck = len(loc.IncidentIDs) > 0
+	return loc
+}
 
-	if err := s.Repo.Save(ctx, loc); err != nil {
-		s.Lg.Error("LocationService.CheckLocation: failed to save location", "error", err, "user_id", userID, "location_id", loc.ID)
+// previous последняя сохранённая точка пользователя, если она раньше момента before
+func (s *LocationService) previous(ctx context.Context, userID uuid.UUID, before time.Time) (*location.Location, error) {
+	last, err := s.latest(ctx, userID)
+	if err != nil || last == nil || !last.Timestamp.Before(before) {
 		return nil, err
 	}
+	return last, nil
+}
+
+// latest последняя сохранённая точка пользователя; nil — точек нет
+func (s *LocationService) latest(ctx context.Context, userID uuid.UUID) (*location.Location, error) {
+	locs, err := s.Repo.ListByUser(ctx, userID, 1)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return locs[0], nil
+}
+
+// followsWithinGap можно ли считать, что пользователь двигался от prev к loc без остановок
+func (s *LocationService) followsWithinGap(prev, loc *location.Location) bool {
+	return prev != nil && s.Opts.TrajectoryMaxGap > 0 && loc.Timestamp.Sub(prev.Timestamp) <= s.Opts.TrajectoryMaxGap
+}
+
+// passedThrough зоны, пересечённые на отрезке пути, в которых пользователь не был ни в одной из двух точек
+func passedThrough(crossed []*incident.Incident, prev, loc *location.Location) []uuid.UUID {
+	ids := []uuid.UUID{}
+	for _, inc := range crossed {
+		if inc.Contains(prev.Lat, prev.Lng) || inc.Contains(loc.Lat, loc.Lng) {
+			continue
+		}
+		ids = append(ids, inc.ID)
+	}
+	return ids
+}
 
-	s.Lg.Debug("LocationService.CheckLocation: location saved", "user_id", userID, "location_id", loc.ID, "incidents_found", len(loc.IncidentIDs))
+func incidentInfo(inc *incident.Incident) location.IncidentInfo {
+	return location.IncidentInfo{
+		IncidentID: inc.ID,
+		Title:      inc.Title,
+		Severity:   inc.Severity,
+		Category:   inc.Category,
 
-	if loc.IsCheck {
-		if err := s.Queue.Enqueue(ctx, loc); err != nil {
-			s.Lg.Error("LocationService.CheckLocation: failed to enqueue webhook", "error", err, "user_id", userID, "location_id", loc.ID)
+		NotifyCooldown: inc.NotifyCooldown,
+	}
+}
+
+// addIncidentInfo добавляет сведения о зонах из ids, пересечённых в пути
+func addIncidentInfo(loc *location.Location, incs []*incident.Incident, ids []uuid.UUID) {
+	want := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	for _, inc := range incs {
+		if want[inc.ID] {
+			loc.Incidents = append(loc.Incidents, incidentInfo(inc))
+		}
+	}
+}
+
+// lockUsers блокирует пользователей в порядке их id, чтобы пересекающиеся пакеты не ждали друг друга по кругу
+func (s *LocationService) lockUsers(ctx context.Context, userIDs []uuid.UUID) (func(), error) {
+	ids := make([]uuid.UUID, 0, len(userIDs))
+	seen := make(map[uuid.UUID]bool, len(userIDs))
+	for _, id := range userIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+	unlocks := make([]func(), 0, len(ids))
+	unlockAll := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, id := range ids {
+		unlock, err := s.Locks.Lock(ctx, id)
+		if err != nil {
+			s.Lg.Error("LocationService.lockUsers: failed to lock user", "error", err, "user_id", id)
+			unlockAll()
 			return nil, err
 		}
-		s.Lg.Debug("LocationService.CheckLocation: webhook enqueued", "user_id", userID, "location_id", loc.ID)
+		unlocks = append(unlocks, unlock)
 	}
+	return unlockAll, nil
+}
 
-	return loc, nil
+// zoneState состояние пользователя относительно зон между проверками
+type zoneState struct {
+	geofence  map[uuid.UUID]location.GeofenceState
+	proximity map[uuid.UUID]location.GeofenceState
+
+	cooldowns map[location.CooldownKey]time.Time // окончание окон повтора; нулевое время — окна нет
+	started   map[location.CooldownKey]time.Time // окна, открытые отправленными уведомлениями
+}
+
+func (s *LocationService) loadState(ctx context.Context, userID uuid.UUID) (*zoneState, error) {
+	geofence, err := s.Geofence.Get(ctx, userID)
+	if err != nil {
+		s.Lg.Error("LocationService.loadState: failed to get geofence state", "error", err, "user_id", userID)
+		return nil, err
+	}
+	proximity, err := s.Proximity.Get(ctx, userID)
+	if err != nil {
+		s.Lg.Error("LocationService.loadState: failed to get proximity state", "error", err, "user_id", userID)
+		return nil, err
+	}
+	return &zoneState{
+		geofence:  geofence,
+		proximity: proximity,
+		cooldowns: make(map[location.CooldownKey]time.Time),
+		started:   make(map[location.CooldownKey]time.Time),
+	}, nil
+}
+
+// loadCooldowns дочитывает окна повтора по зонам, которые могут дать уведомление в точке loc
+func (s *LocationService) loadCooldowns(ctx context.Context, loc *location.Location, st *zoneState) error {
+	var keys []location.CooldownKey
+	add := func(eventType string, ids []uuid.UUID) {
+		for _, id := range ids {
+			k := location.CooldownKey{EventType: eventType, IncidentID: id}
+			if _, known := st.cooldowns[k]; !known {
+				st.cooldowns[k] = time.Time{}
+				keys = append(keys, k)
+			}
+		}
+	}
+	add(location.EventGeofence, loc.MatchedIDs(s.notifyLevels))
+	add(location.EventGeofence, loc.PassedThroughIncidentIDs)
+	add(location.EventProximity, loc.NearbyIDs())
+	if len(keys) == 0 {
+		return nil
+	}
+
+	active, err := s.Cooldowns.Active(ctx, loc.UserID, keys)
+	if err != nil {
+		s.Lg.Error("LocationService.loadCooldowns: failed to get cooldowns", "error", err, "user_id", loc.UserID)
+		return err
+	}
+	for k, until := range active {
+		st.cooldowns[k] = until
+	}
+	return nil
+}
+
+// plan считает переходы точки относительно состояния пользователя, продвигает состояние
+// и отмечает в loc.Events, какие вебхуки записать вместе с точкой. Вход в зону, проезд через неё
+// и приближение к ней открывают окно повтора; такие же события по зоне внутри окна не отправляются,
+// как и DWELL/EXIT входа, пришедшегося на окно
+func (s *LocationService) plan(loc *location.Location, st *zoneState) {
+	transitions, nextStates := location.NextTransitions(st.geofence, loc.MatchedIDs(s.notifyLevels), loc.Timestamp, s.Opts.DwellAfter)
+	loc.Transitions = transitions
+	nearTransitions, nextNear := location.NextTransitions(st.proximity, loc.NearbyIDs(), loc.Timestamp, 0)
+
+	for _, t := range transitions {
+		switch {
+		case t.Event == location.TransitionEnter:
+			if st.suppress(loc, location.EventGeofence, t.IncidentID, t.Event) {
+				state := nextStates[t.IncidentID]
+				state.Muted = true
+				nextStates[t.IncidentID] = state
+				continue
+			}
+			s.startCooldown(loc, st, location.EventGeofence, t.IncidentID)
+		case st.geofence[t.IncidentID].Muted:
+			loc.Notification.Suppressed = append(loc.Notification.Suppressed, location.SuppressedHit{
+				EventType:  location.EventGeofence,
+				IncidentID: t.IncidentID,
+				Event:      t.Event,
+				Reason:     location.ReasonCooldown,
+			})
+		}
+	}
+	for _, id := range loc.PassedThroughIncidentIDs {
+		if !st.suppress(loc, location.EventGeofence, id, location.HitPassedThrough) {
+			s.startCooldown(loc, st, location.EventGeofence, id)
+		}
+	}
+	approaching := false
+	for _, t := range nearTransitions {
+		if t.Event != location.TransitionEnter || st.suppress(loc, location.EventProximity, t.IncidentID, t.Event) {
+			continue
+		}
+		s.startCooldown(loc, st, location.EventProximity, t.IncidentID)
+		approaching = true
+	}
+	st.geofence, st.proximity = nextStates, nextNear
+
+	// вебхук отправляется только при смене состояния или проезде через зону, а не на каждую точку внутри зоны
+	if len(loc.NotifiedTransitions()) > 0 || len(loc.NotifiedPassedThrough()) > 0 {
+		loc.Events = append(loc.Events, location.EventGeofence)
+	}
+	// предупреждение о приближении отправляется, когда зона впервые оказалась рядом
+	if approaching {
+		loc.Events = append(loc.Events, location.EventProximity)
+	}
+
+	loc.Notification.Sent = len(loc.Events) > 0
+	switch {
+	case loc.Notification.Sent:
+	case len(loc.Notification.Suppressed) > 0:
+		loc.Notification.Reason = location.ReasonCooldown
+	default:
+		loc.Notification.Reason = location.ReasonNoChange
+	}
+}
+
+// suppress отмечает событие по зоне подавленным, если окно повтора ещё открыто
+func (st *zoneState) suppress(loc *location.Location, eventType string, id uuid.UUID, event string) bool {
+	until := st.cooldowns[location.CooldownKey{EventType: eventType, IncidentID: id}]
+	if !loc.Timestamp.Before(until) {
+		return false
+	}
+	loc.Notification.Suppressed = append(loc.Notification.Suppressed, location.SuppressedHit{
+		EventType:  eventType,
+		IncidentID: id,
+		Event:      event,
+		Reason:     location.ReasonCooldown,
+		Until:      &until,
+	})
+	return true
+}
+
+// startCooldown открывает окно повтора по зоне, о которой отправляется уведомление
+func (s *LocationService) startCooldown(loc *location.Location, st *zoneState, eventType string, id uuid.UUID) {
+	d := s.notifyCooldown(loc, id)
+	if d <= 0 {
+		return
+	}
+	k := location.CooldownKey{EventType: eventType, IncidentID: id}
+	st.cooldowns[k] = loc.Timestamp.Add(d)
+	st.started[k] = st.cooldowns[k]
+}
+
+// notifyCooldown окно повтора зоны: собственное значение инцидента или глобальная настройка
+func (s *LocationService) notifyCooldown(loc *location.Location, id uuid.UUID) time.Duration {
+	for _, info := range loc.Incidents {
+		if info.IncidentID == id && info.NotifyCooldown != nil {
+			return *info.NotifyCooldown
+		}
+	}
+	return s.Opts.NotifyCooldown
+}
+
+// saveState сохраняет состояние после записи точки. Ошибка не прерывает запрос: точка и события
+// уже записаны, а несохранённое состояние лишь приведёт к повтору тех же переходов на следующей точке
+func (s *LocationService) saveState(ctx context.Context, userID uuid.UUID, st *zoneState) {
+	if err := s.Geofence.Save(ctx, userID, st.geofence); err != nil {
+		s.Lg.Error("LocationService.saveState: failed to save geofence state", "error", err, "user_id", userID)
+	}
+	if err := s.Proximity.Save(ctx, userID, st.proximity); err != nil {
+		s.Lg.Error("LocationService.saveState: failed to save proximity state", "error", err, "user_id", userID)
+	}
+	if len(st.started) > 0 {
+		if err := s.Cooldowns.Start(ctx, userID, st.started); err != nil {
+			s.Lg.Error("LocationService.saveState: failed to start cooldowns", "error", err, "user_id", userID)
+		}
+	}
+}
+
+// recordStats обновляет счётчики статистики. Ошибка не прерывает запрос: точки уже записаны
+// и попадут в точный подсчёт по locations
+func (s *LocationService) recordStats(ctx context.Context, locs []*location.Location) {
+	if s.Stats == nil {
+		return
+	}
+	if err := s.Stats.Record(ctx, locs); err != nil {
+		s.Lg.Error("LocationService.recordStats: failed to record stats", "error", err, "count", len(locs))
+	}
+}
+
+// countChecks учитывает сохранённые проверки в метриках попаданий
+func countChecks(locs []*location.Location) {
+	for _, loc := range locs {
+		result := metrics.ResultMiss
+		if loc.IsCheck {
+			result = metrics.ResultHit
+		}
+		metrics.LocationChecks.WithLabelValues(result).Inc()
+	}
 }
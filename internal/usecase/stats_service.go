@@ -9,24 +9,84 @@ import (
 )
 
 type StatsService struct {
-	Repo location.LocationRepository
-	Lg   *logger.Logger
+	Repo      location.LocationRepository
+	Counter   location.StatsCounter
+	Retention time.Duration // сколько хранятся минутные счётчики Counter
+	Lg        *logger.Logger
 }
 
-func NewStatsService(repo location.LocationRepository, lg *logger.Logger) *StatsService {
+// NewStatsService counter может быть nil — тогда статистика всегда считается по locations
+func NewStatsService(repo location.LocationRepository, counter location.StatsCounter, retention time.Duration, lg *logger.Logger) *StatsService {
 	return &StatsService{
-		Repo: repo,
-		Lg:   lg,
+		Repo:      repo,
+		Counter:   counter,
+		Retention: retention,
+		Lg:        lg,
 	}
 }
 
-func (s *StatsService) GetUserCount(ctx context.Context, minutes int) (int, error) {
-	since := time.Now().Add(-time.Duration(minutes) * time.Minute)
-	count, err := s.Repo.CountUniqueUsers(ctx, since)
+// GetStats статистика попаданий в зоны за последние window с рядом по интервалам bucket.
+// window и bucket должны быть кратны секунде. Если они кратны минуте и окно не старше хранимых
+// счётчиков, статистика берётся из счётчиков Redis; exact или ошибка Redis — точный подсчёт по locations
+func (s *StatsService) GetStats(ctx context.Context, window, bucket time.Duration, exact bool) (*location.Stats, error) {
+	if !exact && s.fromCounter(window, bucket) {
+		// текущая минута входит в окно целиком, хотя ещё не закончилась
+		to := time.Now().UTC().Truncate(time.Minute).Add(time.Minute)
+		stats, err := s.Counter.Stats(ctx, to.Add(-window), to, bucket)
+		if err == nil {
+			stats.Series = fillSeries(stats.Series, stats.From, stats.To, bucket)
+			return stats, nil
+		}
+		s.Lg.Warn("StatsService.GetStats: counters unavailable, falling back to postgres", "error", err, "window", window)
+	}
+	return s.exactStats(ctx, window, bucket)
+}
+
+// fromCounter можно ли ответить по минутным счётчикам
+func (s *StatsService) fromCounter(window, bucket time.Duration) bool {
+	return s.Counter != nil && window%time.Minute == 0 && bucket%time.Minute == 0 && window <= s.Retention
+}
+
+func (s *StatsService) exactStats(ctx context.Context, window, bucket time.Duration) (*location.Stats, error) {
+	// границы интервалов совпадают с date_bin в базе, только если они без долей секунды
+	to := time.Now().UTC().Truncate(time.Second)
+	stats := &location.Stats{From: to.Add(-window), To: to, Bucket: bucket, Exact: true}
+
+	var err error
+	if stats.UserCount, err = s.Repo.CountUsersInIncidents(ctx, stats.From, stats.To); err != nil {
+		s.Lg.Error("StatsService.GetStats: failed to count users in incidents", "error", err, "window", window)
+		return nil, err
+	}
+	if stats.Incidents, err = s.Repo.CountByIncident(ctx, stats.From, stats.To); err != nil {
+		s.Lg.Error("StatsService.GetStats: failed to count hits by incident", "error", err, "window", window)
+		return nil, err
+	}
+	series, err := s.Repo.HitSeries(ctx, stats.From, stats.To, bucket)
 	if err != nil {
-		s.Lg.Error("StatsService.GetUserCount: failed to count unique users", "error", err, "minutes", minutes)
-		return 0, err
+		s.Lg.Error("StatsService.GetStats: failed to get hit series", "error", err, "window", window, "bucket", bucket)
+		return nil, err
+	}
+	stats.Series = fillSeries(series, stats.From, stats.To, bucket)
+
+	s.Lg.Debug("StatsService.GetStats: stats fetched", "user_count", stats.UserCount, "incidents", len(stats.Incidents), "window", window, "bucket", bucket)
+	return stats, nil
+}
+
+// fillSeries дополняет ряд нулевыми интервалами, чтобы на графике не было разрывов
+func fillSeries(series []location.StatsBucket, from, to time.Time, bucket time.Duration) []location.StatsBucket {
+	byStart := make(map[int64]location.StatsBucket, len(series))
+	for _, b := range series {
+		byStart[b.Start.UnixNano()] = b
+	}
+
+	filled := make([]location.StatsBucket, 0, int(to.Sub(from)/bucket)+1)
+	for start := from; start.Before(to); start = start.Add(bucket) {
+		b, ok := byStart[start.UnixNano()]
+		if !ok {
+			b = location.StatsBucket{Start: start}
+		}
+		b.Start = start
+		filled = append(filled, b)
 	}
-	s.Lg.Debug("StatsService.GetUserCount: unique user count fetched", "count", count, "minutes", minutes)
-	return count, nil
+	return filled
 }
@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/subscription"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// SubscriptionService управляет подписками на вебхуки и подбирает подписчиков для событий.
+// Активные подписки держатся в памяти процесса не дольше ttl
+type SubscriptionService struct {
+	Repo        subscription.SubscriptionRepository
+	ttl         time.Duration
+	secretGrace time.Duration
+	lg          *logger.Logger
+
+	mu       sync.RWMutex
+	active   []*subscription.Subscription
+	loadedAt time.Time
+}
+
+// secretGrace сколько прежний секрет остаётся действующим после ротации
+func NewSubscriptionService(repo subscription.SubscriptionRepository, ttl, secretGrace time.Duration, lg *logger.Logger) *SubscriptionService {
+	return &SubscriptionService{
+		Repo:        repo,
+		ttl:         ttl,
+		secretGrace: secretGrace,
+		lg:          lg,
+	}
+}
+
+// CreateSubscription сохраняет подписку; если секрет не задан, он генерируется
+func (s *SubscriptionService) CreateSubscription(ctx context.Context, sub *subscription.Subscription) error {
+	if sub.Secret == "" {
+		secret, err := GenerateSecret()
+		if err != nil {
+			s.lg.Error("CreateSubscription failed to generate secret", "error", err)
+			return err
+		}
+		sub.Secret = secret
+	}
+
+	if err := s.Repo.Create(ctx, sub); err != nil {
+		s.lg.Error("CreateSubscription failed", "subscription_id", sub.ID, "error", err)
+		return err
+	}
+
+	s.invalidate()
+	s.lg.Info("Created subscription", "subscription_id", sub.ID, "url", sub.URL)
+	return nil
+}
+
+func (s *SubscriptionService) GetSubscription(ctx context.Context, id uuid.UUID) (*subscription.Subscription, error) {
+	sub, err := s.Repo.GetByID(ctx, id)
+	if err != nil {
+		s.lg.Error("GetSubscription failed", "subscription_id", id, "error", err)
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *SubscriptionService) UpdateSubscription(ctx context.Context, sub *subscription.Subscription) error {
+	if err := s.Repo.Update(ctx, sub); err != nil {
+		s.lg.Error("UpdateSubscription failed", "subscription_id", sub.ID, "error", err)
+		return err
+	}
+
+	s.invalidate()
+	s.lg.Info("Updated subscription", "subscription_id", sub.ID)
+	return nil
+}
+
+func (s *SubscriptionService) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	if err := s.Repo.Delete(ctx, id); err != nil {
+		s.lg.Error("DeleteSubscription failed", "subscription_id", id, "error", err)
+		return err
+	}
+
+	s.invalidate()
+	s.lg.Info("Deleted subscription", "subscription_id", id)
+	return nil
+}
+
+func (s *SubscriptionService) ListSubscriptions(ctx context.Context) ([]*subscription.Subscription, error) {
+	subs, err := s.Repo.List(ctx)
+	if err != nil {
+		s.lg.Error("ListSubscriptions failed", "error", err)
+		return nil, err
+	}
+	s.lg.Debug("ListSubscriptions success", "count", len(subs))
+	return subs, nil
+}
+
+// RotateSecret выдаёт подписке новый секрет (сгенерированный, если secret пуст).
+// До истечения secretGrace доставки подписываются и прежним секретом
+func (s *SubscriptionService) RotateSecret(ctx context.Context, id uuid.UUID, secret string) (*subscription.Subscription, error) {
+	sub, err := s.Repo.GetByID(ctx, id)
+	if err != nil {
+		s.lg.Error("RotateSecret failed to get subscription", "subscription_id", id, "error", err)
+		return nil, err
+	}
+
+	if secret == "" {
+		if secret, err = GenerateSecret(); err != nil {
+			s.lg.Error("RotateSecret failed to generate secret", "error", err)
+			return nil, err
+		}
+	}
+	sub.RotateSecret(secret, s.secretGrace, time.Now())
+
+	if err := s.Repo.Update(ctx, sub); err != nil {
+		s.lg.Error("RotateSecret failed", "subscription_id", id, "error", err)
+		return nil, err
+	}
+
+	s.invalidate()
+	s.lg.Info("Rotated subscription secret", "subscription_id", id, "previous_valid_until", sub.PreviousSecretExpiresAt)
+	return sub, nil
+}
+
+// Secrets действующие секреты подписки для подписи доставки
+func (s *SubscriptionService) Secrets(ctx context.Context, id uuid.UUID) ([]string, error) {
+	active, err := s.loadActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range active {
+		if sub.ID == id {
+			return sub.Secrets(time.Now()), nil
+		}
+	}
+
+	// кэш мог ещё не увидеть новую подписку; отключённой подписке события не доставляются
+	sub, err := s.Repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !sub.IsActive {
+		return nil, errs.ErrNotFound
+	}
+	return sub.Secrets(time.Now()), nil
+}
+
+// Matching активные подписки, под фильтры которых подходит событие
+func (s *SubscriptionService) Matching(ctx context.Context, e subscription.Event) ([]*subscription.Subscription, error) {
+	active, err := s.loadActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*subscription.Subscription
+	for _, sub := range active {
+		if sub.Matches(e) {
+			out = append(out, sub)
+		}
+	}
+	return out, nil
+}
+
+func (s *SubscriptionService) loadActive(ctx context.Context) ([]*subscription.Subscription, error) {
+	s.mu.RLock()
+	active, fresh := s.active, s.active != nil && time.Since(s.loadedAt) < s.ttl
+	s.mu.RUnlock()
+	if fresh {
+		return active, nil
+	}
+
+	active, err := s.Repo.ListActive(ctx)
+	if err != nil {
+		s.lg.Error("SubscriptionService failed to load active subscriptions", "error", err)
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.active, s.loadedAt = active, time.Now()
+	s.mu.Unlock()
+	return active, nil
+}
+
+func (s *SubscriptionService) invalidate() {
+	s.mu.Lock()
+	s.active = nil
+	s.mu.Unlock()
+}
+
+// GenerateSecret случайный секрет подписчика
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff задержка перед попыткой номер attempt (с 1): base·2^(attempt-1), не больше maxDelay
+// (maxDelay <= 0 — без предела), со случайным разбросом в пределах половины задержки,
+// чтобы повторы разных событий не совпадали
+func Backoff(attempt int, base, maxDelay time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base
+	for i := 1; i < attempt && d <= math.MaxInt64/2; i++ {
+		if maxDelay > 0 && d >= maxDelay {
+			break
+		}
+		d *= 2
+	}
+	if maxDelay > 0 && d > maxDelay {
+		d = maxDelay
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
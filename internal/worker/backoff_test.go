@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempt  int
+		base     time.Duration
+		maxDelay time.Duration
+		want     time.Duration // задержка без разброса: результат в [want/2, want]
+	}{
+		{"first attempt", 1, time.Second, time.Minute, time.Second},
+		{"grows exponentially", 4, time.Second, time.Minute, 8 * time.Second},
+		{"capped by maxDelay", 10, time.Second, time.Minute, time.Minute},
+		{"cap not a power of two", 3, 5 * time.Second, 12 * time.Second, 12 * time.Second},
+		{"zero maxDelay is unlimited", 11, time.Second, 0, 1024 * time.Second},
+		{"negative maxDelay is unlimited", 3, time.Second, -time.Second, 4 * time.Second},
+		// удвоение останавливается на первом значении больше MaxInt64/2, а не переполняется
+		{"unlimited does not overflow", 200, time.Second, 0, time.Second << 33},
+		{"zero attempt", 0, time.Second, time.Minute, time.Second},
+		{"zero base", 5, 0, time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 50 {
+				got := Backoff(tt.attempt, tt.base, tt.maxDelay)
+				if got < tt.want/2 || got > tt.want {
+					t.Fatalf("Backoff(%d, %v, %v) = %v, want in [%v, %v]", tt.attempt, tt.base, tt.maxDelay, got, tt.want/2, tt.want)
+				}
+			}
+		})
+	}
+}
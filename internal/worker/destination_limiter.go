@@ -0,0 +1,43 @@
+package worker
+
+import "sync"
+
+// destinationLimiter ограничивает число одновременных отправок на один адрес,
+// чтобы медленный получатель не занимал все горутины пула
+type destinationLimiter struct {
+	mu    sync.Mutex
+	limit int
+	slots map[string]chan struct{}
+}
+
+func newDestinationLimiter(limit int) *destinationLimiter {
+	return &destinationLimiter{
+		limit: limit,
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+// tryAcquire занимает слот адреса без ожидания; false — все слоты заняты
+func (l *destinationLimiter) tryAcquire(dest string) bool {
+	l.mu.Lock()
+	slots, ok := l.slots[dest]
+	if !ok {
+		slots = make(chan struct{}, l.limit)
+		l.slots[dest] = slots
+	}
+	l.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *destinationLimiter) release(dest string) {
+	l.mu.Lock()
+	slots := l.slots[dest]
+	l.mu.Unlock()
+	<-slots
+}
@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/Soujuruya/01_SPEC/internal/usecase"
+)
+
+// IncidentScheduler периодически включает и выключает инциденты по их расписанию
+type IncidentScheduler struct {
+	service  *usecase.IncidentService
+	interval time.Duration
+	lg       *logger.Logger
+}
+
+func NewIncidentScheduler(service *usecase.IncidentService, interval time.Duration, lg *logger.Logger) *IncidentScheduler {
+	return &IncidentScheduler{
+		service:  service,
+		interval: interval,
+		lg:       lg,
+	}
+}
+
+func (s *IncidentScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.service.SyncSchedule(ctx); err != nil {
+			s.lg.Error("IncidentScheduler sync failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.lg.Info("IncidentScheduler stopped due to context cancellation")
+			return
+		case <-ticker.C:
+		}
+	}
+}
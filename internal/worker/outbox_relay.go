@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/outbox"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+)
+
+// cleanupEvery как часто relay удаляет старые отправленные сообщения
+const cleanupEvery = time.Hour
+
+// OutboxRelay перекладывает события вебхуков из таблицы outbox в очередь Redis.
+// Строка отмечается отправленной в той же транзакции, в которой была выбрана, а очередь
+// пропускает уже опубликованные ID, поэтому каждое событие попадает в очередь ровно один раз
+type OutboxRelay struct {
+	repo      outbox.OutboxRepository
+	queue     outbox.Publisher
+	batch     int
+	poll      time.Duration
+	retention time.Duration
+	lg        *logger.Logger
+}
+
+func NewOutboxRelay(repo outbox.OutboxRepository, queue outbox.Publisher, batch int, poll, retention time.Duration, lg *logger.Logger) *OutboxRelay {
+	return &OutboxRelay{
+		repo:      repo,
+		queue:     queue,
+		batch:     batch,
+		poll:      poll,
+		retention: retention,
+		lg:        lg,
+	}
+}
+
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.poll)
+	defer ticker.Stop()
+	var lastCleanup time.Time
+
+	for {
+		r.drain(ctx)
+
+		if now := time.Now(); now.Sub(lastCleanup) >= cleanupEvery {
+			lastCleanup = now
+			n, err := r.repo.Cleanup(ctx, now.Add(-r.retention))
+			if err != nil && ctx.Err() == nil {
+				r.lg.Error("OutboxRelay cleanup failed", "error", err)
+			} else if n > 0 {
+				r.lg.Debug("OutboxRelay removed sent messages", "count", n)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			r.lg.Info("OutboxRelay stopped due to context cancellation")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain публикует пачки, пока очередная пачка заполнена целиком
+func (r *OutboxRelay) drain(ctx context.Context) {
+	for {
+		n, err := r.repo.Relay(ctx, r.batch, r.queue.Publish)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.lg.Error("OutboxRelay failed to publish messages", "error", err)
+			}
+			return
+		}
+		if n > 0 {
+			r.lg.Debug("OutboxRelay published messages", "count", n)
+		}
+		if n < r.batch {
+			return
+		}
+	}
+}
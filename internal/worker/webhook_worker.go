@@ -3,76 +3,425 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"sync"
 	"time"
 
+	"github.com/Soujuruya/01_SPEC/internal/domain/deadletter"
 	"github.com/Soujuruya/01_SPEC/internal/integration"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/errs"
 	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
-	"github.com/redis/go-redis/v9"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/metrics"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/tracing"
+	"github.com/Soujuruya/01_SPEC/internal/repository/redis"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// fetchWait сколько Fetch ждёт событие, прежде чем проверить отмену контекста
+const fetchWait = 5 * time.Second
+
+// PoolOptions размер пула отправки и его остановка
+type PoolOptions struct {
+	Workers         int           // горутин, одновременно отправляющих вебхуки
+	PerDestination  int           // одновременных отправок на один адрес
+	ShutdownTimeout time.Duration // сколько ждать начатые отправки при остановке
+}
+
+// WebhookQueue очередь вебхуков с подтверждением обработки, см. redis.WebhookConsumer
+type WebhookQueue interface {
+	Heartbeat(ctx context.Context) error
+	Fetch(ctx context.Context, wait time.Duration) (string, error)
+	Ack(ctx context.Context, raw string) error
+	Delivered(ctx context.Context, delivery string) (bool, error)
+	AckDelivered(ctx context.Context, raw, delivery string) error
+	Requeue(ctx context.Context, raw string, items ...[]byte) error
+	Defer(ctx context.Context, raw string, data []byte, at time.Time) error
+	Promote(ctx context.Context, now time.Time) (int, error)
+	Reclaim(ctx context.Context) (int, error)
+	Depth(ctx context.Context) (redis.QueueDepth, error)
+	Visibility() time.Duration
+	Close(ctx context.Context) error
+}
+
+// WebhookSender отправка события получателю, см. integration.WebhookClient
+type WebhookSender interface {
+	Send(ctx context.Context, payload integration.WebhookPayload, lg *logger.Logger) error
+}
+
+// WebhookTargets получатели события, см. integration.WebhookRouter
+type WebhookTargets interface {
+	Targets(ctx context.Context, payload integration.WebhookPayload) ([]integration.Delivery, error)
+}
+
+// DeadLetterRecorder хранилище событий, исчерпавших попытки, см. usecase.DeadLetterService
+type DeadLetterRecorder interface {
+	Record(ctx context.Context, d *deadletter.DeadLetter) error
+}
+
 type WebhookWorker struct {
-	rdb        *redis.Client
-	queueKey   string
-	client     *integration.WebhookClient
+	queue      WebhookQueue
+	client     WebhookSender
+	router     WebhookTargets
+	dead       DeadLetterRecorder
 	retryMax   int
 	retryDelay time.Duration
+	maxDelay   time.Duration
+	poll       time.Duration
+	pool       PoolOptions
+	limiter    *destinationLimiter
 	lg         *logger.Logger
 }
 
 func NewWebhookWorker(
-	rdb *redis.Client,
-	queueKey string,
-	client *integration.WebhookClient,
+	queue WebhookQueue,
+	client WebhookSender,
+	router WebhookTargets,
+	dead DeadLetterRecorder,
 	retryMax int,
 	retryDelay time.Duration,
+	maxDelay time.Duration,
+	poll time.Duration,
+	pool PoolOptions,
 	lg *logger.Logger,
 ) *WebhookWorker {
 	return &WebhookWorker{
-		rdb:        rdb,
-		queueKey:   queueKey,
+		queue:      queue,
 		client:     client,
+		router:     router,
+		dead:       dead,
 		retryMax:   retryMax,
 		retryDelay: retryDelay,
+		maxDelay:   maxDelay,
+		poll:       poll,
+		pool:       pool,
+		limiter:    newDestinationLimiter(pool.PerDestination),
 		lg:         lg,
 	}
 }
 
+// Run обрабатывает очередь пулом из pool.Workers горутин до отмены ctx и возвращается, когда пул остановлен.
+// Событие подтверждается только после ответа 2xx либо после того, как оно атомарно переложено дальше:
+// в копии для подписчиков, в отложенные повторы или в dead letters. Если процесс упадёт посреди отправки,
+// событие останется в списке обработки и другой воркер вернёт его в очередь, когда истечёт heartbeat этого.
+//
+// После отмены ctx новые события не забираются, а начатые отправки получают pool.ShutdownTimeout на
+// завершение; то, что не успело завершиться, возвращается в очередь без учёта попытки
 func (w *WebhookWorker) Run(ctx context.Context, lg *logger.Logger) {
+	// отправки и работа с очередью живут дольше ctx, чтобы начатое можно было довести до конца
+	sendCtx, cancelSends := context.WithCancel(context.Background())
+	defer cancelSends()
+
+	if err := w.queue.Heartbeat(sendCtx); err != nil {
+		w.lg.Error("WebhookWorker failed to register consumer", "error", err)
+	}
+	go w.maintain(sendCtx)
+	go w.promote(ctx)
+
+	var wg sync.WaitGroup
+	for range w.pool.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx, sendCtx, lg)
+		}()
+	}
+	w.lg.Info("WebhookWorker started", "workers", w.pool.Workers, "per_destination", w.pool.PerDestination)
+
+	<-ctx.Done()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.lg.Info("WebhookWorker drained in-flight deliveries")
+	case <-time.After(w.pool.ShutdownTimeout):
+		w.lg.Warn("WebhookWorker shutdown timeout reached, cancelling in-flight deliveries", "timeout", w.pool.ShutdownTimeout)
+		cancelSends()
+		<-done
+	}
+
+	// всё, что осталось в списке обработки, возвращается в очередь
+	cancelSends()
+	w.release()
+	w.lg.Info("WebhookWorker stopped due to context cancellation")
+}
+
+// loop забирает события, пока не отменён ctx
+func (w *WebhookWorker) loop(ctx, sendCtx context.Context, lg *logger.Logger) {
 	for {
-		res, err := w.rdb.BRPop(ctx, 0, w.queueKey).Result()
+		raw, err := w.queue.Fetch(ctx, fetchWait)
 		if err != nil {
 			if ctx.Err() != nil {
-				w.lg.Info("WebhookWorker stopped due to context cancellation")
 				return
 			}
-			w.lg.Error("WebhookWorker BRPop error", "error", err)
+			w.lg.Error("WebhookWorker fetch error", "error", err)
+			time.Sleep(time.Second)
 			continue
 		}
-
-		var payload integration.WebhookPayload
-		if err := json.Unmarshal([]byte(res[1]), &payload); err != nil {
-			w.lg.Error("Failed to unmarshal webhook payload", "error", err, "data", res[1])
-			continue
+		// событие, забранное уже после остановки, вернётся в очередь при release
+		if ctx.Err() != nil {
+			return
 		}
-
-		err = w.client.Send(ctx, payload, lg)
-		if err == nil {
-			w.lg.Info("Webhook sent successfully", "user_id", payload.UserID, "incident_ids", payload.IncidentIDs)
+		if raw == "" {
 			continue
 		}
 
-		payload.Retry++
-		if payload.Retry >= w.retryMax {
-			w.lg.Warn("Webhook retry limit reached, dropping payload", "user_id", payload.UserID, "incident_ids", payload.IncidentIDs, "retries", payload.Retry)
-			continue
+		w.process(sendCtx, raw, lg)
+	}
+}
+
+func (w *WebhookWorker) process(ctx context.Context, raw string, lg *logger.Logger) {
+	var payload integration.WebhookPayload
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		w.lg.Error("Failed to unmarshal webhook payload, dropping", "error", err, "data", raw)
+		metrics.WebhookDropped.WithLabelValues(metrics.DropInvalidPayload).Inc()
+		w.queue.Ack(ctx, raw)
+		return
+	}
+
+	// обработка продолжает трассу проверки, породившей событие, в том числе при повторах
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, payload.TraceContext), "webhook process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("webhook.event_id", payload.EventID.String()),
+			attribute.String("webhook.event_type", payload.EventType),
+		),
+	)
+	defer span.End()
+
+	// событие для нескольких получателей раскладывается на копии, чтобы повторы по каждому шли независимо
+	if payload.Target == "" {
+		deliveries, err := w.router.Targets(ctx, payload)
+		if err != nil {
+			w.lg.Error("Failed to resolve webhook subscribers, retrying", "error", err, "user_id", payload.UserID)
+			w.queue.Defer(ctx, raw, []byte(raw), time.Now().Add(w.retryDelay))
+			return
+		}
+		if len(deliveries) == 0 {
+			w.lg.Debug("No webhook subscribers for event", "user_id", payload.UserID, "event_type", payload.EventType)
+			w.queue.Ack(ctx, raw)
+			return
 		}
+		if len(deliveries) > 1 {
+			w.fanOut(ctx, raw, payload, deliveries)
+			return
+		}
+		payload.SubscriptionID, payload.Target, payload.Format = deliveries[0].SubscriptionID, deliveries[0].URL, deliveries[0].Format
+	}
 
-		w.lg.Warn("Webhook send failed, retrying", "user_id", payload.UserID, "incident_ids", payload.IncidentIDs, "retry", payload.Retry, "error", err)
-		time.Sleep(w.retryDelay)
+	// повтор уже доставленного события (например, после сбоя между ответом и подтверждением) не отправляется
+	delivery := deliveryKey(payload)
+	if delivery != "" {
+		if done, err := w.queue.Delivered(ctx, delivery); err == nil && done {
+			w.lg.Info("Webhook already delivered, skipping duplicate", "event_id", payload.EventID, "subscription_id", payload.SubscriptionID)
+			w.queue.Ack(ctx, raw)
+			return
+		}
+	}
 
+	// адрес с занятыми слотами не держит горутину: событие откладывается без учёта попытки
+	if !w.limiter.tryAcquire(payload.Target) {
+		w.lg.Debug("Webhook destination busy, deferring", "subscription_id", payload.SubscriptionID, "target", payload.Target)
 		data, _ := json.Marshal(payload)
-		if err := w.rdb.LPush(ctx, w.queueKey, data).Err(); err != nil {
-			w.lg.Error("Failed to push webhook back to queue", "error", err)
+		w.queue.Defer(ctx, raw, data, time.Now().Add(w.poll))
+		return
+	}
+	start := time.Now()
+	err := w.client.Send(ctx, payload, lg)
+	elapsed := time.Since(start)
+	w.limiter.release(payload.Target)
+	if ctx.Err() != nil {
+		// отправку прервала остановка воркера: событие останется в списке обработки и вернётся в очередь
+		return
+	}
+	if err == nil {
+		metrics.WebhookDeliveryDuration.WithLabelValues(metrics.ResultSuccess).Observe(elapsed.Seconds())
+		w.lg.Info("Webhook sent successfully", "user_id", payload.UserID, "event_id", payload.EventID, "incident_ids", payload.IncidentIDs)
+		if delivery != "" {
+			w.queue.AckDelivered(ctx, raw, delivery)
+		} else {
+			w.queue.Ack(ctx, raw)
 		}
+		return
+	}
+	if errors.Is(err, errs.ErrNotFound) {
+		w.lg.Info("Webhook subscription no longer exists, dropping payload", "user_id", payload.UserID, "subscription_id", payload.SubscriptionID)
+		metrics.WebhookDropped.WithLabelValues(metrics.DropSubscriptionGone).Inc()
+		w.queue.Ack(ctx, raw)
+		return
+	}
+	// получатель недоступен: событие ждёт пробной отправки, попытка не засчитывается
+	var open *integration.CircuitOpenError
+	if errors.As(err, &open) {
+		w.lg.Debug("Webhook destination circuit open, holding event", "subscription_id", payload.SubscriptionID, "host", open.Host, "retry_at", open.RetryAt)
+		data, _ := json.Marshal(payload)
+		w.queue.Defer(ctx, raw, data, open.RetryAt)
+		return
+	}
+	metrics.WebhookDeliveryDuration.WithLabelValues(metrics.ResultFailure).Observe(elapsed.Seconds())
+
+	payload.Retry++
+	payload.Attempts = append(payload.Attempts, attemptOf(err))
+	if payload.Retry >= w.retryMax {
+		w.lg.Warn("Webhook retry limit reached, moving payload to dead letters", "user_id", payload.UserID, "incident_ids", payload.IncidentIDs, "retries", payload.Retry)
+		w.deadLetter(ctx, raw, payload)
+		return
+	}
+
+	delay := w.retryAfter(payload.Retry, err)
+	w.lg.Warn("Webhook send failed, retry scheduled", "user_id", payload.UserID, "incident_ids", payload.IncidentIDs, "retry", payload.Retry, "delay", delay, "error", err)
+
+	data, _ := json.Marshal(payload)
+	if err := w.queue.Defer(ctx, raw, data, time.Now().Add(delay)); err == nil {
+		metrics.WebhookRetries.Inc()
+	}
+}
+
+// retryAfter задержка перед следующей попыткой: экспоненциальная с разбросом,
+// но не раньше, чем просил получатель в Retry-After, и не позже maxDelay
+func (w *WebhookWorker) retryAfter(attempt int, err error) time.Duration {
+	delay := Backoff(attempt, w.retryDelay, w.maxDelay)
+	var de *integration.DeliveryError
+	if errors.As(err, &de) && de.RetryAfter > delay {
+		delay = de.RetryAfter
+		if w.maxDelay > 0 {
+			delay = min(delay, w.maxDelay)
+		}
+	}
+	return delay
+}
+
+// release снимает регистрацию воркера при остановке; контекст Run к этому моменту уже отменён
+func (w *WebhookWorker) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchWait)
+	defer cancel()
+	if err := w.queue.Close(ctx); err != nil {
+		w.lg.Error("WebhookWorker failed to release consumer", "error", err)
+	}
+}
+
+// promote раз в poll переносит наступившие повторы обратно в очередь
+func (w *WebhookWorker) promote(ctx context.Context) {
+	ticker := time.NewTicker(w.poll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n, err := w.queue.Promote(ctx, now)
+			if err != nil {
+				if ctx.Err() == nil {
+					w.lg.Error("Failed to promote due webhook retries", "error", err)
+				}
+				continue
+			}
+			if n > 0 {
+				w.lg.Debug("Promoted due webhook retries", "count", n)
+			}
+		}
+	}
+}
+
+// maintain продлевает heartbeat воркера, забирает события упавших воркеров и обновляет метрики очереди
+func (w *WebhookWorker) maintain(ctx context.Context) {
+	ticker := time.NewTicker(w.queue.Visibility() / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.queue.Heartbeat(ctx)
+			if _, err := w.queue.Reclaim(ctx); err != nil && ctx.Err() == nil {
+				w.lg.Error("Failed to reclaim stuck webhooks", "error", err)
+			}
+			w.observeDepth(ctx)
+		}
+	}
+}
+
+func (w *WebhookWorker) observeDepth(ctx context.Context) {
+	depth, err := w.queue.Depth(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.lg.Error("Failed to read webhook queue depth", "error", err)
+		}
+		return
+	}
+	metrics.WebhookQueueDepth.WithLabelValues(metrics.QueuePending).Set(float64(depth.Pending))
+	metrics.WebhookQueueDepth.WithLabelValues(metrics.QueueRetry).Set(float64(depth.Retry))
+	metrics.WebhookQueueDepth.WithLabelValues(metrics.QueueProcessing).Set(float64(depth.Processing))
+}
+
+func (w *WebhookWorker) fanOut(ctx context.Context, raw string, payload integration.WebhookPayload, deliveries []integration.Delivery) {
+	copies := make([][]byte, 0, len(deliveries))
+	for _, d := range deliveries {
+		payload.SubscriptionID, payload.Target, payload.Format = d.SubscriptionID, d.URL, d.Format
+		data, _ := json.Marshal(payload)
+		copies = append(copies, data)
+	}
+
+	if err := w.queue.Requeue(ctx, raw, copies...); err != nil {
+		w.lg.Error("Failed to push routed webhooks to queue", "error", err, "user_id", payload.UserID)
+		return
+	}
+	w.lg.Debug("Webhook fanned out to subscribers", "user_id", payload.UserID, "count", len(deliveries))
+}
+
+// deliveryKey ключ доставки события конкретному получателю; "" у событий без event_id
+func deliveryKey(p integration.WebhookPayload) string {
+	if p.EventID == uuid.Nil {
+		return ""
+	}
+	if p.SubscriptionID != nil {
+		return p.EventID.String() + ":" + p.SubscriptionID.String()
+	}
+	return p.EventID.String() + ":" + p.Target
+}
+
+// attemptOf неудачная попытка доставки со статусом ответа, если он был
+func attemptOf(err error) deadletter.Attempt {
+	a := deadletter.Attempt{At: time.Now(), Error: err.Error()}
+	var de *integration.DeliveryError
+	if errors.As(err, &de) {
+		a.StatusCode = de.StatusCode
+	}
+	return a
+}
+
+// deadLetter сохраняет событие, исчерпавшее попытки; в запись попадает копия, готовая к повторной отправке.
+// Если сохранить не удалось, событие откладывается на максимальную задержку, чтобы не потерять его
+func (w *WebhookWorker) deadLetter(ctx context.Context, raw string, payload integration.WebhookPayload) {
+	attempts := payload.Attempts
+	payload.Retry, payload.Attempts = 0, nil
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		w.lg.Error("Failed to marshal dead letter payload", "error", err, "user_id", payload.UserID)
+		return
+	}
+
+	d := deadletter.NewDeadLetter(data, attempts)
+	d.SubscriptionID = payload.SubscriptionID
+	d.URL = payload.Target
+	d.EventType = payload.EventType
+	d.UserID = payload.UserID
+
+	if err := w.dead.Record(ctx, d); err != nil {
+		w.lg.Error("Failed to store dead letter, deferring payload", "error", err, "user_id", payload.UserID)
+		payload.Retry, payload.Attempts = w.retryMax-1, attempts
+		retry, _ := json.Marshal(payload)
+		w.queue.Defer(ctx, raw, retry, time.Now().Add(w.maxDelay))
+		return
 	}
+	metrics.WebhookDropped.WithLabelValues(metrics.DropDeadLetter).Inc()
+	w.queue.Ack(ctx, raw)
 }
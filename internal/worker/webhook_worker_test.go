@@ -0,0 +1,294 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Soujuruya/01_SPEC/internal/domain/deadletter"
+	"github.com/Soujuruya/01_SPEC/internal/integration"
+	"github.com/Soujuruya/01_SPEC/internal/pkg/logger"
+	"github.com/Soujuruya/01_SPEC/internal/repository/redis"
+	"github.com/google/uuid"
+)
+
+// fakeQueue запоминает, чем завершилась обработка события
+type fakeQueue struct {
+	delivered map[string]bool
+
+	acked     []string
+	ackedKeys []string
+	deferred  []deferred
+	requeued  [][]byte
+}
+
+type deferred struct {
+	data []byte
+	at   time.Time
+}
+
+func (q *fakeQueue) Heartbeat(context.Context) error                      { return nil }
+func (q *fakeQueue) Fetch(context.Context, time.Duration) (string, error) { return "", nil }
+func (q *fakeQueue) Promote(context.Context, time.Time) (int, error)      { return 0, nil }
+func (q *fakeQueue) Reclaim(context.Context) (int, error)                 { return 0, nil }
+func (q *fakeQueue) Depth(context.Context) (redis.QueueDepth, error)      { return redis.QueueDepth{}, nil }
+func (q *fakeQueue) Visibility() time.Duration                            { return time.Minute }
+func (q *fakeQueue) Close(context.Context) error                          { return nil }
+
+func (q *fakeQueue) Ack(_ context.Context, raw string) error {
+	q.acked = append(q.acked, raw)
+	return nil
+}
+
+func (q *fakeQueue) Delivered(_ context.Context, delivery string) (bool, error) {
+	return q.delivered[delivery], nil
+}
+
+func (q *fakeQueue) AckDelivered(_ context.Context, raw, delivery string) error {
+	q.acked = append(q.acked, raw)
+	q.ackedKeys = append(q.ackedKeys, delivery)
+	return nil
+}
+
+func (q *fakeQueue) Requeue(_ context.Context, raw string, items ...[]byte) error {
+	q.acked = append(q.acked, raw)
+	q.requeued = append(q.requeued, items...)
+	return nil
+}
+
+func (q *fakeQueue) Defer(_ context.Context, _ string, data []byte, at time.Time) error {
+	q.deferred = append(q.deferred, deferred{data: data, at: at})
+	return nil
+}
+
+type fakeSender struct {
+	err  error
+	sent []integration.WebhookPayload
+}
+
+func (s *fakeSender) Send(_ context.Context, payload integration.WebhookPayload, _ *logger.Logger) error {
+	s.sent = append(s.sent, payload)
+	return s.err
+}
+
+type fakeTargets []integration.Delivery
+
+func (t fakeTargets) Targets(context.Context, integration.WebhookPayload) ([]integration.Delivery, error) {
+	return t, nil
+}
+
+type fakeDeadLetters struct {
+	err      error
+	recorded []*deadletter.DeadLetter
+}
+
+func (d *fakeDeadLetters) Record(_ context.Context, dl *deadletter.DeadLetter) error {
+	d.recorded = append(d.recorded, dl)
+	return d.err
+}
+
+const testRetryMax = 3
+
+func newTestWorker(sender *fakeSender, targets fakeTargets, dead *fakeDeadLetters) (*WebhookWorker, *fakeQueue) {
+	q := &fakeQueue{delivered: map[string]bool{}}
+	w := NewWebhookWorker(q, sender, targets, dead, testRetryMax, time.Second, time.Hour, time.Second,
+		PoolOptions{Workers: 1, PerDestination: 1}, logger.New("test"))
+	return w, q
+}
+
+func testPayload(retry int) (integration.WebhookPayload, string) {
+	sub := uuid.New()
+	p := integration.WebhookPayload{
+		EventID:        uuid.New(),
+		EventType:      "location_check",
+		UserID:         uuid.New(),
+		SubscriptionID: &sub,
+		Target:         "https://hooks.example.com/in",
+		Retry:          retry,
+	}
+	raw, _ := json.Marshal(p)
+	return p, string(raw)
+}
+
+func decodePayload(t *testing.T, data []byte) integration.WebhookPayload {
+	t.Helper()
+	var p integration.WebhookPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	return p
+}
+
+func TestWebhookWorkerProcessDelivered(t *testing.T) {
+	sender := &fakeSender{}
+	w, q := newTestWorker(sender, nil, &fakeDeadLetters{})
+	p, raw := testPayload(0)
+
+	w.process(context.Background(), raw, w.lg)
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("sent %d times, want 1", len(sender.sent))
+	}
+	if len(q.acked) != 1 || q.acked[0] != raw || len(q.ackedKeys) != 1 || q.ackedKeys[0] != deliveryKey(p) {
+		t.Fatalf("acked=%v keys=%v, want AckDelivered with %s", q.acked, q.ackedKeys, deliveryKey(p))
+	}
+	if len(q.deferred) != 0 {
+		t.Fatalf("deferred %d times, want none", len(q.deferred))
+	}
+}
+
+func TestWebhookWorkerProcessDuplicate(t *testing.T) {
+	sender := &fakeSender{}
+	w, q := newTestWorker(sender, nil, &fakeDeadLetters{})
+	p, raw := testPayload(0)
+	q.delivered[deliveryKey(p)] = true
+
+	w.process(context.Background(), raw, w.lg)
+
+	if len(sender.sent) != 0 {
+		t.Fatalf("duplicate sent %d times, want none", len(sender.sent))
+	}
+	if len(q.acked) != 1 || len(q.ackedKeys) != 0 {
+		t.Fatalf("acked=%v keys=%v, want plain Ack", q.acked, q.ackedKeys)
+	}
+}
+
+func TestWebhookWorkerProcessCircuitOpen(t *testing.T) {
+	retryAt := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	sender := &fakeSender{err: &integration.CircuitOpenError{Host: "hooks.example.com", RetryAt: retryAt}}
+	w, q := newTestWorker(sender, nil, &fakeDeadLetters{})
+	_, raw := testPayload(1)
+
+	w.process(context.Background(), raw, w.lg)
+
+	if len(q.deferred) != 1 || len(q.acked) != 0 {
+		t.Fatalf("deferred=%d acked=%d, want one Defer", len(q.deferred), len(q.acked))
+	}
+	if !q.deferred[0].at.Equal(retryAt) {
+		t.Fatalf("deferred until %v, want %v", q.deferred[0].at, retryAt)
+	}
+	if got := decodePayload(t, q.deferred[0].data); got.Retry != 1 || len(got.Attempts) != 0 {
+		t.Fatalf("retry=%d attempts=%d, want attempt not counted", got.Retry, len(got.Attempts))
+	}
+}
+
+func TestWebhookWorkerProcessRetry(t *testing.T) {
+	sender := &fakeSender{err: errors.New("connection refused")}
+	dead := &fakeDeadLetters{}
+	w, q := newTestWorker(sender, nil, dead)
+	_, raw := testPayload(0)
+
+	before := time.Now()
+	w.process(context.Background(), raw, w.lg)
+
+	if len(q.deferred) != 1 || len(dead.recorded) != 0 {
+		t.Fatalf("deferred=%d dead=%d, want one Defer", len(q.deferred), len(dead.recorded))
+	}
+	if q.deferred[0].at.Before(before) {
+		t.Fatalf("deferred until %v, want after %v", q.deferred[0].at, before)
+	}
+	if got := decodePayload(t, q.deferred[0].data); got.Retry != 1 || len(got.Attempts) != 1 {
+		t.Fatalf("retry=%d attempts=%d, want 1 and 1", got.Retry, len(got.Attempts))
+	}
+}
+
+func TestWebhookWorkerProcessDeadLetter(t *testing.T) {
+	t.Run("recorded", func(t *testing.T) {
+		sender := &fakeSender{err: errors.New("connection refused")}
+		dead := &fakeDeadLetters{}
+		w, q := newTestWorker(sender, nil, dead)
+		p, raw := testPayload(testRetryMax - 1)
+
+		w.process(context.Background(), raw, w.lg)
+
+		if len(dead.recorded) != 1 {
+			t.Fatalf("recorded %d dead letters, want 1", len(dead.recorded))
+		}
+		d := dead.recorded[0]
+		if d.URL != p.Target || d.SubscriptionID == nil || *d.SubscriptionID != *p.SubscriptionID || len(d.Attempts) != 1 {
+			t.Fatalf("dead letter = %+v", d)
+		}
+		// в записи копия для повторной отправки: счётчик сброшен
+		if got := decodePayload(t, d.Payload); got.Retry != 0 || len(got.Attempts) != 0 {
+			t.Fatalf("dead letter payload retry=%d attempts=%d, want reset", got.Retry, len(got.Attempts))
+		}
+		if len(q.acked) != 1 || len(q.deferred) != 0 {
+			t.Fatalf("acked=%d deferred=%d, want Ack", len(q.acked), len(q.deferred))
+		}
+	})
+
+	t.Run("record failed", func(t *testing.T) {
+		sender := &fakeSender{err: errors.New("connection refused")}
+		dead := &fakeDeadLetters{err: errors.New("db is down")}
+		w, q := newTestWorker(sender, nil, dead)
+		_, raw := testPayload(testRetryMax - 1)
+
+		before := time.Now()
+		w.process(context.Background(), raw, w.lg)
+
+		if len(q.acked) != 0 || len(q.deferred) != 1 {
+			t.Fatalf("acked=%d deferred=%d, want Defer", len(q.acked), len(q.deferred))
+		}
+		if q.deferred[0].at.Before(before.Add(w.maxDelay)) {
+			t.Fatalf("deferred until %v, want maxDelay later", q.deferred[0].at)
+		}
+		// следующая неудача снова упрётся в предел и ещё раз попробует сохранить dead letter
+		if got := decodePayload(t, q.deferred[0].data); got.Retry != testRetryMax-1 || len(got.Attempts) != 1 {
+			t.Fatalf("retry=%d attempts=%d, want %d and 1", got.Retry, len(got.Attempts), testRetryMax-1)
+		}
+	})
+}
+
+func TestWebhookWorkerProcessFanOut(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	targets := fakeTargets{
+		{SubscriptionID: &first, URL: "https://a.example.com/hook", Format: "json"},
+		{SubscriptionID: &second, URL: "https://b.example.com/hook", Format: "json"},
+	}
+	sender := &fakeSender{}
+	w, q := newTestWorker(sender, targets, &fakeDeadLetters{})
+
+	p, _ := testPayload(0)
+	p.SubscriptionID, p.Target = nil, ""
+	data, _ := json.Marshal(p)
+	raw := string(data)
+
+	w.process(context.Background(), raw, w.lg)
+
+	if len(sender.sent) != 0 {
+		t.Fatalf("sent %d times before fan-out, want none", len(sender.sent))
+	}
+	if len(q.acked) != 1 || q.acked[0] != raw || len(q.requeued) != 2 {
+		t.Fatalf("acked=%d requeued=%d, want original replaced by 2 copies", len(q.acked), len(q.requeued))
+	}
+	for i, item := range q.requeued {
+		got := decodePayload(t, item)
+		if got.EventID != p.EventID || got.Target != targets[i].URL || *got.SubscriptionID != *targets[i].SubscriptionID {
+			t.Fatalf("copy %d = %+v, want event %s for %s", i, got, p.EventID, targets[i].URL)
+		}
+	}
+}
+
+func TestWebhookWorkerRetryAfter(t *testing.T) {
+	w := &WebhookWorker{retryDelay: time.Second, maxDelay: 10 * time.Minute}
+
+	tests := []struct {
+		name     string
+		err      error
+		min, max time.Duration
+	}{
+		{"backoff", errors.New("connection refused"), time.Second / 2, time.Second},
+		{"retry-after later than backoff", &integration.DeliveryError{StatusCode: 503, RetryAfter: time.Minute}, time.Minute, time.Minute},
+		{"retry-after earlier than backoff", &integration.DeliveryError{StatusCode: 503, RetryAfter: time.Millisecond}, time.Second / 2, time.Second},
+		{"retry-after capped by maxDelay", &integration.DeliveryError{StatusCode: 429, RetryAfter: 24 * time.Hour}, 10 * time.Minute, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.retryAfter(1, tt.err); got < tt.min || got > tt.max {
+				t.Fatalf("retryAfter = %v, want in [%v, %v]", got, tt.min, tt.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_incidents_set_zone ON incidents;
+DROP FUNCTION IF EXISTS incidents_set_zone();
+DROP INDEX IF EXISTS idx_incidents_zone;
+ALTER TABLE incidents DROP COLUMN IF EXISTS zone;
@@ -0,0 +1,40 @@
+-- Колонка geography для PostGIS-бэкенда. Без установленного PostGIS миграция ничего не делает,
+-- и сервис продолжает работать с бэкендом postgres
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'postgis') THEN
+        RAISE NOTICE 'postgis is not available, skipping incidents.zone';
+        RETURN;
+    END IF;
+
+    CREATE EXTENSION IF NOT EXISTS postgis;
+
+    ALTER TABLE incidents ADD COLUMN IF NOT EXISTS zone geography;
+
+    -- зона считается из geometry (GeoJSON) либо строится кругом вокруг центра: расстояние в запросах
+    -- не зависит от колонок, и ST_DWithin использует GiST индекс. 64 вершины круга сокращают радиус
+    -- между вершинами не больше чем на 0.12%
+    CREATE OR REPLACE FUNCTION incidents_set_zone() RETURNS trigger AS $fn$
+    BEGIN
+        IF NEW.geometry IS NOT NULL THEN
+            NEW.zone := ST_GeomFromGeoJSON(NEW.geometry::text)::geography;
+        ELSE
+            NEW.zone := ST_Buffer(ST_SetSRID(ST_MakePoint(NEW.lng, NEW.lat), 4326)::geography, NEW.radius, 'quad_segs=16');
+        END IF;
+        RETURN NEW;
+    END;
+    $fn$ LANGUAGE plpgsql;
+
+    DROP TRIGGER IF EXISTS trg_incidents_set_zone ON incidents;
+    CREATE TRIGGER trg_incidents_set_zone
+        BEFORE INSERT OR UPDATE OF lat, lng, radius, geometry ON incidents
+        FOR EACH ROW EXECUTE FUNCTION incidents_set_zone();
+
+    UPDATE incidents SET zone = CASE
+        WHEN geometry IS NOT NULL THEN ST_GeomFromGeoJSON(geometry::text)::geography
+        ELSE ST_Buffer(ST_SetSRID(ST_MakePoint(lng, lat), 4326)::geography, radius, 'quad_segs=16')
+    END;
+
+    CREATE INDEX IF NOT EXISTS idx_incidents_zone ON incidents USING GIST (zone);
+END;
+$$;
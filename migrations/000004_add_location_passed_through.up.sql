@@ -0,0 +1 @@
+ALTER TABLE locations ADD COLUMN IF NOT EXISTS passed_through_incident_ids UUID[] DEFAULT '{}';
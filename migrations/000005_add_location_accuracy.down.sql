@@ -0,0 +1,6 @@
+ALTER TABLE locations
+    DROP COLUMN IF EXISTS confidence,
+    DROP COLUMN IF EXISTS heading,
+    DROP COLUMN IF EXISTS speed,
+    DROP COLUMN IF EXISTS altitude,
+    DROP COLUMN IF EXISTS accuracy_m;
@@ -0,0 +1,8 @@
+ALTER TABLE locations
+    ADD COLUMN IF NOT EXISTS accuracy_m DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS altitude DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS speed DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS heading DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS confidence TEXT NOT NULL DEFAULT 'outside';
+
+UPDATE locations SET confidence = 'certain' WHERE is_check;
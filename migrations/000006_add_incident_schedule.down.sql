@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_incidents_schedule;
+
+ALTER TABLE incidents
+    DROP COLUMN IF EXISTS recurrence,
+    DROP COLUMN IF EXISTS ends_at,
+    DROP COLUMN IF EXISTS starts_at;
@@ -0,0 +1,6 @@
+ALTER TABLE incidents
+    ADD COLUMN IF NOT EXISTS starts_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS ends_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS recurrence JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_incidents_schedule ON incidents(starts_at, ends_at);
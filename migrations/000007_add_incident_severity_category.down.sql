@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_incidents_category_severity;
+
+ALTER TABLE incidents
+    DROP COLUMN IF EXISTS category,
+    DROP COLUMN IF EXISTS severity;
@@ -0,0 +1,6 @@
+ALTER TABLE incidents
+    ADD COLUMN IF NOT EXISTS severity TEXT NOT NULL DEFAULT 'warning'
+        CHECK (severity IN ('info', 'warning', 'critical')),
+    ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT 'other';
+
+CREATE INDEX IF NOT EXISTS idx_incidents_category_severity ON incidents(category, severity);
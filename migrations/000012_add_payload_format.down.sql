@@ -0,0 +1,4 @@
+DELETE FROM outbox WHERE location_id IS NULL;
+ALTER TABLE outbox ALTER COLUMN location_id SET NOT NULL;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS payload_format;
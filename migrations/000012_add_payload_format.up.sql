@@ -0,0 +1,4 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS payload_format TEXT NOT NULL DEFAULT 'json';
+
+-- события об изменении инцидента не привязаны к проверке локации
+ALTER TABLE outbox ALTER COLUMN location_id DROP NOT NULL;
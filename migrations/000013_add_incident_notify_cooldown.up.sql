@@ -0,0 +1,2 @@
+ALTER TABLE incidents
+    ADD COLUMN IF NOT EXISTS notify_cooldown_seconds INT CHECK (notify_cooldown_seconds >= 0);
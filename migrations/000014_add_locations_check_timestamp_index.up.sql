@@ -0,0 +1,2 @@
+-- Статистика считает только проверки с попаданием в зону
+CREATE INDEX IF NOT EXISTS idx_locations_check_timestamp ON locations(timestamp) WHERE is_check;
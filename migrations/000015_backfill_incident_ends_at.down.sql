@@ -0,0 +1 @@
+-- Заполненные ends_at не отличить от заданных вручную, откатывать нечего
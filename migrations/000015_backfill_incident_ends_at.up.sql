@@ -0,0 +1,8 @@
+-- Выключенные через PUT инциденты без расписания не хранили время выключения;
+-- берём последнее изменение, как раньше делала пакетная проверка
+UPDATE incidents
+SET ends_at = updated_at
+WHERE NOT is_active
+  AND ends_at IS NULL
+  AND starts_at IS NULL
+  AND recurrence IS NULL;
@@ -0,0 +1,192 @@
+// Package webhooksig подписывает и проверяет вебхуки системы геооповещений.
+//
+// Подпись — HMAC-SHA256 от строки "<timestamp>.<тело запроса>" секретом подписчика.
+// Время отправки (unix-секунды) передаётся в X-Timestamp, подписи — в X-Signature
+// в виде "v1=<hex>". Во время смены секрета отправитель подписывает запрос и новым,
+// и старым секретом, поэтому подписей в заголовке может быть несколько через запятую
+package webhooksig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderSignature = "X-Signature"
+	HeaderTimestamp = "X-Timestamp"
+
+	// SchemeV1 префикс подписи HMAC-SHA256
+	SchemeV1 = "v1"
+
+	// DefaultTolerance допустимое расхождение X-Timestamp с часами получателя
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrMissingHeaders = errors.New("webhooksig: missing signature or timestamp header")
+	ErrBadTimestamp   = errors.New("webhooksig: malformed timestamp")
+	ErrExpired        = errors.New("webhooksig: timestamp outside tolerance")
+	ErrNoSecrets      = errors.New("webhooksig: no secrets to verify with")
+	ErrMismatch       = errors.New("webhooksig: signature mismatch")
+	ErrReplayed       = errors.New("webhooksig: request already seen")
+)
+
+// Sign подпись тела одним секретом в виде "v1=<hex>"
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return SchemeV1 + "=" + hex.EncodeToString(mac(secret, timestamp.Unix(), body))
+}
+
+// SignRequest проставляет X-Timestamp и X-Signature с подписью каждым из секретов
+func SignRequest(req *http.Request, body []byte, now time.Time, secrets ...string) {
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+
+	sigs := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret != "" {
+			sigs = append(sigs, Sign(secret, now, body))
+		}
+	}
+	if len(sigs) > 0 {
+		req.Header.Set(HeaderSignature, strings.Join(sigs, ","))
+	}
+}
+
+// Verify проверяет заголовки запроса: время в пределах tolerance и хотя бы одна подпись
+// совпадает с подписью одним из secrets. Несколько секретов нужны получателю на время смены
+func Verify(header http.Header, body []byte, now time.Time, tolerance time.Duration, secrets ...string) error {
+	_, err := verify(header, body, now, tolerance, secrets)
+	return err
+}
+
+// verify проверка подписи, возвращает timestamp запроса
+func verify(header http.Header, body []byte, now time.Time, tolerance time.Duration, secrets []string) (int64, error) {
+	ts, sigs, err := parse(header)
+	if err != nil {
+		return 0, err
+	}
+
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return 0, ErrExpired
+	}
+
+	if len(secrets) == 0 {
+		return 0, ErrNoSecrets
+	}
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		expected := mac(secret, ts, body)
+		for _, sig := range sigs {
+			if hmac.Equal(sig, expected) {
+				return ts, nil
+			}
+		}
+	}
+	return 0, ErrMismatch
+}
+
+// Verifier проверка подписи с защитой от повторной отправки: запрос с тем же timestamp и телом
+// принимается один раз, пока его timestamp не выйдет за пределы tolerance. Ключ не зависит от записи
+// X-Signature, поэтому повтор не пройдёт ни с изменённым регистром hex, ни без одной из подписей при смене секрета
+type Verifier struct {
+	secrets   []string
+	tolerance time.Duration
+	now       func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func NewVerifier(tolerance time.Duration, secrets ...string) *Verifier {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	return &Verifier{
+		secrets:   secrets,
+		tolerance: tolerance,
+		now:       time.Now,
+		seen:      make(map[string]time.Time),
+	}
+}
+
+// Verify проверяет подпись и запоминает её до истечения окна
+func (v *Verifier) Verify(header http.Header, body []byte) error {
+	now := v.now()
+	ts, err := verify(header, body, now, v.tolerance, v.secrets)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(body)
+	key := strconv.FormatInt(ts, 10) + "." + hex.EncodeToString(digest[:])
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for k, until := range v.seen {
+		if now.After(until) {
+			delete(v.seen, k)
+		}
+	}
+	if _, dup := v.seen[key]; dup {
+		return ErrReplayed
+	}
+	v.seen[key] = now.Add(2 * v.tolerance)
+	return nil
+}
+
+// VerifyRequest читает тело запроса и проверяет его подпись. Тело возвращается для дальнейшей обработки
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	return body, v.Verify(r.Header, body)
+}
+
+func parse(header http.Header) (int64, [][]byte, error) {
+	rawTs, rawSig := header.Get(HeaderTimestamp), header.Get(HeaderSignature)
+	if rawTs == "" || rawSig == "" {
+		return 0, nil, ErrMissingHeaders
+	}
+
+	ts, err := strconv.ParseInt(rawTs, 10, 64)
+	if err != nil {
+		return 0, nil, ErrBadTimestamp
+	}
+
+	var sigs [][]byte
+	for _, part := range strings.Split(rawSig, ",") {
+		scheme, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || scheme != SchemeV1 {
+			continue
+		}
+		if sig, err := hex.DecodeString(value); err == nil {
+			sigs = append(sigs, sig)
+		}
+	}
+	if len(sigs) == 0 {
+		return 0, nil, ErrMismatch
+	}
+	return ts, sigs, nil
+}
+
+func mac(secret string, ts int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(ts, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,116 @@
+package webhooksig
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var at = time.Unix(1760000000, 0)
+
+func signedHeader(body []byte, secrets ...string) http.Header {
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com/hook", nil)
+	SignRequest(req, body, at, secrets...)
+	return req.Header
+}
+
+func newTestVerifier(secrets ...string) *Verifier {
+	v := NewVerifier(DefaultTolerance, secrets...)
+	v.now = func() time.Time { return at }
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event_id":"1"}`)
+	header := signedHeader(body, "new", "old")
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    []byte
+		now     time.Time
+		secrets []string
+		want    error
+	}{
+		{"new secret", header, body, at, []string{"new"}, nil},
+		{"old secret", header, body, at, []string{"old"}, nil},
+		{"wrong secret", header, body, at, []string{"other"}, ErrMismatch},
+		{"tampered body", header, []byte(`{"event_id":"2"}`), at, []string{"new"}, ErrMismatch},
+		{"expired", header, body, at.Add(DefaultTolerance + time.Second), []string{"new"}, ErrExpired},
+		{"no secrets", header, body, at, nil, ErrNoSecrets},
+		{"no headers", http.Header{}, body, at, []string{"new"}, ErrMissingHeaders},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.header, tt.body, tt.now, DefaultTolerance, tt.secrets...); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifierRejectsReplay(t *testing.T) {
+	body := []byte(`{"event_id":"1"}`)
+	header := signedHeader(body, "new", "old")
+	sigs := strings.Split(header.Get(HeaderSignature), ",")
+
+	// тот же запрос с иначе записанными заголовками: подписи остаются верными
+	reformat := map[string]func(h http.Header){
+		"same headers": func(h http.Header) {},
+		"upper-case hex": func(h http.Header) {
+			h.Set(HeaderSignature, SchemeV1+"="+strings.ToUpper(strings.TrimPrefix(sigs[0], SchemeV1+"=")))
+		},
+		"leading space": func(h http.Header) {
+			h.Set(HeaderSignature, " "+h.Get(HeaderSignature))
+		},
+		"only new signature": func(h http.Header) {
+			h.Set(HeaderSignature, sigs[0])
+		},
+		"only old signature": func(h http.Header) {
+			h.Set(HeaderSignature, sigs[1])
+		},
+		"signatures swapped": func(h http.Header) {
+			h.Set(HeaderSignature, sigs[1]+","+sigs[0])
+		},
+		"timestamp with leading zero": func(h http.Header) {
+			h.Set(HeaderTimestamp, "0"+strconv.FormatInt(at.Unix(), 10))
+		},
+	}
+	for name, change := range reformat {
+		t.Run(name, func(t *testing.T) {
+			v := newTestVerifier("new", "old")
+			if err := v.Verify(header, body); err != nil {
+				t.Fatalf("first delivery: %v", err)
+			}
+
+			replay := header.Clone()
+			change(replay)
+			if err := v.Verify(replay, body); !errors.Is(err, ErrReplayed) {
+				t.Fatalf("replay = %v, want %v", err, ErrReplayed)
+			}
+		})
+	}
+}
+
+func TestVerifierAcceptsDistinctRequests(t *testing.T) {
+	v := newTestVerifier("new")
+
+	first := []byte(`{"event_id":"1"}`)
+	if err := v.Verify(signedHeader(first, "new"), first); err != nil {
+		t.Fatalf("first event: %v", err)
+	}
+	// другое событие с тем же timestamp
+	second := []byte(`{"event_id":"2"}`)
+	if err := v.Verify(signedHeader(second, "new"), second); err != nil {
+		t.Fatalf("second event: %v", err)
+	}
+
+	// повтор после окна отклоняется уже по времени
+	v.now = func() time.Time { return at.Add(3 * DefaultTolerance) }
+	if err := v.Verify(signedHeader(first, "new"), first); !errors.Is(err, ErrExpired) {
+		t.Fatalf("late replay = %v, want %v", err, ErrExpired)
+	}
+}
@@ -5,17 +5,56 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Soujuruya/01_SPEC/pkg/webhooksig"
 )
 
+// verifier проверяет подписи, если задан WEBHOOK_SECRET (несколько секретов — через запятую)
+var verifier *webhooksig.Verifier
+
+// seen ID уже принятых событий: повтор с тем же Idempotency-Key подтверждается без обработки
+var seen sync.Map
+
 func handler(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("[%s] %s\n", r.Method, r.URL.Path)
+	if tp := r.Header.Get("traceparent"); tp != "" {
+		fmt.Printf("traceparent %s\n", tp)
+	}
 	body, _ := io.ReadAll(r.Body)
+
+	if verifier != nil {
+		if err := verifier.Verify(r.Header, body); err != nil {
+			fmt.Printf("signature rejected: %v\n", err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		fmt.Println("signature ok")
+	}
+
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		if _, dup := seen.LoadOrStore(key, struct{}{}); dup {
+			fmt.Printf("duplicate event %s, skipped\n", key)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("OK"))
+			return
+		}
+	}
+
 	fmt.Println(string(body))
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
 func main() {
+	if raw := os.Getenv("WEBHOOK_SECRET"); raw != "" {
+		verifier = webhooksig.NewVerifier(webhooksig.DefaultTolerance, strings.Split(raw, ",")...)
+	} else {
+		fmt.Println("WEBHOOK_SECRET is not set, signatures are not verified")
+	}
+
 	// Обработчик всех путей
 	http.HandleFunc("/", handler)
 